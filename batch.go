@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/palette"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	batchPath     string
+	batchOut      string
+	batchSizes    string
+	batchPalettes string
+	batchJobs     uint
+)
+
+// batchManifest lists the locations a batch renders, and optionally the sizes and palettes to render each of them at
+// in place of -batch-sizes and -batch-palettes
+type batchManifest struct {
+	Sizes     []string        `json:"sizes,omitempty"`
+	Palettes  []string        `json:"palettes,omitempty"`
+	Locations []batchLocation `json:"locations"`
+}
+
+// batchLocation is a location of a batch manifest: a bookmark file, relative to the manifest, an inline bookmark, or
+// just the name of a preset location
+type batchLocation struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	bookmark
+}
+
+// batchJob is a single image of a batch
+type batchJob struct {
+	path     string
+	params   render.Params
+	bookmark bookmark
+}
+
+// renders each location read from batchPath at each size and palette to batchOut, batchJobs images at a time. The
+// images are named after their location, size and palette, e.g. seahorse-1920x1080-fire.png.
+func runBatch() error {
+	manifest, err := readBatch(batchPath)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Sizes) == 0 {
+		manifest.Sizes = strings.Split(batchSizes, ",")
+	}
+	if len(manifest.Palettes) == 0 && batchPalettes != "" {
+		manifest.Palettes = strings.Split(batchPalettes, ",")
+	}
+	jobs, err := batchJobList(manifest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(batchOut, 0755); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rendering %d images to %s\n", len(jobs), batchOut)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		failures int
+	)
+	queue := make(chan batchJob)
+	for i := uint(0); i < batchJobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				err := writeImage(job.path, job.params, job.bookmark, nil)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failures++
+					fmt.Printf("[%d/%d] failed to render %s: %s\n", done, len(jobs), job.path, err)
+				} else {
+					fmt.Printf("[%d/%d] rendered %s\n", done, len(jobs), job.path)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d images failed to render", failures, len(jobs))
+	}
+	return nil
+}
+
+// reads the batch at path, which is either a manifest or a directory of bookmark files and images exported with their
+// views embedded
+func readBatch(path string) (batchManifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return batchManifest{}, err
+	}
+	if !info.IsDir() {
+		return loadBatchManifest(path)
+	}
+
+	var manifest batchManifest
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return batchManifest{}, err
+	}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || ext != ".json" && ext != ".png" {
+			continue
+		}
+		manifest.Locations = append(manifest.Locations, batchLocation{
+			Name: strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())),
+			File: filepath.Join(path, e.Name()),
+		})
+	}
+	if len(manifest.Locations) == 0 {
+		return batchManifest{}, fmt.Errorf("%s has no bookmark files or exported images", path)
+	}
+	return manifest, nil
+}
+
+func loadBatchManifest(path string) (batchManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return batchManifest{}, err
+	}
+	var manifest batchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return batchManifest{}, fmt.Errorf("invalid batch manifest %s: %s", path, err)
+	}
+	if len(manifest.Locations) == 0 {
+		return batchManifest{}, fmt.Errorf("batch manifest %s has no locations", path)
+	}
+	// bookmark files are relative to the manifest rather than the working directory
+	for i, l := range manifest.Locations {
+		if l.File != "" && !filepath.IsAbs(l.File) {
+			manifest.Locations[i].File = filepath.Join(filepath.Dir(path), l.File)
+		}
+	}
+	return manifest, nil
+}
+
+// returns the bookmark of the location, loading it from its file or looking up the preset it names if it isn't inline
+func (l batchLocation) load() (bookmark, error) {
+	switch {
+	case l.File != "" && strings.EqualFold(filepath.Ext(l.File), ".png"):
+		state, err := readImageState(l.File)
+		if err != nil {
+			return bookmark{}, err
+		}
+		return decodeState(state)
+	case l.File != "":
+		return loadBookmark(l.File)
+	case l.Zoom == 0:
+		i := lookupLocation(l.Name)
+		if i < 0 {
+			return bookmark{}, fmt.Errorf("location %q has no file or view, and isn't one of %s", l.Name,
+				strings.Join(locationNames(), ", "))
+		}
+		return locations[i].bookmark, nil
+	}
+	if err := l.bookmark.validate(); err != nil {
+		return bookmark{}, fmt.Errorf("location %q: %s", l.Name, err)
+	}
+	return l.bookmark, nil
+}
+
+// the characters replaced in location names to make file names of them
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// returns every image of the batch, checking the whole batch is valid before anything is rendered
+func batchJobList(manifest batchManifest) ([]batchJob, error) {
+	sizes := make([]pixel.Vec, len(manifest.Sizes))
+	for i, s := range manifest.Sizes {
+		size, err := parseImageSize(s)
+		if err != nil {
+			return nil, err
+		}
+		sizes[i] = size
+	}
+	palettes := make([]string, len(manifest.Palettes))
+	for i, name := range manifest.Palettes {
+		palettes[i] = strings.TrimSpace(name)
+		if _, ok := palette.LookupGradient(palettes[i]); !ok {
+			return nil, fmt.Errorf("unknown palette %q", name)
+		}
+	}
+
+	// each location's settings are applied over those of the command line rather than the previous location's
+	base := newBookmark(mandelbrotBounds)
+	quality, _ := lookupQuality(exportQualityName)
+	seen := make(map[string]bool)
+	var jobs []batchJob
+	for i, l := range manifest.Locations {
+		b, err := l.load()
+		if err != nil {
+			return nil, err
+		}
+		name := unsafeFileChars.ReplaceAllString(l.Name, "-")
+		if name == "" {
+			name = "location-" + strconv.Itoa(i+1)
+		}
+
+		// render each location in its own palette if no palettes are given
+		names := palettes
+		if len(names) == 0 {
+			names = []string{b.Palette}
+		}
+		for _, size := range sizes {
+			for _, paletteOverride := range names {
+				windowBounds := pixel.R(0, 0, size.X, size.Y)
+				base.apply(windowBounds)
+				bounds := b.apply(windowBounds)
+				if paletteOverride != "" {
+					paletteName = paletteOverride
+				}
+
+				file := fmt.Sprintf("%s-%dx%d-%s.png", name, int(size.X), int(size.Y), paletteName)
+				if seen[file] {
+					return nil, fmt.Errorf("more than one image of the batch would be written to %s", file)
+				}
+				seen[file] = true
+				jobs = append(jobs, batchJob{
+					path:     filepath.Join(batchOut, file),
+					params:   quality.apply(newParams(bounds, size)),
+					bookmark: newBookmark(bounds),
+				})
+			}
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		// the largest images first, so that the last few don't leave the other jobs idle
+		return jobs[i].params.Width*jobs[i].params.Height > jobs[j].params.Width*jobs[j].params.Height
+	})
+	return jobs, nil
+}
+
+// parses an image size written as WIDTHxHEIGHT, e.g. 1920x1080
+func parseImageSize(s string) (pixel.Vec, error) {
+	parts := strings.Split(strings.TrimSpace(s), "x")
+	if len(parts) == 2 {
+		w, err1 := strconv.Atoi(parts[0])
+		h, err2 := strconv.Atoi(parts[1])
+		if err1 == nil && err2 == nil && w > 0 && h > 0 {
+			return pixel.V(float64(w), float64(h)), nil
+		}
+	}
+	return pixel.Vec{}, fmt.Errorf("invalid image size %q, expected WIDTHxHEIGHT, e.g. 1920x1080", s)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	benchmark bool
+	benchRuns uint
+)
+
+// the image sizes each benchmarked viewport is rendered at
+var benchSizes = []int{256, 1024}
+
+// benchReport is the machine readable output of a benchmark run
+type benchReport struct {
+	CPUs    int           `json:"cpus"`
+	Runs    uint          `json:"runs"`
+	Results []benchResult `json:"results"`
+}
+
+// benchResult is the fastest of the runs of a single viewport at a single size. Times are in milliseconds.
+type benchResult struct {
+	Viewport        string  `json:"viewport"`
+	Width           int     `json:"width"`
+	Height          int     `json:"height"`
+	Iterations      int     `json:"iterations"`
+	IterateMS       float64 `json:"iterate_ms"`
+	ColourMS        float64 `json:"colour_ms"`
+	TotalMS         float64 `json:"total_ms"`
+	PixelsPerSecond float64 `json:"pixels_per_second"`
+}
+
+// renders the initial view and each preset location at each of benchSizes without opening a window, and writes the
+// timings of the iterate and colour stages to stdout as JSON
+func bench() error {
+	if benchRuns == 0 {
+		return fmt.Errorf("at least 1 run is required")
+	}
+
+	// the initial view is captured before any preset changes the settings
+	viewports := []struct {
+		name     string
+		bookmark bookmark
+	}{
+		{name: "initial", bookmark: newBookmark(mandelbrotBounds.Moved(initialOffset))},
+	}
+	viewports = append(viewports, locations...)
+
+	report := benchReport{CPUs: runtime.GOMAXPROCS(0), Runs: benchRuns}
+	for _, v := range viewports {
+		for _, size := range benchSizes {
+			windowBounds := pixel.R(0, 0, float64(size), float64(size))
+			p := newParams(v.bookmark.apply(windowBounds), windowBounds.Size())
+
+			result, err := benchParams(p)
+			if err != nil {
+				return fmt.Errorf("failed to render %s at %dx%d: %s", v.name, size, size, err)
+			}
+			result.Viewport = v.name
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(report)
+}
+
+// renders p benchRuns times, returning the fastest time of each stage
+func benchParams(p render.Params) (benchResult, error) {
+	var iterateTime, colourTime time.Duration
+	for i := uint(0); i < benchRuns; i++ {
+		start := time.Now()
+		s, err := render.Iterate(context.Background(), p)
+		if err != nil {
+			return benchResult{}, err
+		}
+		iterated := time.Now()
+		s.Image()
+		coloured := time.Now()
+
+		if d := iterated.Sub(start); i == 0 || d < iterateTime {
+			iterateTime = d
+		}
+		if d := coloured.Sub(iterated); i == 0 || d < colourTime {
+			colourTime = d
+		}
+	}
+
+	total := iterateTime + colourTime
+	return benchResult{
+		Width:           p.Width,
+		Height:          p.Height,
+		Iterations:      p.Iterations,
+		IterateMS:       milliseconds(iterateTime),
+		ColourMS:        milliseconds(colourTime),
+		TotalMS:         milliseconds(total),
+		PixelsPerSecond: float64(p.Width*p.Height) / total.Seconds(),
+	}, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// the actions which can be bound to keys
+const (
+	actionQuit           = "quit"
+	actionPanLeft        = "pan-left"
+	actionPanRight       = "pan-right"
+	actionPanUp          = "pan-up"
+	actionPanDown        = "pan-down"
+	actionZoomIn         = "zoom-in"
+	actionZoomOut        = "zoom-out"
+	actionIterationsUp   = "iterations-up"
+	actionIterationsDown = "iterations-down"
+	actionCycleFractal   = "cycle-fractal"
+	actionCycleColouring = "cycle-colouring"
+	actionCyclePalette   = "cycle-palette"
+	actionCycleInterior  = "cycle-interior"
+	actionCycleQuality   = "cycle-quality"
+	actionToggleHUD      = "toggle-hud"
+	actionTogglePanel    = "toggle-panel"
+	actionToggleVSync    = "toggle-vsync"
+	actionToggleJulia    = "toggle-julia"
+	actionPickSeed       = "pick-julia-seed"
+	actionMeasure        = "measure"
+	actionToggleCross    = "toggle-crosshair"
+	actionToggleOrbit    = "toggle-orbit"
+	actionCycleOverlay   = "cycle-overlay"
+	actionCopyPoint      = "copy-point"
+	actionExport         = "export"
+	actionSaveBookmark   = "save-bookmark"
+	actionLoadBookmark   = "load-bookmark"
+	actionNextLocation   = "next-location"
+	actionShare          = "share"
+	actionExplore        = "explore"
+	actionAutoExplore    = "auto-explore"
+	actionReset          = "reset"
+	actionRestoreSession = "restore-session"
+	actionBack           = "back"
+	actionForward        = "forward"
+)
+
+// the keys bound to each action unless overridden by a bindings file, named as pixelgl names them
+var defaultBindings = map[string][]string{
+	actionQuit:           {"Escape"},
+	actionPanLeft:        {"A"},
+	actionPanRight:       {"D"},
+	actionPanUp:          {"W"},
+	actionPanDown:        {"S"},
+	actionZoomIn:         {"R"},
+	actionZoomOut:        {"F"},
+	actionIterationsUp:   {"Equal", "KPAdd"},
+	actionIterationsDown: {"Minus", "KPSubtract"},
+	actionCycleFractal:   {"T"},
+	actionCycleColouring: {"C"},
+	actionCyclePalette:   {"P"},
+	actionCycleInterior:  {"I"},
+	actionCycleQuality:   {"Q"},
+	actionToggleHUD:      {"H"},
+	actionTogglePanel:    {"U"},
+	actionToggleVSync:    {"V"},
+	actionToggleJulia:    {"J"},
+	actionPickSeed:       {"Shift+J"},
+	actionMeasure:        {"M"},
+	actionToggleCross:    {"N"},
+	actionToggleOrbit:    {"O"},
+	actionCycleOverlay:   {"F3"},
+	actionCopyPoint:      {"Ctrl+C"},
+	actionExport:         {"X"},
+	actionSaveBookmark:   {"B"},
+	actionLoadBookmark:   {"L"},
+	actionNextLocation:   {"G"},
+	actionShare:          {"K"},
+	actionExplore:        {"E"},
+	actionAutoExplore:    {"Shift+E"},
+	actionReset:          {"Home"},
+	actionRestoreSession: {"Shift+Home"},
+	actionBack:           {"Backspace"},
+	actionForward:        {"Shift+Backspace"},
+}
+
+// the modifiers which can prefix a key name, e.g. Shift+Backspace, and the left and right keys for each
+var modifiers = map[string][2]pixelgl.Button{
+	"Shift": {pixelgl.KeyLeftShift, pixelgl.KeyRightShift},
+	"Ctrl":  {pixelgl.KeyLeftControl, pixelgl.KeyRightControl},
+	"Alt":   {pixelgl.KeyLeftAlt, pixelgl.KeyRightAlt},
+}
+
+var (
+	bindingsPath string
+	// the key bindings in use
+	keys keyBindings
+)
+
+// keyBindings maps actions to the keys which trigger them
+type keyBindings map[string][]keyBinding
+
+// keyBinding is a key which triggers an action, optionally only while modifier keys are held
+type keyBinding struct {
+	button    pixelgl.Button
+	modifiers [][2]pixelgl.Button
+}
+
+// creates the default bindings, overridden by those in the JSON file at path if it isn't empty. The file maps action
+// names to lists of key names, e.g. {"zoom-in": ["Up"], "zoom-out": ["Ctrl+Up"]}, and actions it leaves out keep
+// their default keys.
+func loadBindings(path string) (keyBindings, error) {
+	names := make(map[string][]string, len(defaultBindings))
+	for action, keys := range defaultBindings {
+		names[action] = keys
+	}
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var overrides map[string][]string
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return nil, fmt.Errorf("invalid bindings file %s: %s", path, err)
+		}
+		for action, keys := range overrides {
+			if _, ok := defaultBindings[action]; !ok {
+				return nil, fmt.Errorf("invalid bindings file %s: unknown action %q, expected one of %s", path, action, strings.Join(bindingActions(), ", "))
+			}
+			names[action] = keys
+		}
+	}
+
+	buttons := buttonsByName()
+	bindings := make(keyBindings, len(names))
+	for action, keys := range names {
+		for _, key := range keys {
+			b, err := parseBinding(key, buttons)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %q bound to %s: %s", key, action, err)
+			}
+			bindings[action] = append(bindings[action], b)
+		}
+	}
+	return bindings, nil
+}
+
+// parses a key name with optional modifier prefixes, e.g. Ctrl+Shift+S
+func parseBinding(key string, buttons map[string]pixelgl.Button) (keyBinding, error) {
+	parts := strings.Split(key, "+")
+	b, ok := buttons[parts[len(parts)-1]]
+	if !ok {
+		return keyBinding{}, fmt.Errorf("unknown key %q", parts[len(parts)-1])
+	}
+
+	binding := keyBinding{button: b}
+	for _, name := range parts[:len(parts)-1] {
+		m, ok := modifiers[name]
+		if !ok {
+			return keyBinding{}, fmt.Errorf("unknown modifier %q, expected Shift, Ctrl or Alt", name)
+		}
+		binding.modifiers = append(binding.modifiers, m)
+	}
+	return binding, nil
+}
+
+// reports whether the binding's modifier keys are all held
+func (b keyBinding) modifiersHeld(win *pixelgl.Window) bool {
+	for _, m := range b.modifiers {
+		if !win.Pressed(m[0]) && !win.Pressed(m[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the names of the bindable actions in alphabetical order
+func bindingActions() []string {
+	actions := make([]string, 0, len(defaultBindings))
+	for action := range defaultBindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// maps pixelgl's button names back to the buttons
+func buttonsByName() map[string]pixelgl.Button {
+	buttons := make(map[string]pixelgl.Button)
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		if name := b.String(); name != "Invalid" {
+			buttons[name] = b
+		}
+	}
+	return buttons
+}
+
+// reports whether any of the action's keys are held down
+func (k keyBindings) pressed(win *pixelgl.Window, action string) bool {
+	for _, b := range k[action] {
+		if win.Pressed(b.button) && b.modifiersHeld(win) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the actions whose keys were pressed since the last update, or also auto-repeated if repeat is set, along
+// with those of gamepadBindings pressed on pad. Of the actions bound to the same key, only those whose modifiers are
+// the most specific held are triggered, so Shift+Home restores the session without also triggering Home's reset.
+func (k keyBindings) triggered(win *pixelgl.Window, pad *gamepad, repeat bool) map[string]bool {
+	type hit struct {
+		action  string
+		binding keyBinding
+	}
+	var hits []hit
+	// the most modifiers held by the triggered bindings of each key
+	most := make(map[pixelgl.Button]int)
+	for action, bindings := range k {
+		for _, b := range bindings {
+			pressed := win.JustPressed(b.button) || repeat && win.Repeated(b.button)
+			if !pressed || !b.modifiersHeld(win) {
+				continue
+			}
+			hits = append(hits, hit{action, b})
+			if n, ok := most[b.button]; !ok || len(b.modifiers) > n {
+				most[b.button] = len(b.modifiers)
+			}
+		}
+	}
+
+	actions := make(map[string]bool)
+	for _, h := range hits {
+		if len(h.binding.modifiers) == most[h.binding.button] {
+			actions[h.action] = true
+		}
+	}
+	for action, button := range gamepadBindings {
+		if pad.justPressed(win, button) {
+			actions[action] = true
+		}
+	}
+	return actions
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+func TestParseBinding(t *testing.T) {
+	shift, ctrl := modifiers["Shift"], modifiers["Ctrl"]
+	tests := []struct {
+		name    string
+		key     string
+		want    keyBinding
+		wantErr bool
+	}{
+		{name: "key", key: "A", want: keyBinding{button: pixelgl.KeyA}},
+		{name: "named key", key: "Backspace", want: keyBinding{button: pixelgl.KeyBackspace}},
+		{name: "modifier", key: "Shift+Home",
+			want: keyBinding{button: pixelgl.KeyHome, modifiers: [][2]pixelgl.Button{shift}}},
+		{name: "modifiers", key: "Ctrl+Shift+S",
+			want: keyBinding{button: pixelgl.KeyS, modifiers: [][2]pixelgl.Button{ctrl, shift}}},
+		{name: "empty", key: "", wantErr: true},
+		{name: "unknown key", key: "Hyper", wantErr: true},
+		{name: "lower case key", key: "a", wantErr: true},
+		{name: "unknown modifier", key: "Super+A", wantErr: true},
+		{name: "missing key", key: "Ctrl+", wantErr: true},
+		{name: "modifier as key", key: "Shift", wantErr: true},
+	}
+	buttons := buttonsByName()
+	for _, tt := range tests {
+		got, err := parseBinding(tt.key, buttons)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error parsing %q, got %+v", tt.name, tt.key, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error parsing %q: %s", tt.name, tt.key, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q to parse as %+v, got %+v", tt.name, tt.key, tt.want, got)
+		}
+	}
+}
+
+func TestLoadBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{name: "override", file: `{"zoom-in": ["Up"], "zoom-out": ["Ctrl+Up"]}`},
+		{name: "unbound", file: `{"quit": []}`},
+		{name: "invalid JSON", file: `{"zoom-in": "Up"`, wantErr: true},
+		{name: "unknown action", file: `{"zoom-sideways": ["Up"]}`, wantErr: true},
+		{name: "unknown key", file: `{"zoom-in": ["Hyper"]}`, wantErr: true},
+	}
+
+	dir, err := ioutil.TempDir("", "bindings")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bindings.json")
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(path, []byte(tt.file), 0644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_, err := loadBindings(path)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error loading %s", tt.name, tt.file)
+		} else if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error loading %s: %s", tt.name, tt.file, err)
+		}
+	}
+
+	// the overridden actions take the file's keys, and the rest keep their defaults
+	if err := ioutil.WriteFile(path, []byte(tests[0].file), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	bindings, err := loadBindings(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := bindings[actionZoomIn]; len(got) != 1 || got[0].button != pixelgl.KeyUp {
+		t.Errorf("expected zoom-in to be bound to Up, got %+v", got)
+	}
+	if got := bindings[actionQuit]; len(got) != 1 || got[0].button != pixelgl.KeyEscape {
+		t.Errorf("expected quit to keep its default Escape, got %+v", got)
+	}
+}
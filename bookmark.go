@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/palette"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	bookmarkPath string
+	loadPath     string
+)
+
+// bookmark is a serialisable snapshot of the exploration state
+type bookmark struct {
+	Centre     point   `json:"centre"`
+	Zoom       float64 `json:"zoom"`
+	Iterations uint    `json:"iterations"`
+	Adaptive   bool    `json:"adaptive"`
+	Fractal    string  `json:"fractal,omitempty"`
+	Exponent   float64 `json:"exponent,omitempty"`
+	Formula    string  `json:"formula,omitempty"`
+	Polynomial string  `json:"polynomial,omitempty"`
+	Bailout    float64 `json:"bailout,omitempty"`
+	Norm       string  `json:"norm,omitempty"`
+	Colouring  string  `json:"colouring,omitempty"`
+	Contrast   uint    `json:"contrast,omitempty"`
+	Palette    string  `json:"palette,omitempty"`
+	Interior   string  `json:"interior,omitempty"`
+	Trap       string  `json:"trap,omitempty"`
+	// the colour levels, where a zero luminance contrast or gamma is the neutral 1
+	Brightness        float64 `json:"brightness,omitempty"`
+	LuminanceContrast float64 `json:"luminance_contrast,omitempty"`
+	Gamma             float64 `json:"gamma,omitempty"`
+	// the Julia set seed, only captured in split view
+	Seed *point `json:"seed,omitempty"`
+}
+
+// point is a position on the complex plane
+type point struct {
+	Re float64 `json:"re"`
+	Im float64 `json:"im"`
+}
+
+// captures the current view
+func newBookmark(bounds pixel.Rect) bookmark {
+	c := bounds.Center()
+	b := bookmark{
+		Centre:     point{Re: c.X, Im: c.Y},
+		Zoom:       zoomLevel(bounds),
+		Iterations: iterations,
+		Adaptive:   adaptive,
+		Fractal:    fractalName,
+		Exponent:   exponent,
+		Formula:    formula,
+		Polynomial: polynomial,
+		Bailout:    bailout,
+		Norm:       norm,
+		Colouring:  colouring,
+		Contrast:   contrast,
+		Palette:    paletteName,
+		Interior:   interior,
+		Trap:       trap,
+
+		Brightness:        brightness,
+		LuminanceContrast: bookmarkLevel(levelsContrast),
+		Gamma:             bookmarkLevel(gamma),
+	}
+	if splitView {
+		b.Seed = &point{Re: juliaSeed.X, Im: juliaSeed.Y}
+	}
+	return b
+}
+
+// returns a luminance contrast or gamma as captured in a bookmark, leaving the neutral 1 as zero so that it's left out
+func bookmarkLevel(v float64) float64 {
+	if v == 1 {
+		return 0
+	}
+	return v
+}
+
+// returns the plane bounds the bookmark describes when viewed in a window of the given size
+func (b bookmark) bounds(windowBounds pixel.Rect) pixel.Rect {
+	// the zoom level is relative to the shorter side of the window
+	span := defaultSpan / b.Zoom
+	size := windowBounds.Size().Scaled(span / math.Min(windowBounds.W(), windowBounds.H()))
+	return centredRect(pixel.V(b.Centre.Re, b.Centre.Im), size)
+}
+
+// applies the bookmark's settings to the current state, returning the new plane bounds
+func (b bookmark) apply(windowBounds pixel.Rect) pixel.Rect {
+	iterations = b.Iterations
+	adaptive = b.Adaptive
+	if b.Fractal != "" {
+		fractalName = b.Fractal
+	}
+	if b.Exponent != 0 {
+		exponent = b.Exponent
+	}
+	// bookmarks without a formula are of one of the built in fractals
+	formula = b.Formula
+	if b.Polynomial != "" {
+		polynomial = b.Polynomial
+	}
+	if b.Bailout != 0 {
+		bailout = b.Bailout
+	}
+	if b.Norm != "" {
+		norm = b.Norm
+	}
+	if b.Colouring != "" {
+		colouring = b.Colouring
+	}
+	if b.Contrast != 0 {
+		contrast = b.Contrast
+	}
+	if b.Palette != "" {
+		paletteName = b.Palette
+	}
+	if b.Interior != "" {
+		interior = b.Interior
+	}
+	if b.Trap != "" {
+		trap = b.Trap
+	}
+	// bookmarks without levels are of a view without any adjustment
+	brightness, levelsContrast, gamma = b.Brightness, 1, 1
+	if b.LuminanceContrast != 0 {
+		levelsContrast = b.LuminanceContrast
+	}
+	if b.Gamma != 0 {
+		gamma = b.Gamma
+	}
+	if b.Seed != nil {
+		juliaSeed = pixel.V(b.Seed.Re, b.Seed.Im)
+	}
+	return b.bounds(windowBounds)
+}
+
+func saveBookmark(path string, b bookmark) error {
+	data, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func loadBookmark(path string) (bookmark, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return bookmark{}, err
+	}
+
+	var b bookmark
+	if err := json.Unmarshal(data, &b); err != nil {
+		return bookmark{}, fmt.Errorf("invalid bookmark file %s: %s", path, err)
+	}
+	if err := b.validate(); err != nil {
+		return bookmark{}, fmt.Errorf("invalid bookmark file %s: %s", path, err)
+	}
+	return b, nil
+}
+
+// checks the bookmark's settings are all valid
+func (b bookmark) validate() error {
+	if !finite(b.Zoom) || b.Zoom <= 0 {
+		return fmt.Errorf("zoom must be positive")
+	}
+	if !finite(b.Centre.Re) || !finite(b.Centre.Im) {
+		return fmt.Errorf("centre must be finite")
+	}
+	if b.Seed != nil && (!finite(b.Seed.Re) || !finite(b.Seed.Im)) {
+		return fmt.Errorf("julia seed must be finite")
+	}
+	if b.Iterations == 0 {
+		return fmt.Errorf("iterations must be positive")
+	}
+	if b.Fractal != "" && !render.IsFractal(b.Fractal) {
+		return fmt.Errorf("unknown fractal %q", b.Fractal)
+	}
+	if b.Formula != "" {
+		if err := render.CheckFormula(b.Formula); err != nil {
+			return fmt.Errorf("invalid formula %q: %s", b.Formula, err)
+		}
+	}
+	if b.Polynomial != "" {
+		if err := render.CheckPolynomial(b.Polynomial); err != nil {
+			return fmt.Errorf("invalid polynomial %q: %s", b.Polynomial, err)
+		}
+	}
+	if b.Bailout < 0 {
+		return fmt.Errorf("bailout must not be negative")
+	}
+	if b.Norm != "" && !render.IsNorm(b.Norm) {
+		return fmt.Errorf("unknown bailout norm %q", b.Norm)
+	}
+	if b.Colouring != "" && !render.IsColouring(b.Colouring) {
+		return fmt.Errorf("unknown colouring %q", b.Colouring)
+	}
+	if b.Contrast > 255 {
+		return fmt.Errorf("contrast must be between 1 and 255")
+	}
+	if _, ok := palette.LookupGradient(b.Palette); b.Palette != "" && !ok {
+		return fmt.Errorf("unknown palette %q", b.Palette)
+	}
+	if b.Interior != "" && !render.IsInterior(b.Interior) {
+		return fmt.Errorf("unknown interior colouring %q", b.Interior)
+	}
+	if b.Trap != "" && !render.IsTrap(b.Trap) {
+		return fmt.Errorf("unknown orbit trap %q", b.Trap)
+	}
+	if b.Brightness < -1 || b.Brightness > 1 {
+		return fmt.Errorf("brightness must be between -1 and 1")
+	}
+	if b.LuminanceContrast < 0 || b.Gamma < 0 {
+		return fmt.Errorf("luminance contrast and gamma must be positive")
+	}
+	return nil
+}
+
+// reports whether f is neither NaN nor infinite
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
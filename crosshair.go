@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/faiface/mainthread"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// the gap in pixels left around the cursor by the crosshair, so that the point under it stays visible
+const crosshairGap = 6
+
+var (
+	crosshairColour = pixel.RGB(1, 1, 1)
+	// drawn beneath the crosshair so that it stands out against light regions too
+	crosshairShadow = pixel.RGBA{A: 0.6}
+)
+
+// draws lines across the pane through pos, leaving a gap around it
+func drawCrosshair(imd *imdraw.IMDraw, pos pixel.Vec, paneBounds pixel.Rect) {
+	for _, line := range []struct {
+		colour    pixel.RGBA
+		thickness float64
+	}{{crosshairShadow, 3}, {crosshairColour, 1}} {
+		imd.Color = line.colour
+		imd.Push(pixel.V(paneBounds.Min.X, pos.Y), pixel.V(pos.X-crosshairGap, pos.Y))
+		imd.Line(line.thickness)
+		imd.Push(pixel.V(pos.X+crosshairGap, pos.Y), pixel.V(paneBounds.Max.X, pos.Y))
+		imd.Line(line.thickness)
+		imd.Push(pixel.V(pos.X, paneBounds.Min.Y), pixel.V(pos.X, pos.Y-crosshairGap))
+		imd.Line(line.thickness)
+		imd.Push(pixel.V(pos.X, pos.Y+crosshairGap), pixel.V(pos.X, paneBounds.Max.Y))
+		imd.Line(line.thickness)
+	}
+}
+
+// formats a point on the complex plane as a complex number, e.g. -0.7453+0.1127i, with as many digits as it takes to
+// read back exactly the same float64s
+func formatComplex(v pixel.Vec) string {
+	im := formatFloat(v.Y)
+	if im[0] != '-' {
+		im = "+" + im
+	}
+	return formatFloat(v.X) + im + "i"
+}
+
+// copies s to the system clipboard
+func copyToClipboard(s string) (err error) {
+	// glfw must be called from the main thread, and panics rather than returning errors
+	mainthread.Call(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		glfw.SetClipboardString(s)
+	})
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+// the address to serve profiles and metrics on, if any
+var debugAddr string
+
+// metrics of the frames rendered for the window, published at /debug/vars
+var (
+	framesRendered  = expvar.NewInt("frames_rendered")
+	framesAbandoned = expvar.NewInt("frames_abandoned")
+	frameTime       = expvar.NewFloat("frame_time_seconds_total")
+	tileCacheHits   = expvar.NewInt("tile_cache_hits")
+	tileCacheMisses = expvar.NewInt("tile_cache_misses")
+)
+
+func init() {
+	expvar.Publish("frame_time_seconds_avg", expvar.Func(func() interface{} {
+		if n := framesRendered.Value(); n > 0 {
+			return frameTime.Value() / float64(n)
+		}
+		return 0.0
+	}))
+	expvar.Publish("workers", expvar.Func(func() interface{} {
+		stats := render.ReadWorkStats()
+		return map[string]interface{}{
+			"gomaxprocs":        runtime.GOMAXPROCS(0),
+			"goroutines":        runtime.NumGoroutine(),
+			"busy_seconds":      stats.Busy.Seconds(),
+			"available_seconds": stats.Available.Seconds(),
+			"utilisation":       stats.Utilisation(),
+		}
+	}))
+}
+
+// counts a completed frame which took renderTime and reused hits tiles from the cache, computing misses
+func recordFrame(renderTime time.Duration, hits, misses int) {
+	framesRendered.Add(1)
+	frameTime.Add(renderTime.Seconds())
+	tileCacheHits.Add(int64(hits))
+	tileCacheMisses.Add(int64(misses))
+}
+
+// serves the pprof profiles at /debug/pprof/ and the expvar metrics at /debug/vars on debugAddr
+func serveDebug() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return http.ListenAndServe(debugAddr, mux)
+}
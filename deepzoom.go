@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+// the width and height of the tiles of deep zoom pyramids in pixels
+const deepZoomTileSize = 256
+
+var (
+	deepZoomPath string
+	deepZoomSize uint
+)
+
+// deepZoomLevel is a level of a deep zoom pyramid, the full size image halved in size once for each level below the
+// deepest, down to a single pixel at level 0
+type deepZoomLevel struct {
+	level         int
+	width, height int
+	// the plane units per pixel
+	scale float64
+}
+
+// returns the tiles along each axis of the level
+func (l deepZoomLevel) tiles() (int, int) {
+	return (l.width + deepZoomTileSize - 1) / deepZoomTileSize, (l.height + deepZoomTileSize - 1) / deepZoomTileSize
+}
+
+// returns the levels of a pyramid of an image of the given size, deepest first
+func deepZoomLevels(width, height int, scale float64) []deepZoomLevel {
+	deepest := int(math.Ceil(math.Log2(math.Max(float64(width), float64(height)))))
+	levels := make([]deepZoomLevel, 0, deepest+1)
+	for level := deepest; level >= 0; level-- {
+		f := 1 << (deepest - level)
+		levels = append(levels, deepZoomLevel{
+			level:  level,
+			width:  (width + f - 1) / f,
+			height: (height + f - 1) / f,
+			scale:  scale * float64(f),
+		})
+	}
+	return levels
+}
+
+// returns the params of the tile in column x and row y of the level of a pyramid of full, which every level shares
+// the top left corner of
+func (l deepZoomLevel) tileParams(full render.Params, x, y int) render.Params {
+	p := full
+	p.Scale = l.scale
+	// tiles along the right and bottom edges are cut short by the edges of the image
+	p.Width, p.Height = deepZoomTileSize, deepZoomTileSize
+	if edge := l.width - x*deepZoomTileSize; edge < p.Width {
+		p.Width = edge
+	}
+	if edge := l.height - y*deepZoomTileSize; edge < p.Height {
+		p.Height = edge
+	}
+	topLeft := full.Centre + complex(-float64(full.Width)/2*full.Scale, float64(full.Height)/2*full.Scale)
+	offsetX := float64(x*deepZoomTileSize) + float64(p.Width)/2
+	offsetY := float64(y*deepZoomTileSize) + float64(p.Height)/2
+	p.Centre = topLeft + complex(offsetX*l.scale, -offsetY*l.scale)
+	return p
+}
+
+// renders the view the window would start at as a Deep Zoom Image tile pyramid at deepZoomPath, -deepzoom-size pixels
+// along the longer side at its deepest, with a page next to it for exploring it in a browser
+func writeDeepZoom() error {
+	if ext := strings.ToLower(filepath.Ext(deepZoomPath)); ext != ".dzi" {
+		return fmt.Errorf("unknown deep zoom format %q, expected .dzi", ext)
+	}
+	base := strings.TrimSuffix(deepZoomPath, filepath.Ext(deepZoomPath))
+	tilesDir := base + "_files"
+
+	windowBounds := pixel.R(0, 0, windowSize, windowSize)
+	bounds := startingView(windowBounds, windowBounds)
+	scale := float64(deepZoomSize) / math.Max(windowBounds.W(), windowBounds.H())
+	size := pixel.V(math.Round(windowBounds.W()*scale), math.Round(windowBounds.H()*scale))
+	quality, _ := lookupQuality(exportQualityName)
+	full := quality.apply(newParams(bounds, size))
+
+	levels := deepZoomLevels(full.Width, full.Height, full.Scale)
+	var total int
+	for _, l := range levels {
+		cols, rows := l.tiles()
+		total += cols * rows
+	}
+	fmt.Printf("Rendering a %dx%d deep zoom pyramid of %d tiles to %s\n", full.Width, full.Height, total, tilesDir)
+
+	done := 0
+	for _, l := range levels {
+		dir := filepath.Join(tilesDir, fmt.Sprint(l.level))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		cols, rows := l.tiles()
+		for y := 0; y < rows; y++ {
+			for x := 0; x < cols; x++ {
+				samples, err := iterateImage(context.Background(), l.tileParams(full, x, y))
+				if err != nil {
+					return err
+				}
+				if err := writePNG(filepath.Join(dir, fmt.Sprintf("%d_%d.png", x, y)), samples); err != nil {
+					return err
+				}
+				done++
+				fmt.Printf("\rRendered tile %d/%d", done, total)
+			}
+		}
+	}
+	fmt.Println()
+
+	descriptor := fmt.Sprintf(deepZoomDescriptor, deepZoomTileSize, full.Width, full.Height)
+	if err := ioutil.WriteFile(deepZoomPath, []byte(descriptor), 0644); err != nil {
+		return err
+	}
+	page, err := os.Create(base + ".html")
+	if err != nil {
+		return err
+	}
+	err = deepZoomPage.Execute(page, map[string]interface{}{
+		"Title":    fmt.Sprintf("%s at %.4gx", full.Fractal, zoomLevel(bounds)),
+		"Tiles":    filepath.Base(tilesDir) + "/",
+		"TileSize": deepZoomTileSize,
+		"Width":    full.Width,
+		"Height":   full.Height,
+		"State":    encodeState(newBookmark(bounds)),
+	})
+	if closeErr := page.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s and %s.html\n", deepZoomPath, base)
+	return nil
+}
+
+// colours samples and writes them to path as a PNG
+func writePNG(path string, samples *render.Samples) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, samples.Image())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// the Deep Zoom Image descriptor of a pyramid, given its tile size and the size of its deepest level
+const deepZoomDescriptor = `<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" Format="png" Overlap="0" TileSize="%d">
+    <Size Width="%d" Height="%d"/>
+</Image>
+`
+
+// an OpenSeadragon viewer of a pyramid, noting the state to reopen the view with -from. The descriptor is inlined
+// rather than fetched so that the page works when opened straight from disk, where browsers refuse to fetch files.
+var deepZoomPage = template.Must(template.New("deepzoom").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>{{.Title}}</title>
+    <meta name="mandelbrot-state" content="{{.State}}">
+    <script src="https://cdn.jsdelivr.net/npm/openseadragon@4.1.1/build/openseadragon/openseadragon.min.js"></script>
+    <style>html, body, #viewer { margin: 0; height: 100%; background: #000; }</style>
+</head>
+<body>
+<div id="viewer"></div>
+<script>
+    OpenSeadragon({
+        id: "viewer",
+        prefixUrl: "https://cdn.jsdelivr.net/npm/openseadragon@4.1.1/build/openseadragon/images/",
+        maxZoomPixelRatio: 2,
+        tileSources: {
+            Image: {
+                xmlns: "http://schemas.microsoft.com/deepzoom/2008",
+                Url: "{{.Tiles}}",
+                Format: "png",
+                Overlap: "0",
+                TileSize: "{{.TileSize}}",
+                Size: {Width: "{{.Width}}", Height: "{{.Height}}"}
+            }
+        }
+    });
+</script>
+</body>
+</html>
+`))
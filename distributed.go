@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+const (
+	// the height in pixels of the strips distributed renders are split into
+	distributedStripRows = 32
+	// the number of strips in a row a worker can fail to return before it's left out of the rest of the render
+	workerRetries = 3
+)
+
+var (
+	workerAddr string
+	workerList string
+	// the addresses of the workers offline renders are farmed out to
+	workerAddrs []string
+)
+
+// renders p for offline use, farming strips out to the workers if any are configured
+func renderImage(ctx context.Context, p render.Params) (*image.RGBA, error) {
+	if len(workerAddrs) == 0 {
+		return render.Render(ctx, p)
+	}
+	samples, err := iterateDistributed(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return samples.Image(), nil
+}
+
+// iterates p for offline use without colouring it, farming strips out to the workers if any are configured
+func iterateImage(ctx context.Context, p render.Params) (*render.Samples, error) {
+	if len(workerAddrs) == 0 {
+		return render.Iterate(ctx, p)
+	}
+	return iterateDistributed(ctx, p)
+}
+
+// parses a comma separated list of worker addresses, accepting host:port with or without a scheme
+func parseWorkers(list string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		addrs = append(addrs, strings.TrimSuffix(addr, "/"))
+	}
+	return addrs
+}
+
+// rejectedError is a worker refusing to iterate a strip, rather than failing to reach it, so retrying won't help
+type rejectedError struct {
+	err error
+}
+
+func (e rejectedError) Error() string {
+	return e.err.Error()
+}
+
+// splits p into strips and iterates them across the workers, then joins the results so that they can be coloured
+// locally, keeping colourings which depend on the whole frame seamless. Strips a worker fails to iterate are handed
+// back to all of the workers. A worker which rejects a strip, or fails workerRetries strips in a row, is left out of
+// the rest of the render, and iterating only fails if every worker is.
+func iterateDistributed(ctx context.Context, p render.Params) (*render.Samples, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	strips := render.Split(p, (p.Height+distributedStripRows-1)/distributedStripRows)
+	results := make([]*render.Samples, len(strips))
+	jobs := make(chan int, len(strips))
+	for i := range strips {
+		jobs <- i
+	}
+
+	var (
+		mu        sync.Mutex
+		remaining = len(strips)
+		done      = make(chan struct{})
+		lastErr   error
+		wg        sync.WaitGroup
+	)
+	for _, addr := range workerAddrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			failures := 0
+			for {
+				var i int
+				select {
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				case i = <-jobs:
+				}
+
+				samples, err := requestStrip(ctx, addr, strips[i])
+				if err != nil {
+					// hand the strip back, and leave it to the remaining workers if this one can't iterate it
+					jobs <- i
+					mu.Lock()
+					lastErr = fmt.Errorf("worker %s failed: %s", addr, err)
+					mu.Unlock()
+					if _, rejected := err.(rejectedError); rejected {
+						return
+					}
+					if failures++; failures == workerRetries {
+						return
+					}
+					continue
+				}
+				failures = 0
+
+				mu.Lock()
+				results[i] = samples
+				if remaining--; remaining == 0 {
+					close(done)
+				}
+				mu.Unlock()
+			}
+		}(addr)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("all workers failed, last error: %s", lastErr)
+	}
+
+	return render.Join(p, results)
+}
+
+// asks the worker at addr to iterate the samples of p. Only the settings which affect iterating are sent, as the
+// samples are coloured locally, so workers needn't have the palettes registered here.
+func requestStrip(ctx context.Context, addr string, p render.Params) (*render.Samples, error) {
+	p = p.IterationParams()
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(p); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+"/iterate", &body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, rejectedError{err}
+		}
+		return nil, err
+	}
+
+	samples := &render.Samples{}
+	if err := samples.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if samples.Params != p {
+		return nil, rejectedError{fmt.Errorf("returned samples for different params")}
+	}
+	return samples, nil
+}
+
+// serves POST /iterate requests from a coordinator until the server fails
+func runWorker() error {
+	fmt.Printf("Worker listening on %s\n", workerAddr)
+	return http.ListenAndServe(workerAddr, workerHandler())
+}
+
+// returns the handler of a worker's requests. Requests carry gob encoded render.Params and are answered with the
+// encoded render.Samples.
+func workerHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/iterate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "expected a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var p render.Params
+		if err := gob.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode params: %s", err), http.StatusBadRequest)
+			return
+		}
+		samples, err := render.Iterate(r.Context(), p)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to iterate: %s", err), http.StatusBadRequest)
+			return
+		}
+		data, err := samples.MarshalBinary()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode samples: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	})
+	return mux
+}
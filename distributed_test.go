@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jemgunay/mandelbrot/palette"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+func testStrip() render.Params {
+	return render.Params{
+		Centre:     complex(-0.5, 0),
+		Scale:      4.0 / 64,
+		Width:      64,
+		Height:     48,
+		Iterations: 100,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringSmooth,
+	}
+}
+
+func TestDistributedPalette(t *testing.T) {
+	worker := httptest.NewServer(workerHandler())
+	defer worker.Close()
+
+	// workers only iterate, so they needn't know the palettes the coordinator colours with
+	p := testStrip()
+	p.Palette = "registered-on-the-coordinator-only"
+	samples, err := requestStrip(context.Background(), worker.URL, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if samples.Params != p.IterationParams() {
+		t.Errorf("expected samples iterated with %+v, got %+v", p.IterationParams(), samples.Params)
+	}
+
+	if err := palette.RegisterGradient("distributed", palette.Gradient{
+		{Pos: 0, Colour: color.RGBA{255, 0, 0, 255}},
+		{Pos: 1, Colour: color.RGBA{0, 0, 255, 255}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer func(addrs []string) { workerAddrs = addrs }(workerAddrs)
+	workerAddrs = []string{worker.URL}
+	p.Palette = "distributed"
+	img, err := renderImage(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error rendering across the workers: %s", err)
+	}
+	want, err := render.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(img.Pix) != string(want.Pix) {
+		t.Error("expected the distributed render to match rendering locally")
+	}
+}
+
+// a worker failing its first fail requests with status, or all of them if fail is negative, counting the requests
+type failingWorker struct {
+	status   int
+	fail     int32
+	requests int32
+}
+
+func (w *failingWorker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if n := atomic.AddInt32(&w.requests, 1); w.fail < 0 || n <= w.fail {
+		http.Error(rw, "failing", w.status)
+		return
+	}
+	workerHandler().ServeHTTP(rw, r)
+}
+
+func TestDistributedFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		// the failing worker, alongside a healthy worker if healthy is set
+		failing      failingWorker
+		healthy      bool
+		wantErr      bool
+		wantRequests int32
+	}{
+		{name: "retried", failing: failingWorker{status: http.StatusServiceUnavailable, fail: workerRetries - 1}},
+		{name: "too many failures", failing: failingWorker{status: http.StatusServiceUnavailable, fail: -1},
+			wantErr: true, wantRequests: workerRetries},
+		{name: "rejected", failing: failingWorker{status: http.StatusBadRequest, fail: -1}, wantErr: true,
+			wantRequests: 1},
+		{name: "failures left to healthy worker", failing: failingWorker{status: http.StatusServiceUnavailable, fail: -1},
+			healthy: true},
+		{name: "rejections left to healthy worker", failing: failingWorker{status: http.StatusBadRequest, fail: -1},
+			healthy: true},
+	}
+	defer func(addrs []string) { workerAddrs = addrs }(workerAddrs)
+
+	p := testStrip()
+	want, err := render.Iterate(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, tt := range tests {
+		failing := httptest.NewServer(&tt.failing)
+		workerAddrs = []string{failing.URL}
+		if tt.healthy {
+			healthy := httptest.NewServer(workerHandler())
+			defer healthy.Close()
+			workerAddrs = append(workerAddrs, healthy.URL)
+		}
+
+		samples, err := iterateDistributed(context.Background(), p)
+		failing.Close()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+		} else if string(samples.Image().Pix) != string(want.Image().Pix) {
+			t.Errorf("%s: expected the distributed samples to match iterating locally", tt.name)
+		}
+		if requests := atomic.LoadInt32(&tt.failing.requests); tt.wantRequests != 0 && requests != tt.wantRequests {
+			t.Errorf("%s: expected the failing worker to be sent %d requests, got %d", tt.name, tt.wantRequests, requests)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+const (
+	// the size in pixels of the longer side of the low resolution render searched for detail
+	exploreResolution = 128
+	// the number of cells along each side of the grid the view is searched in
+	exploreGrid = 8
+	// how many times smaller than the view each suggested region is
+	exploreZoom = 4
+	// how many times the autopilot magnifies the view each second
+	exploreZoomSpeed = 1.5
+	// how many of the most detailed regions a randomised search picks between
+	exploreChoices = 4
+	// how many times the screensaver magnifies the view each second, slower than the autopilot for a calmer journey
+	screensaverZoomSpeed = 1.2
+	// how long the screensaver waits after the view was last moved by hand before taking back control
+	screensaverIdle = 30 * time.Second
+)
+
+var (
+	// whether to start with the autopilot on
+	autoExplore bool
+	// whether to run as a screensaver, zooming into randomly picked detailed regions forever
+	screensaver bool
+
+	exploreColour = pixel.RGB(1, 0.8, 0)
+)
+
+// explorer searches the view for the most detailed region to zoom into. It either suggests the region to the user or,
+// on autopilot, zooms into it then searches again, journeying deeper without interaction.
+type explorer struct {
+	// the region of the plane to zoom into, if one has been suggested
+	target    pixel.Rect
+	suggested bool
+	// whether to keep zooming into suggestions, and how many times to magnify the view each second when doing so
+	auto      bool
+	zoomSpeed float64
+	// picks between the most detailed regions at random if not nil, otherwise the most detailed is always picked
+	random *rand.Rand
+	// receives the region found by the search in progress, or an empty rect if there's nothing worth exploring. It's
+	// nil when there's no search in progress.
+	found chan pixel.Rect
+}
+
+// starts searching the view described by bounds for a detailed region in the background, unless a search is already
+// in progress
+func (e *explorer) search(bounds pixel.Rect, windowSize pixel.Vec) {
+	if e.found != nil {
+		return
+	}
+	scale := exploreResolution / math.Max(windowSize.X, windowSize.Y)
+	p := newParams(bounds, pixel.V(math.Ceil(windowSize.X*scale), math.Ceil(windowSize.Y*scale)))
+	// the rand isn't safe for concurrent use, so pick which of the regions to take up front
+	choice := 0
+	if e.random != nil {
+		choice = e.random.Intn(exploreChoices)
+	}
+
+	found := make(chan pixel.Rect, 1)
+	e.found = found
+	go func() {
+		regions, err := render.DetailedRegions(context.Background(), p, exploreGrid)
+		if err != nil || regions[0].Detail == 0 {
+			found <- pixel.Rect{}
+			return
+		}
+		// only regions with some detail are worth picking
+		for choice > 0 && regions[choice].Detail == 0 {
+			choice--
+		}
+		c := regions[choice].Centre
+		found <- centredRect(pixel.V(real(c), imag(c)), bounds.Size().Scaled(1.0/exploreZoom))
+	}()
+}
+
+// collects the result of the search in progress if it has finished, reporting whether it found nothing worth exploring
+func (e *explorer) poll() bool {
+	select {
+	case target := <-e.found:
+		e.found = nil
+		if target.Area() == 0 {
+			fmt.Println("Found nothing to explore here")
+			e.stop()
+			return true
+		}
+		e.target, e.suggested = target, true
+		if !e.auto {
+			fmt.Println("Press explore again to zoom into the highlighted region")
+		}
+	default:
+	}
+	return false
+}
+
+// moves bounds towards the target at a steady zoom speed, dt seconds after the last step, reporting whether it has
+// been reached. The centre moves in proportion to the magnification left, so that both arrive together.
+func (e *explorer) approach(bounds pixel.Rect, dt float64) (pixel.Rect, bool) {
+	remaining := math.Log(bounds.W() / e.target.W())
+	step := math.Log(e.zoomSpeed) * dt
+	if remaining <= step {
+		return e.target, true
+	}
+	centre := pixel.Lerp(bounds.Center(), e.target.Center(), step/remaining)
+	return centredRect(centre, bounds.Size().Scaled(math.Exp(-step))), false
+}
+
+// stops the autopilot and withdraws any suggestion. A search in progress is left to finish, but its result is only
+// suggested rather than zoomed into.
+func (e *explorer) stop() {
+	e.auto, e.suggested = false, false
+}
+
+// outlines the suggested region, given the window region the plane bounds are drawn in
+func (e *explorer) draw(imd *imdraw.IMDraw, windowBounds, bounds pixel.Rect) {
+	if !e.suggested || e.auto {
+		return
+	}
+	imd.Color = exploreColour
+	imd.Push(planeToWindow(e.target.Min, windowBounds, bounds), planeToWindow(e.target.Max, windowBounds, bounds))
+	imd.Rectangle(1)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+// the height in pixels of the strips exports are rendered in, bounding the memory used by large exports
+const exportStripRows = 256
+
+var (
+	exportPath string
+	exportSize uint
+	// held while an export is in progress so that only one runs at a time
+	exportMu sync.Mutex
+)
+
+// describes a render of bounds at exportSize pixels along the longer side, keeping the aspect ratio of the window size,
+// at the export quality
+func exportParams(bounds pixel.Rect, windowSize pixel.Vec) render.Params {
+	scale := float64(exportSize) / math.Max(windowSize.X, windowSize.Y)
+	size := pixel.V(math.Round(windowSize.X*scale), math.Round(windowSize.Y*scale))
+	quality, _ := lookupQuality(exportQualityName)
+	return quality.apply(newParams(bounds, size))
+}
+
+// renders p and writes it to exportPath as a PNG, embedding the render parameters and the bookmark b of the view as
+// text chunks so that it can be reopened later
+func exportView(p render.Params, b bookmark) error {
+	if !exportMu.TryLock() {
+		return fmt.Errorf("an export is already in progress")
+	}
+	defer exportMu.Unlock()
+
+	fmt.Printf("Exporting %dx%d image to %s\n", p.Width, p.Height, exportPath)
+	err := writeImage(exportPath, p, b, func(done, total int) {
+		fmt.Printf("\rRendered strip %d/%d", done, total)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %s\n", exportPath)
+	return nil
+}
+
+// renders p and writes it to path as a PNG with the metadata of exportView, calling progress if it isn't nil after each
+// strip is rendered. The file is removed if the render fails.
+func writeImage(path string, p render.Params, b bookmark, progress func(done, total int)) error {
+	r, err := render.NewStripRenderer(p, exportStripRows)
+	if err != nil {
+		return err
+	}
+	if len(workerAddrs) > 0 {
+		r.Iterate = iterateDistributed
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	img := &stripImage{renderer: r, bounds: image.Rect(0, 0, p.Width, p.Height), index: -1, progress: progress}
+	w := &pngTextWriter{w: f}
+	err = png.Encode(w, img)
+	if img.err != nil {
+		err = img.err
+	}
+	if err == nil {
+		err = w.finish(exportMetadata(p, b, time.Since(start)))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// stripImage is an image which renders its strips on demand, holding only the current strip in memory. It must be read
+// from top to bottom, which suits encoders that write images a row at a time such as image/png.
+type stripImage struct {
+	renderer *render.StripRenderer
+	bounds   image.Rectangle
+
+	// the strip currently being read and its index
+	strip *image.RGBA
+	index int
+	// called with the number of strips rendered so far, if not nil
+	progress func(done, total int)
+	// the first error encountered rendering a strip, after which every pixel reads as transparent
+	err error
+}
+
+func (s *stripImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (s *stripImage) Bounds() image.Rectangle {
+	return s.bounds
+}
+
+// Opaque stops image/png scanning the whole image up front to decide whether to store an alpha channel.
+func (s *stripImage) Opaque() bool {
+	return false
+}
+
+func (s *stripImage) At(x, y int) color.Color {
+	if s.err != nil {
+		return color.RGBA{}
+	}
+	// move on to the strip containing the row once the current one has been read
+	for s.strip == nil || y >= s.strip.Bounds().Max.Y {
+		if s.index+1 >= s.renderer.Len() {
+			return color.RGBA{}
+		}
+		s.index++
+		if s.strip, s.err = s.renderer.Strip(context.Background(), s.index); s.err != nil {
+			return color.RGBA{}
+		}
+		if s.progress != nil {
+			s.progress(s.index+1, s.renderer.Len())
+		}
+	}
+	return s.strip.RGBAAt(x, y)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+const (
+	// how far a stick or trigger has to move from rest before it registers, hiding the drift of worn controllers
+	gamepadDeadZone = 0.15
+	// the speed in window widths per second the view pans at with the left stick pushed all the way
+	gamepadPanSpeed = 0.75
+	// how many times over the view zooms per second with a trigger pulled all the way
+	gamepadZoomRate = 2.5
+	// joysticks which glfw recognises as gamepads report this many buttons and axes in the standard layout
+	gamepadButtons = int(pixelgl.ButtonDpadLeft) + 1
+	gamepadAxes    = int(pixelgl.AxisRightTrigger) + 1
+)
+
+// the actions triggered by the controller's buttons as well as by their keys
+var gamepadBindings = map[string]pixelgl.GamepadButton{
+	actionCyclePalette: pixelgl.ButtonA,
+	actionExport:       pixelgl.ButtonX,
+}
+
+// gamepad navigates with the first connected controller: the left stick pans, the right trigger zooms in and the left
+// trigger zooms out, with speed following how far they're pushed.
+type gamepad struct {
+	js        pixelgl.Joystick
+	connected bool
+	// the pan distance in window pixels and zoom factor for the current frame
+	pan  pixel.Vec
+	zoom float64
+}
+
+// picks up the controller's sticks and triggers for the frame, dt seconds after the last, over a view width window
+// pixels wide
+func (g *gamepad) update(win *pixelgl.Window, dt, width float64) {
+	g.connected = false
+	for js := pixelgl.Joystick1; js <= pixelgl.JoystickLast; js++ {
+		// joysticks without a gamepad mapping report their axes in any order, so leave them alone
+		if win.JoystickPresent(js) && win.JoystickButtonCount(js) == gamepadButtons && win.JoystickAxisCount(js) == gamepadAxes {
+			g.js, g.connected = js, true
+			break
+		}
+	}
+	g.pan, g.zoom = pixel.ZV, 1
+	if !g.connected {
+		return
+	}
+
+	// the stick's y axis points down the window, and the view moves the way the stick is pushed
+	stick := pixel.V(win.JoystickAxis(g.js, pixelgl.AxisLeftX), -win.JoystickAxis(g.js, pixelgl.AxisLeftY))
+	if l := stick.Len(); l > 0 {
+		g.pan = stick.Scaled(deadZone(math.Min(l, 1)) / l * gamepadPanSpeed * width * dt)
+	}
+
+	// triggers rest at -1 and are pulled all the way at 1
+	in := deadZone((win.JoystickAxis(g.js, pixelgl.AxisRightTrigger) + 1) / 2)
+	out := deadZone((win.JoystickAxis(g.js, pixelgl.AxisLeftTrigger) + 1) / 2)
+	g.zoom = math.Pow(gamepadZoomRate, (out-in)*dt)
+}
+
+// reports whether the sticks or triggers are moving the view
+func (g *gamepad) active() bool {
+	return g.pan != pixel.ZV || g.zoom != 1
+}
+
+// reports whether button was pressed on the controller since the last update
+func (g *gamepad) justPressed(win *pixelgl.Window, button pixelgl.GamepadButton) bool {
+	return g.connected && win.JoystickJustPressed(g.js, button)
+}
+
+// maps how far a stick or trigger is pushed, from 0 to 1, to a speed from 0 to 1. Inputs within the dead zone are
+// ignored and the rest is squared, giving finer control when pushed gently.
+func deadZone(v float64) float64 {
+	if v < gamepadDeadZone {
+		return 0
+	}
+	v = (v - gamepadDeadZone) / (1 - gamepadDeadZone)
+	return v * v
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/faiface/glhf"
+	"github.com/faiface/mainthread"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jemgunay/mandelbrot/palette"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+// the smallest pixel spacing, relative to the magnitude of the coordinates, that float32 arithmetic in the shader can
+// resolve before neighbouring pixels collapse into blocks
+const gpuMinRelativeSpacing = 1.0 / (1 << 20)
+
+// shader identifiers for each of the render package's fractals
+var gpuFractals = map[string]int32{
+	"mandelbrot":   0,
+	"burning-ship": 1,
+	"tricorn":      2,
+	"multibrot":    3,
+}
+
+// shader identifiers for each of the render package's bailout norms
+var gpuNorms = map[string]int32{
+	render.NormEuclidean: 0,
+	render.NormManhattan: 1,
+	render.NormImaginary: 2,
+}
+
+// evaluates the escape-time algorithm per fragment. The formulas mirror those in the render package and the colouring
+// mirrors palette.Escape.
+var mandelbrotFragmentShader = fmt.Sprintf(`
+#version 330 core
+
+in vec2 vPosition;
+
+out vec4 fragColor;
+
+uniform vec2 uMin;
+uniform vec2 uSize;
+uniform vec2 uResolution;
+uniform int uIterations;
+uniform int uFractal;
+uniform float uExponent;
+uniform int uSamples;
+uniform float uBailout;
+uniform int uNorm;
+uniform float uContrast;
+uniform float uBrightness;
+uniform float uLevelsContrast;
+uniform float uGamma;
+
+vec2 iterate(vec2 z, vec2 c) {
+	if (uFractal == %d) {
+		z = abs(z);
+	} else if (uFractal == %d) {
+		z.y = -z.y;
+	} else if (uFractal == %d) {
+		float r = length(z);
+		if (r == 0) {
+			return c;
+		}
+		float theta = atan(z.y, z.x) * uExponent;
+		return pow(r, uExponent) * vec2(cos(theta), sin(theta)) + c;
+	}
+	return vec2(z.x*z.x - z.y*z.y, 2*z.x*z.y) + c;
+}
+
+// mirrors palette.Levels
+vec3 adjust(vec3 rgb) {
+	rgb = (pow(rgb, vec3(1 / uGamma)) - 0.5) * uLevelsContrast + 0.5;
+	return clamp(rgb + uBrightness, 0, 1);
+}
+
+bool escaped(vec2 z) {
+	if (uNorm == %d) {
+		return abs(z.x) + abs(z.y) > uBailout;
+	} else if (uNorm == %d) {
+		return abs(z.y) > uBailout;
+	}
+	return dot(z, z) > uBailout * uBailout;
+}
+
+vec4 colour(vec2 c) {
+	vec2 z = vec2(0, 0);
+
+	for (int n = 0; n < uIterations; n++) {
+		z = iterate(z, c);
+
+		if (escaped(z)) {
+			float shade = mod(float(n) * uContrast, 256);
+			vec3 rgb = vec3(mod(60 + 256 - shade, 256), mod(180 + 256 - shade, 256), shade) / 255;
+			return vec4(adjust(rgb), 1);
+		}
+	}
+	return vec4(0, 0, 0, 1);
+}
+
+void main() {
+	// vPosition is the fragment's pixel centre, so step back to its corner to spread the samples across it
+	vec2 corner = vPosition - vec2(0.5, 0.5);
+	vec4 sum = vec4(0, 0, 0, 0);
+
+	for (int sy = 0; sy < uSamples; sy++) {
+		for (int sx = 0; sx < uSamples; sx++) {
+			vec2 offset = (vec2(sx, sy) + vec2(0.5, 0.5)) / float(uSamples);
+			sum += colour(uMin + (corner + offset) / uResolution * uSize);
+		}
+	}
+	fragColor = sum / float(uSamples * uSamples);
+}
+`, gpuFractals["burning-ship"], gpuFractals["tricorn"], gpuFractals["multibrot"], gpuNorms[render.NormManhattan],
+	gpuNorms[render.NormImaginary])
+
+// the vertex format and shader pixelgl uses for canvases, required to compile the fragment shader standalone
+var (
+	canvasVertexFormat = glhf.AttrFormat{
+		{Name: "aPosition", Type: glhf.Vec2},
+		{Name: "aColor", Type: glhf.Vec4},
+		{Name: "aTexCoords", Type: glhf.Vec2},
+		{Name: "aIntensity", Type: glhf.Float},
+	}
+	canvasVertexShader = `
+#version 330 core
+
+in vec2  aPosition;
+in vec4  aColor;
+in vec2  aTexCoords;
+in float aIntensity;
+
+out vec4  vColor;
+out vec2  vTexCoords;
+out float vIntensity;
+out vec2  vPosition;
+
+uniform mat3 uTransform;
+uniform vec4 uBounds;
+
+void main() {
+	vec2 transPos = (uTransform * vec3(aPosition, 1.0)).xy;
+	vec2 normPos = (transPos - uBounds.xy) / uBounds.zw * 2 - vec2(1, 1);
+	gl_Position = vec4(normPos, 0.0, 1.0);
+	vColor = aColor;
+	vPosition = aPosition;
+	vTexCoords = aTexCoords;
+	vIntensity = aIntensity;
+}
+`
+)
+
+// gpuRenderer evaluates the mandelbrot in a fragment shader on a canvas, fast enough to redraw every frame
+type gpuRenderer struct {
+	canvas *pixelgl.Canvas
+	quad   *imdraw.IMDraw
+
+	// uniform values, read by the canvas on each draw
+	min, size, resolution mgl32.Vec2
+	iterations            int32
+	fractal               int32
+	exponent              float32
+	samples               int32
+	bailout               float32
+	norm                  int32
+	contrast              float32
+	brightness            float32
+	levelsContrast, gamma float32
+}
+
+// creates a GPU renderer, returning an error if the shader can't be compiled on this machine
+func newGPURenderer(bounds pixel.Rect) (*gpuRenderer, error) {
+	// pixelgl panics on the main thread if a canvas shader fails to compile, so check it compiles first
+	err := mainthread.CallErr(func() error {
+		_, err := glhf.NewShader(canvasVertexFormat, glhf.AttrFormat{}, canvasVertexShader, mandelbrotFragmentShader)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gpuRenderer{
+		canvas: pixelgl.NewCanvas(bounds),
+		quad:   imdraw.New(nil),
+	}
+	g.canvas.SetUniform("uMin", &g.min)
+	g.canvas.SetUniform("uSize", &g.size)
+	g.canvas.SetUniform("uResolution", &g.resolution)
+	g.canvas.SetUniform("uIterations", &g.iterations)
+	g.canvas.SetUniform("uFractal", &g.fractal)
+	g.canvas.SetUniform("uExponent", &g.exponent)
+	g.canvas.SetUniform("uSamples", &g.samples)
+	g.canvas.SetUniform("uBailout", &g.bailout)
+	g.canvas.SetUniform("uNorm", &g.norm)
+	g.canvas.SetUniform("uContrast", &g.contrast)
+	g.canvas.SetUniform("uBrightness", &g.brightness)
+	g.canvas.SetUniform("uLevelsContrast", &g.levelsContrast)
+	g.canvas.SetUniform("uGamma", &g.gamma)
+	g.canvas.SetFragmentShader(mandelbrotFragmentShader)
+	return g, nil
+}
+
+// reports whether the shader supports the fractal, formula, Julia and colouring modes, and float32 arithmetic has enough
+// precision to render it
+func (g *gpuRenderer) canRender(p render.Params) bool {
+	if _, ok := gpuFractals[p.Fractal]; !ok || p.Formula != "" || p.Julia {
+		return false
+	}
+	// frame-wide colourings can't be computed per fragment
+	if p.Colouring != render.ColouringBands || p.Interior != render.InteriorFlat {
+		return false
+	}
+	return resolvable(paramsBounds(p), p.Scale, gpuMinRelativeSpacing)
+}
+
+// renders the params and draws the result centred on the target
+func (g *gpuRenderer) draw(t pixel.Target, centre pixel.Vec, p render.Params) {
+	canvasBounds := pixel.R(0, 0, float64(p.Width), float64(p.Height))
+	if g.canvas.Bounds() != canvasBounds {
+		g.canvas.SetBounds(canvasBounds)
+	}
+
+	b := paramsBounds(p)
+	g.min = mgl32.Vec2{float32(b.Min.X), float32(b.Min.Y)}
+	g.size = mgl32.Vec2{float32(b.W()), float32(b.H())}
+	g.resolution = mgl32.Vec2{float32(p.Width), float32(p.Height)}
+	g.iterations = int32(p.Iterations)
+	g.fractal = gpuFractals[p.Fractal]
+	g.exponent = float32(p.Exponent)
+	g.samples = int32(p.Samples)
+	g.bailout = float32(p.Bailout)
+	if p.Bailout == 0 {
+		g.bailout = render.DefaultBailout
+	}
+	g.norm = gpuNorms[p.Norm]
+	g.contrast = float32(p.Contrast)
+	if p.Contrast == 0 {
+		g.contrast = palette.Contrast
+	}
+	g.brightness = float32(p.Levels.Brightness)
+	g.levelsContrast, g.gamma = float32(p.Levels.Contrast), float32(p.Levels.Gamma)
+	if p.Levels.Contrast == 0 {
+		g.levelsContrast = 1
+	}
+	if p.Levels.Gamma == 0 {
+		g.gamma = 1
+	}
+
+	// the shader computes every fragment covered, so cover the whole canvas
+	g.quad.Clear()
+	g.quad.Push(canvasBounds.Min, canvasBounds.Max)
+	g.quad.Rectangle(0)
+
+	g.canvas.Clear(colourBlack)
+	g.quad.Draw(g.canvas)
+	g.canvas.Draw(t, pixel.IM.Moved(centre))
+}
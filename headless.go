@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// whether to render the starting view to a file rather than opening a window
+var headless bool
+
+// opens the window and explores until it's closed, returning an error if no window could be opened, e.g. on machines
+// without working OpenGL
+func runWindow() (err error) {
+	started := false
+	// pixelgl panics rather than returning an error when glfw can't be initialised
+	defer func() {
+		if r := recover(); r != nil {
+			if started {
+				panic(r)
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	pixelgl.Run(func() {
+		started = true
+		err = start()
+	})
+	return err
+}
+
+// renders the view the window would start at to exportPath, at the export size and quality
+func renderHeadless() error {
+	windowBounds := pixel.R(0, 0, windowSize, windowSize)
+	// split view needs a window to track the cursor, so only the fractal is rendered
+	bounds := startingView(windowBounds, windowBounds)
+	return exportView(exportParams(bounds, windowBounds.Size()), newBookmark(bounds))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	heightMapPath   string
+	heightMapSize   uint
+	heightMapHeight float64
+)
+
+// the thickness of the solid below the lowest point of height map meshes, as a fraction of their width
+const heightMapBase = 0.02
+
+// renders the view the window would start at as a height map, writing it to heightMapPath as an OBJ or STL mesh for
+// 3D printing or a PNG of shaded relief depending on its extension
+func writeHeightMap() error {
+	ext := strings.ToLower(filepath.Ext(heightMapPath))
+	if ext != ".obj" && ext != ".stl" && ext != ".png" {
+		return fmt.Errorf("unknown height map format %q, expected .obj, .stl or .png", ext)
+	}
+
+	windowBounds := pixel.R(0, 0, windowSize, windowSize)
+	bounds := startingView(windowBounds, windowBounds)
+	scale := float64(heightMapSize) / math.Max(windowBounds.W(), windowBounds.H())
+	size := pixel.V(math.Round(windowBounds.W()*scale), math.Round(windowBounds.H()*scale))
+	p := newParams(bounds, size)
+	// smooth escape times make slopes rather than terraces
+	p.Colouring = render.ColouringSmooth
+
+	fmt.Printf("Rendering %dx%d height map to %s\n", p.Width, p.Height, heightMapPath)
+	samples, err := iterateImage(context.Background(), p)
+	if err != nil {
+		return err
+	}
+	h := samples.HeightMap()
+
+	f, err := os.Create(heightMapPath)
+	if err != nil {
+		return err
+	}
+	height, base := heightMapHeight*size.X, heightMapBase*size.X
+	switch ext {
+	case ".obj":
+		err = h.WriteOBJ(f, height, base)
+	case ".stl":
+		err = h.WriteSTL(f, height, base)
+	default:
+		err = png.Encode(f, h.Relief(paletteName, height))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(heightMapPath)
+		return err
+	}
+	fmt.Printf("Wrote %s\n", heightMapPath)
+	return nil
+}
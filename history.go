@@ -0,0 +1,58 @@
+package main
+
+import "github.com/faiface/pixel"
+
+// the most views remembered in each direction
+const maxHistory = 100
+
+// view is a region of the plane and the size of the window it was shown in
+type view struct {
+	bounds     pixel.Rect
+	windowSize pixel.Vec
+}
+
+// returns the view's bounds adjusted to fit a window of the given size at the same scale
+func (v view) fit(windowSize pixel.Vec) pixel.Rect {
+	return resizeBounds(v.bounds, v.windowSize, windowSize)
+}
+
+// history is a browser style navigation history of the views visited
+type history struct {
+	back, forward []view
+}
+
+// records the current view before navigating away from it, discarding the forward history
+func (h *history) visit(current view) {
+	// repeatedly visiting the same view, e.g. resetting twice, shouldn't need stepping back through twice
+	if n := len(h.back); n > 0 && h.back[n-1] == current {
+		h.forward = nil
+		return
+	}
+	h.back = append(h.back, current)
+	if len(h.back) > maxHistory {
+		h.back = h.back[1:]
+	}
+	h.forward = nil
+}
+
+// returns the previous view, remembering current so that it can be returned to with goForward
+func (h *history) goBack(current view) (view, bool) {
+	if len(h.back) == 0 {
+		return current, false
+	}
+	prev := h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+	h.forward = append(h.forward, current)
+	return prev, true
+}
+
+// returns the view last stepped back from, remembering current so that it can be returned to with goBack
+func (h *history) goForward(current view) (view, bool) {
+	if len(h.forward) == 0 {
+		return current, false
+	}
+	next := h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+	h.back = append(h.back, current)
+	return next, true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+// returns a distinct view for each i
+func testView(i int) view {
+	return view{bounds: pixel.R(float64(i), 0, float64(i)+1, 1), windowSize: pixel.V(800, 800)}
+}
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		// the number of views visited before current, and the steps taken from it, b for back and f for forward
+		visits int
+		steps  string
+		// the view ended on, whether the last step moved, and the views left in each direction
+		want        int
+		wantOK      bool
+		wantBack    int
+		wantForward int
+	}{
+		{name: "empty back", steps: "b", want: 0},
+		{name: "empty forward", steps: "f", want: 0},
+		{name: "back", visits: 3, steps: "b", want: 2, wantOK: true, wantBack: 2, wantForward: 1},
+		{name: "back to the start", visits: 3, steps: "bbb", want: 0, wantOK: true, wantForward: 3},
+		{name: "back past the start", visits: 3, steps: "bbbb", want: 0, wantForward: 3},
+		{name: "back and forward", visits: 3, steps: "bbf", want: 2, wantOK: true, wantBack: 2, wantForward: 1},
+		{name: "forward past the end", visits: 3, steps: "bff", want: 3, wantBack: 3},
+		{name: "limit", visits: maxHistory + 10, steps: strings.Repeat("b", maxHistory), want: 10, wantOK: true,
+			wantForward: maxHistory},
+		{name: "back past the limit", visits: maxHistory + 10, steps: strings.Repeat("b", maxHistory+1), want: 10,
+			wantForward: maxHistory},
+	}
+	for _, tt := range tests {
+		var h history
+		for i := 0; i < tt.visits; i++ {
+			h.visit(testView(i))
+		}
+
+		current, ok := testView(tt.visits), false
+		for _, step := range tt.steps {
+			if step == 'b' {
+				current, ok = h.goBack(current)
+			} else {
+				current, ok = h.goForward(current)
+			}
+		}
+
+		if current != testView(tt.want) || ok != tt.wantOK {
+			t.Errorf("%s: expected to end on %v (%t), got %v (%t)", tt.name, testView(tt.want).bounds, tt.wantOK,
+				current.bounds, ok)
+		}
+		if len(h.back) != tt.wantBack || len(h.forward) != tt.wantForward {
+			t.Errorf("%s: expected %d views back and %d forward, got %d and %d", tt.name, tt.wantBack, tt.wantForward,
+				len(h.back), len(h.forward))
+		}
+	}
+}
+
+func TestHistoryVisit(t *testing.T) {
+	var h history
+	h.visit(testView(0))
+	h.visit(testView(1))
+	h.visit(testView(1))
+	if len(h.back) != 2 {
+		t.Errorf("expected revisiting a view to be remembered once, got %d views back", len(h.back))
+	}
+
+	// visiting a view after stepping back discards the views stepped back from
+	current, _ := h.goBack(testView(2))
+	h.visit(current)
+	if _, ok := h.goForward(current); ok || len(h.forward) != 0 {
+		t.Errorf("expected visiting to discard the forward history, got %d views forward", len(h.forward))
+	}
+}
+
+func TestViewFit(t *testing.T) {
+	v := view{bounds: pixel.R(-2, -2, 2, 2), windowSize: pixel.V(800, 800)}
+	if got, want := v.fit(pixel.V(1600, 800)), pixel.R(-4, -2, 4, 2); got != want {
+		t.Errorf("expected the view to fit a wider window as %v, got %v", want, got)
+	}
+}
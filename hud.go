@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/jemgunay/mandelbrot/render"
+	"golang.org/x/image/font/basicfont"
+)
+
+const hudMargin = 8
+
+var (
+	hudTextColour       = pixel.RGB(1, 1, 1)
+	hudBackgroundColour = pixel.RGBA{R: 0, G: 0, B: 0, A: 0.6}
+)
+
+// hudStats is the information displayed on the HUD
+type hudStats struct {
+	centre     pixel.Vec
+	cursor     pixel.Vec
+	zoom       float64
+	iterations int
+	colouring  string
+	trap       string
+	palette    string
+	interior   string
+	renderTime time.Duration
+	renderer   string
+	// the fraction of the frame being rendered done, or 1 if there isn't one taking long enough to report
+	progress float64
+	// the debug overlay being drawn, if any, and the stats of the latest frame shown alongside it
+	overlay string
+	frame   frameStats
+	// a warning about the accuracy of the frame, if any
+	warning string
+	// the input mode other than navigating, and what it's showing, if any
+	mode string
+}
+
+// hud is a toggleable text overlay describing the current view
+type hud struct {
+	visible    bool
+	txt        *text.Text
+	background *imdraw.IMDraw
+
+	// frames drawn since fpsSince, used to calculate the FPS once per second
+	frames   int
+	fps      int
+	fpsSince time.Time
+}
+
+func newHUD() *hud {
+	return &hud{
+		visible:    true,
+		txt:        text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII)),
+		background: imdraw.New(nil),
+		fpsSince:   time.Now(),
+	}
+}
+
+// counts a drawn frame towards the FPS
+func (h *hud) tick() {
+	h.frames++
+	if elapsed := time.Since(h.fpsSince); elapsed >= time.Second {
+		h.fps = int(float64(h.frames) / elapsed.Seconds())
+		h.frames = 0
+		h.fpsSince = time.Now()
+	}
+}
+
+// draws the HUD in the top left corner of the window
+func (h *hud) draw(win *pixelgl.Window, stats hudStats) {
+	if !h.visible {
+		return
+	}
+
+	h.txt.Clear()
+	fmt.Fprintf(h.txt, "centre  %+.12f %+.12fi\n", stats.centre.X, stats.centre.Y)
+	fmt.Fprintf(h.txt, "cursor  %+.12f %+.12fi\n", stats.cursor.X, stats.cursor.Y)
+	fmt.Fprintf(h.txt, "zoom    %.4gx\n", stats.zoom)
+	fmt.Fprintf(h.txt, "iter    %d\n", stats.iterations)
+	switch stats.colouring {
+	case render.ColouringBands:
+		fmt.Fprintf(h.txt, "colour  %s, %s interior\n", stats.colouring, stats.interior)
+	case render.ColouringOrbitTrap:
+		fmt.Fprintf(h.txt, "colour  %s %s (%s), %s interior\n", stats.trap, stats.colouring, stats.palette, stats.interior)
+	default:
+		fmt.Fprintf(h.txt, "colour  %s (%s), %s interior\n", stats.colouring, stats.palette, stats.interior)
+	}
+	if stats.renderer == "gpu" {
+		fmt.Fprintf(h.txt, "render  realtime (gpu)\n")
+	} else if stats.progress < 1 {
+		fmt.Fprintf(h.txt, "render  %s %.0f%% (%s)\n", progressBar(stats.progress), stats.progress*100, stats.renderer)
+	} else {
+		fmt.Fprintf(h.txt, "render  %s (%s)\n", stats.renderTime.Round(time.Millisecond), stats.renderer)
+	}
+	if stats.overlay != "" {
+		f := stats.frame
+		fmt.Fprintf(h.txt, "debug   %s, tiles %d cached %d new %d mirrored, %.0f%% busy\n", stats.overlay, f.hits,
+			f.misses, f.mirrored, f.utilisation*100)
+	}
+	if stats.warning != "" {
+		fmt.Fprintf(h.txt, "warning %s\n", stats.warning)
+	}
+	if stats.mode != "" {
+		fmt.Fprintf(h.txt, "mode    %s\n", stats.mode)
+	}
+	fmt.Fprintf(h.txt, "fps     %d", h.fps)
+
+	// the text origin is the baseline of the first line, so shift it down from the top edge by the line's ascent
+	m := pixel.IM.Moved(pixel.V(hudMargin, win.Bounds().H()-hudMargin-h.txt.Atlas().Ascent()))
+
+	bounds := h.txt.Bounds()
+	h.background.Clear()
+	h.background.Color = hudBackgroundColour
+	h.background.Push(m.Project(bounds.Min).Sub(pixel.V(hudMargin/2, hudMargin/2)), m.Project(bounds.Max).Add(pixel.V(hudMargin/2, hudMargin/2)))
+	h.background.Rectangle(0)
+	h.background.Draw(win)
+
+	h.txt.DrawColorMask(win, m, hudTextColour)
+}
+
+// returns the HUD warning for a frame described by p which is too deep for its arithmetic to tell its pixels apart,
+// suggesting a precision which can, or empty if it isn't blocky
+func precisionWarning(p render.Params, blocky bool) string {
+	if !blocky {
+		return ""
+	}
+	if p.Perturbable() {
+		return fmt.Sprintf("pixels blur together in %s, switch to -precision %s", p.SelectedPrecision(),
+			render.PrecisionPerturbation)
+	}
+	return fmt.Sprintf("pixels blur together in %s, which is as deep as this fractal can go", p.SelectedPrecision())
+}
+
+// draws a text progress bar filled to the fraction done
+func progressBar(done float64) string {
+	const width = 20
+	filled := int(done * width)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
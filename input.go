@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// the input modes, which decide what the left mouse button does
+const (
+	// pressing the left mouse button starts selecting a rectangle
+	modeNavigate = "navigate"
+	// releasing the left mouse button zooms to the rectangle dragged out
+	modeSelect = "select-rectangle"
+	// the Julia set seed follows the cursor until a click pins it
+	modePickSeed = "pick-julia-seed"
+	// dragging with the left mouse button measures the distance between two points on the plane
+	modeMeasure = "measure"
+)
+
+// the points scrolling and the zoom keys can zoom in on
+const (
+	zoomToCursor = "cursor"
+	zoomToCentre = "centre"
+)
+
+var (
+	// what scrolling and the zoom keys zoom in on, zoomToCursor or zoomToCentre
+	scrollZoomTo string
+	keyZoomTo    string
+
+	measureColour = pixel.RGB(1, 0.8, 0.2)
+)
+
+// actionHandler runs when its action is triggered
+type actionHandler struct {
+	action string
+	// whether the handler runs again while the action's key auto-repeats
+	repeat bool
+	handle func()
+}
+
+// controller turns the input of each update of the window into actions and mouse interactions, depending on the input
+// mode it's in. The actions are handled by the handlers added with on and onRepeat, in the order they were added.
+type controller struct {
+	mode     string
+	handlers []actionHandler
+	// called with the region of the window selected by dragging a rectangle
+	zoomTo func(selected pixel.Rect)
+
+	// the window position the current drag started at
+	dragStart pixel.Vec
+	// the points on the plane measured between, whether the measurement is being dragged out, and whether there's one
+	// to show
+	measureFrom, measureTo pixel.Vec
+	measuring, measured    bool
+}
+
+// returns whether name is one of the points zooming can be anchored on
+func isZoomAnchor(name string) bool {
+	return name == zoomToCursor || name == zoomToCentre
+}
+
+// returns the window position to zoom about: the cursor if anchor is zoomToCursor and it's over the pane, or the
+// centre of the pane otherwise
+func zoomAnchor(win *pixelgl.Window, pane pixel.Rect, anchor string) pixel.Vec {
+	if anchor == zoomToCursor && pane.Contains(win.MousePosition()) {
+		return win.MousePosition()
+	}
+	return pane.Center()
+}
+
+// adds a handler run each time the action's keys are pressed
+func (c *controller) on(action string, handle func()) {
+	c.handlers = append(c.handlers, actionHandler{action: action, handle: handle})
+}
+
+// adds a handler run each time the action's keys are pressed and again while they auto-repeat
+func (c *controller) onRepeat(action string, handle func()) {
+	c.handlers = append(c.handlers, actionHandler{action: action, repeat: true, handle: handle})
+}
+
+// runs the handlers of the actions triggered since the last update
+func (c *controller) dispatch(win *pixelgl.Window, pad *gamepad) {
+	pressed := keys.triggered(win, pad, false)
+	repeated := keys.triggered(win, pad, true)
+	for _, h := range c.handlers {
+		if pressed[h.action] || h.repeat && repeated[h.action] {
+			h.handle()
+		}
+	}
+}
+
+// switches to the input mode, abandoning any drag in progress
+func (c *controller) setMode(mode string) {
+	if mode != c.mode {
+		c.measuring = false
+		c.measured = false
+	}
+	c.mode = mode
+}
+
+// steps back out of the current mode, reporting whether there was one to leave
+func (c *controller) cancel() bool {
+	if c.mode == modeNavigate {
+		return false
+	}
+	c.setMode(modeNavigate)
+	return true
+}
+
+// handles the mouse for the update over pane, which shows bounds of the plane. Clicks taken by the panel are ignored.
+func (c *controller) updateMouse(win *pixelgl.Window, pane, bounds pixel.Rect, panelClick bool) {
+	pos := win.MousePosition()
+	pressed := win.JustPressed(pixelgl.MouseButtonLeft) && !panelClick && pane.Contains(pos)
+	released := win.JustReleased(pixelgl.MouseButtonLeft)
+
+	switch c.mode {
+	case modeNavigate:
+		if pressed {
+			c.dragStart = pos
+			c.mode = modeSelect
+		}
+	case modeSelect:
+		if released {
+			c.mode = modeNavigate
+			if isSelection(c.dragStart, pos) {
+				c.zoomTo(aspectCorrect(c.dragStart, pos, pane))
+			}
+		}
+	case modePickSeed:
+		// the seed only follows the cursor as it moves, so that a restored seed stays put until then
+		if pos != win.MousePreviousPosition() && pane.Contains(pos) {
+			juliaSeed = windowToPlane(pos, pane, bounds)
+		}
+		if pressed {
+			c.mode = modeNavigate
+			fmt.Printf("Pinned the Julia set seed at %s\n", formatComplex(juliaSeed))
+		}
+	case modeMeasure:
+		if pressed {
+			c.measureFrom = windowToPlane(pos, pane, bounds)
+			c.measuring, c.measured = true, true
+		}
+		if c.measuring {
+			c.measureTo = windowToPlane(pos, pane, bounds)
+		}
+		if c.measuring && released {
+			c.measuring = false
+			d := c.measureTo.Sub(c.measureFrom)
+			fmt.Printf("Measured %s from %s to %s\n", formatFloat(d.Len()), formatComplex(c.measureFrom),
+				formatComplex(c.measureTo))
+		}
+	}
+}
+
+// draws the rectangle being selected or the measurement over pane, which shows bounds of the plane
+func (c *controller) draw(imd *imdraw.IMDraw, win *pixelgl.Window, pane, bounds pixel.Rect) {
+	if c.mode == modeSelect && isSelection(c.dragStart, win.MousePosition()) {
+		drawSelection(imd, c.dragStart, win.MousePosition(), pane)
+	}
+	if c.mode == modeMeasure && c.measured {
+		from, to := planeToWindow(c.measureFrom, pane, bounds), planeToWindow(c.measureTo, pane, bounds)
+		imd.Color = measureColour
+		imd.Push(from, to)
+		imd.Line(1)
+		imd.Push(from, to)
+		imd.Circle(3, 1)
+	}
+}
+
+// returns the HUD line describing the mode, or empty while navigating
+func (c *controller) status() string {
+	switch c.mode {
+	case modePickSeed:
+		return fmt.Sprintf("%s, click to pin %s", c.mode, formatComplex(juliaSeed))
+	case modeMeasure:
+		if !c.measured {
+			return fmt.Sprintf("%s, drag between two points", c.mode)
+		}
+		d := c.measureTo.Sub(c.measureFrom)
+		return fmt.Sprintf("%s %.6g across, at %.1f degrees", c.mode, d.Len(), math.Atan2(d.Y, d.X)*180/math.Pi)
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package main
+
+import "github.com/jemgunay/mandelbrot/render"
+
+var (
+	locationName string
+	// the index into locations of the location last jumped to, or -1 before the first jump
+	locationIndex = -1
+)
+
+// the built in preset locations, in cycling order
+var locations = []struct {
+	name     string
+	bookmark bookmark
+}{
+	{name: "seahorse", bookmark: bookmark{
+		Centre:     point{Re: -0.7453, Im: 0.1127},
+		Zoom:       600,
+		Iterations: 500,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "ultra",
+	}},
+	{name: "elephant", bookmark: bookmark{
+		Centre:     point{Re: 0.2925, Im: 0.0149},
+		Zoom:       200,
+		Iterations: 400,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "fire",
+	}},
+	{name: "triple-spiral", bookmark: bookmark{
+		Centre:     point{Re: -0.088, Im: 0.654},
+		Zoom:       40,
+		Iterations: 400,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "lime",
+	}},
+	{name: "misiurewicz", bookmark: bookmark{
+		Centre:     point{Re: -0.77568377, Im: 0.13646737},
+		Zoom:       2000,
+		Iterations: 800,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "ultra",
+	}},
+	{name: "dendrite", bookmark: bookmark{
+		Centre:     point{Re: 0, Im: 1},
+		Zoom:       30,
+		Iterations: 300,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "grey",
+	}},
+	{name: "mini", bookmark: bookmark{
+		Centre:     point{Re: -1.7549, Im: 0},
+		Zoom:       80,
+		Iterations: 500,
+		Fractal:    "mandelbrot",
+		Colouring:  render.ColouringHistogram,
+		Palette:    "fire",
+	}},
+}
+
+// returns the names of the preset locations in cycling order
+func locationNames() []string {
+	names := make([]string, len(locations))
+	for i, l := range locations {
+		names[i] = l.name
+	}
+	return names
+}
+
+// returns the index into locations of the named preset, or -1 if there is no such preset
+func lookupLocation(name string) int {
+	for i, l := range locations {
+		if l.name == name {
+			return i
+		}
+	}
+	return -1
+}
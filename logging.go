@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the levels of diagnostic log messages, from the most verbose
+const (
+	logDebug = iota
+	logInfo
+	logWarn
+	logError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+var (
+	logLevelName string
+	logPath      string
+	verbose      bool
+
+	// diagnostics are written to logOutput if they're at least logLevel
+	logLevel            = logWarn
+	logOutput io.Writer = os.Stderr
+	logMu     sync.Mutex
+)
+
+// sets up diagnostic logging from the -log-level, -v and -log-file flags
+func setupLogging() error {
+	level := -1
+	for i, name := range logLevelNames {
+		if name == logLevelName {
+			level = i
+		}
+	}
+	if level < 0 {
+		return fmt.Errorf("unknown log level %q, expected one of %s", logLevelName, strings.Join(logLevelNames, ", "))
+	}
+	if verbose {
+		level = logDebug
+	}
+	logLevel = level
+
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		logOutput = f
+	}
+	return nil
+}
+
+// reports whether messages at level are logged, so that costly diagnostics can be skipped
+func logEnabled(level int) bool {
+	return level >= logLevel
+}
+
+// logs msg at level followed by the key value pairs in keyvals, in logfmt, e.g.
+// time=2021-06-01T12:00:00.000Z level=debug msg="frame rendered" width=500
+func logf(level int, msg string, keyvals ...interface{}) {
+	if !logEnabled(level) {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s level=%s msg=%s", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		logLevelNames[level], logValue(msg))
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], logValue(keyvals[i+1]))
+	}
+	b.WriteByte('\n')
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	io.WriteString(logOutput, b.String())
+}
+
+// formats v as a logfmt value, quoting it if it has spaces or quotes
+func logValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(v, 'g', 4, 64)
+	case time.Duration:
+		s = v.Round(time.Microsecond).String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
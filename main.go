@@ -4,153 +4,1082 @@ import (
 	"flag"
 	"fmt"
 	"image/color"
-	"math/cmplx"
-	"sync"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
+	"github.com/jemgunay/mandelbrot/palette"
+	"github.com/jemgunay/mandelbrot/render"
 )
 
 var (
 	iterations       uint
+	adaptive         bool
+	samples          uint
+	sampleEdges      bool
+	traceBoundaries  bool
+	fractalName      string
+	exponent         float64
+	formula          string
+	polynomial       string
+	bailout          float64
+	norm             string
+	colouring        string
+	contrast         uint
+	paletteName      string
+	paletteFile      string
+	brightness       float64
+	levelsContrast   float64
+	gamma            float64
+	interior         string
+	trap             string
 	windowSize       float64
-	windowBounds     pixel.Rect
 	mandelbrotBounds = pixel.R(-2, -2, 2, 2)
 
-	pixelData        *pixel.PictureData
-	mandelbrotSprite *pixel.Sprite
-	// mutex serialises access to the drawable pixel data
-	mandelbrotMu sync.RWMutex
+	// which renderer to draw with: cpu, cpu-simd, opencl or gpu
+	rendererName string
+	// the arithmetic the cpu renderers iterate with, one of render.Precisions
+	precision string
+	// the frame rate cap, or 0 for none, and whether to wait for the display's vertical sync
+	fps   uint
+	vsync bool
+	// whether to draw a crosshair at the cursor
+	crosshair bool
+	// the debug overlay drawn over the fractal, one of render.Overlays, or empty for none
+	debugOverlay string
 
 	colourBlack = color.RGBA{0, 0, 0, 0}
+
+	// offset applied to the default bounds to centre the initial view over a zoomable area within the set
+	initialOffset = pixel.V(-0.6, -0.43)
 )
 
 const (
-	colourContrast = 20
+	// extra iterations added in adaptive mode each time the magnification doubles
+	iterationsPerZoomDoubling = 100
+	// the span of the complex plane visible across the shorter side of the window at 1x magnification
+	defaultSpan = 4
+	// the smallest pixel spacing, relative to the magnitude of the coordinates, at which the float64 bounds of the view
+	// can still be moved by less than a pixel, which limits how deep perturbation can be taken
+	viewMinRelativeSpacing = 1.0 / (1 << 50)
 )
 
+// describes a render of bounds at the given resolution using the current settings
+func newParams(bounds pixel.Rect, size pixel.Vec) render.Params {
+	centre := bounds.Center()
+	p := render.Params{
+		Centre:      complex(centre.X, centre.Y),
+		Scale:       bounds.W() / size.X,
+		Width:       int(size.X),
+		Height:      int(size.Y),
+		Iterations:  int(iterations),
+		Fractal:     fractalName,
+		Exponent:    exponent,
+		Formula:     formula,
+		Polynomial:  polynomial,
+		Bailout:     bailout,
+		Norm:        norm,
+		Samples:     int(samples),
+		SampleEdges: sampleEdges,
+		Trace:       traceBoundaries,
+		SIMD:        rendererName == "cpu-simd",
+		OpenCL:      rendererName == "opencl",
+		Precision:   precision,
+		Colouring:   colouring,
+		Contrast:    int(contrast),
+		Palette:     paletteName,
+		Levels:      palette.Levels{Brightness: brightness, Contrast: levelsContrast, Gamma: gamma},
+		Interior:    interior,
+		Trap:        trap,
+	}
+	if adaptive {
+		p.Iterations = int(adaptiveIterations(iterations, zoomLevel(bounds)))
+	}
+	return p
+}
+
+// returns the region of the complex plane covered by the params
+func paramsBounds(p render.Params) pixel.Rect {
+	size := pixel.V(float64(p.Width), float64(p.Height)).Scaled(p.Scale)
+	return centredRect(pixel.V(real(p.Centre), imag(p.Centre)), size)
+}
+
 func main() {
 	// process flags
 	flag.UintVar(&iterations, "iterations", 200, "the number of mandelbrot iterations")
+	flag.BoolVar(&adaptive, "adaptive", false, "increase the number of iterations logarithmically with the zoom level")
 	flag.Float64Var(&windowSize, "size", 500, "the window size")
+	flag.StringVar(&fractalName, "fractal", "mandelbrot", "the fractal to render: "+strings.Join(render.Fractals(), ", "))
+	flag.Float64Var(&exponent, "exponent", 3, "the exponent d of the multibrot formula z^d + c")
+	flag.StringVar(&formula, "formula", "", "a custom formula in z and c to iterate instead of -fractal, e.g. \"z^3 + c*sin(z)\"")
+	flag.StringVar(&polynomial, "polynomial", "1, 0, 0, -1", "the comma separated complex coefficients of the newton fractal's polynomial from the highest power down, e.g. \"1, 0, 0, -1\" for z^3 - 1")
+	flag.Float64Var(&bailout, "bailout", render.DefaultBailout, "the escape radius beyond which a point is considered to have escaped")
+	flag.StringVar(&norm, "norm", render.NormEuclidean, "the norm the escape radius is measured with: "+strings.Join(render.Norms(), ", "))
+	flag.StringVar(&colouring, "colouring", render.ColouringBands, "the colouring algorithm: "+strings.Join(render.Colourings(), ", "))
+	flag.UintVar(&contrast, "contrast", palette.Contrast, "the shade step between consecutive escape iterations of the bands and smooth colourings")
+	flag.StringVar(&paletteName, "palette", palette.Gradients()[0], "the gradient used by the colourings other than bands: "+strings.Join(palette.Gradients(), ", "))
+	flag.StringVar(&paletteFile, "palette-file", "", "a .json, .csv or Fractint .map file of gradient stops to colour with instead of -palette, named after the file for cycling")
+	flag.Float64Var(&brightness, "brightness", 0, "added to every colour channel, from -1 to 1, to brighten or darken the image for the display")
+	flag.Float64Var(&levelsContrast, "luminance-contrast", 1, "how far every colour channel is stretched away from mid grey, above 1 for more contrast or below for less")
+	flag.Float64Var(&gamma, "gamma", 1, "the gamma colour channels are raised to, above 1 to brighten the mid tones or below to darken them")
+	flag.StringVar(&interior, "interior", render.InteriorFlat, "the colouring of points inside the set: "+strings.Join(render.Interiors(), ", "))
+	flag.StringVar(&trap, "trap", render.TrapPoint, "the orbit trap shape used by the orbit-trap colouring: "+strings.Join(render.Traps(), ", "))
+	flag.UintVar(&samples, "samples", 1, "anti-alias by averaging samples x samples subpixel samples per pixel")
+	flag.BoolVar(&traceBoundaries, "trace", true, "skip iterating the insides of rectangles whose borders all escape on the same iteration, filling them instead")
+	flag.BoolVar(&sampleEdges, "edge-aa", false, "only take multiple -samples for pixels on edges, sampling the rest once")
+	flag.StringVar(&qualityName, "quality", "normal", "the quality preset to explore at, trading fidelity for speed: "+strings.Join(qualityNames(), ", "))
+	flag.StringVar(&scrollZoomTo, "scroll-zoom", zoomToCursor, "what scrolling zooms in on: cursor, or centre for the centre of the view")
+	flag.StringVar(&keyZoomTo, "key-zoom", zoomToCentre, "what the zoom keys zoom in on: centre, or cursor for the point under the cursor")
+	flag.Float64Var(&interactionResolution, "interaction-resolution", 0.5, "the fraction of the window's pixels rendered along each axis while the view is moved by hand, or 1 to always render at full resolution")
+	flag.DurationVar(&interactionSettle, "interaction-settle", 250*time.Millisecond, "how long after the view stops moving it's rendered at full resolution again")
+	flag.DurationVar(&frameBudget, "frame-budget", 0, "render frames in slices of this long between display frames, e.g. 16ms, drawing them as they improve rather than waiting for each to finish, or 0 to render them in one go")
+	flag.StringVar(&refineQualityName, "refine-quality", "high", "the quality preset a still view is refined to, from the centre of the screen outwards, or empty to keep the -quality preset: "+strings.Join(qualityNames(), ", "))
+	flag.DurationVar(&refineDelay, "refine-delay", time.Second, "how long the view must stay still before it's refined to -refine-quality")
+	flag.StringVar(&exportQualityName, "export-quality", "export", "the quality preset the export key renders at: "+strings.Join(qualityNames(), ", "))
+	flag.UintVar(&fps, "fps", 120, "the maximum number of frames drawn per second, or 0 for no limit")
+	flag.BoolVar(&vsync, "vsync", false, "synchronise frames with the display's refresh rate to avoid tearing")
+	flag.DurationVar(&transitionTime, "transition", 1500*time.Millisecond, "how long loading a bookmark or jumping to a preset location takes to zoom and pan there, or 0 to jump straight there")
+	flag.StringVar(&debugOverlay, "overlay", "", "a debug overlay to draw over the fractal, showing what each region costs to render: "+strings.Join(render.Overlays(), ", "))
+	flag.BoolVar(&crosshair, "crosshair", false, "draw a crosshair at the cursor")
+	flag.BoolVar(&showOrbit, "orbit", false, "draw the orbit of the point under the cursor over the fractal")
+	flag.UintVar(&orbitLength, "orbit-length", 500, "the most iterations of the orbit drawn by -orbit")
+	flag.BoolVar(&splitView, "julia", false, "split the window between the fractal and the Julia set of the point under the cursor")
+	flag.StringVar(&rendererName, "renderer", "cpu", "the renderer to use: cpu, cpu-simd to iterate four or eight points at once with AVX2, opencl to iterate in double precision on an OpenCL device (in builds with -tags opencl), or gpu to evaluate the set in a fragment shader (falling back to cpu when out of precision)")
+	flag.StringVar(&precision, "precision", render.PrecisionAuto, "the arithmetic the cpu renderers iterate with: "+strings.Join(render.Precisions(), ", ")+", where auto picks the cheapest which resolves the view")
+	flag.StringVar(&bookmarkPath, "bookmark", "bookmark.json", "the file the save/load bookmark keys write to and read from")
+	flag.StringVar(&bindingsPath, "keys", "", "a JSON file mapping actions to lists of keys, overriding the default key bindings")
+	flag.StringVar(&sessionPath, "session", defaultSessionPath(), "the file the exploration state is autosaved to, for restoring after the process dies")
+	flag.DurationVar(&autosaveInterval, "autosave", 30*time.Second, "how often the session is saved while the view changes, or 0 to turn autosaving off")
+	flag.BoolVar(&restoreSession, "restore", false, "start at the view of the last session")
+	flag.StringVar(&loadPath, "load", "", "a bookmark file to restore on start up")
+	flag.BoolVar(&autoExplore, "explore", false, "start on autopilot, endlessly zooming into the most detailed region of the view")
+	flag.BoolVar(&screensaver, "screensaver", false, "run as a screensaver, slowly zooming into randomly picked detailed regions and starting over when precision runs out")
+	flag.StringVar(&fromState, "from", "", "a shared state printed by the share key to reproduce exactly, e.g. mandelbrot:?centre=-0.75,0.1&zoom=600")
+	flag.StringVar(&openPath, "open", "", "a PNG written by the export key to restore the view of, e.g. export.png")
+	flag.StringVar(&locationName, "location", "", "a preset location to start at: "+strings.Join(locationNames(), ", "))
+	flag.StringVar(&scriptPath, "script", "", "a file of commands to play a tour of the fractal with, or - to read them from stdin")
+	flag.StringVar(&exportPath, "export", "export.png", "the PNG file the export key writes the current view to")
+	flag.UintVar(&exportSize, "export-size", 8000, "the size in pixels of the longer side of exported images")
+	flag.StringVar(&recordPath, "record", "", "render a zoom sequence to frames_%04d.png, an animated .gif or any other ffmpeg supported video file instead of opening a window")
+	flag.UintVar(&recordFrames, "record-frames", 120, "the number of frames in a recorded zoom sequence")
+	flag.Float64Var(&recordTarget.X, "record-x", -0.743643, "the real component of the point a recorded zoom sequence zooms in on")
+	flag.Float64Var(&recordTarget.Y, "record-y", 0.131825, "the imaginary component of the point a recorded zoom sequence zooms in on")
+	flag.StringVar(&recordExponent, "record-exponent", "", "record a sweep of the multibrot exponent d in z^d + c across a range such as 2:5 at the initial view instead of a zoom")
+	flag.Float64Var(&recordZoom, "record-zoom", 1000, "the magnification reached at the end of a recorded zoom sequence")
+	flag.UintVar(&recordFPS, "record-fps", 30, "the playback frame rate of a recorded zoom sequence")
+	flag.BoolVar(&verbose, "v", false, "log verbose diagnostics, the same as -log-level=debug")
+	flag.StringVar(&logLevelName, "log-level", "warn", "the least severe diagnostics to log: "+strings.Join(logLevelNames, ", "))
+	flag.StringVar(&logPath, "log-file", "", "a file to append diagnostics to instead of stderr")
+	flag.StringVar(&debugAddr, "debug-addr", "", "serve pprof profiles at /debug/pprof/ and metrics at /debug/vars on the given address, e.g. localhost:6060")
+	flag.BoolVar(&headless, "headless", false, "render the starting view to the -export file without opening a window, as happens anyway when OpenGL is unavailable")
+	flag.StringVar(&heightMapPath, "heightmap", "", "render the starting view as a 3D height map to an .obj or .stl mesh for 3D printing, or a .png of shaded relief, instead of opening a window")
+	flag.UintVar(&heightMapSize, "heightmap-size", 512, "the size in pixels of the longer side of height maps, each pixel becoming a vertex of meshes")
+	flag.Float64Var(&heightMapHeight, "heightmap-height", 0.15, "the height of the tallest point of height maps as a fraction of their width")
+	flag.StringVar(&rawPath, "raw", "", "render the starting view's escape iterations at the export size to a 16-bit greyscale .png, .csv or NumPy .npy file for post-processing, instead of opening a window")
+	flag.BoolVar(&rawSmooth, "raw-smooth", true, "write fractional escape iterations to -raw files rather than whole ones")
+	flag.StringVar(&deepZoomPath, "deepzoom", "", "render the starting view as a Deep Zoom Image tile pyramid to a .dzi file and its _files directory, with an .html viewer to explore it in a browser, instead of opening a window")
+	flag.UintVar(&deepZoomSize, "deepzoom-size", 16384, "the size in pixels of the longer side of the deepest level of -deepzoom pyramids")
+	flag.StringVar(&batchPath, "batch", "", "render every location of a JSON manifest, or every bookmark file and exported image in a directory, to -batch-out instead of opening a window")
+	flag.StringVar(&batchOut, "batch-out", "batch", "the directory -batch writes images to")
+	flag.StringVar(&batchSizes, "batch-sizes", "1920x1080", "the comma separated sizes -batch renders each location at, unless its manifest lists sizes")
+	flag.StringVar(&batchPalettes, "batch-palettes", "", "the comma separated palettes -batch renders each location in, unless its manifest lists palettes, rather than each location's own")
+	flag.UintVar(&batchJobs, "batch-jobs", 2, "the number of images -batch renders at once")
+	flag.BoolVar(&benchmark, "bench", false, "render a fixed set of viewports without opening a window and print the timings as JSON")
+	flag.UintVar(&benchRuns, "bench-runs", 3, "the number of times each viewport is rendered by -bench, keeping the fastest")
+	flag.StringVar(&serveAddr, "serve", "", "serve map tiles at /tiles/{z}/{x}/{y}.png on the given address, e.g. :8080, instead of opening a window")
+	flag.StringVar(&workerAddr, "worker", "", "iterate strips of distributed renders for a coordinator on the given address, e.g. :8081, instead of opening a window")
+	flag.StringVar(&workerList, "workers", "", "a comma separated list of -worker addresses to farm recorded frames out to")
 	flag.Parse()
+	workerAddrs = parseWorkers(workerList)
+	if err := setupLogging(); err != nil {
+		fmt.Printf("failed to set up logging: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !render.IsFractal(fractalName) {
+		fmt.Printf("unknown fractal %q, expected one of %s\n", fractalName, strings.Join(render.Fractals(), ", "))
+		os.Exit(1)
+	}
+	if formula != "" {
+		if err := render.CheckFormula(formula); err != nil {
+			fmt.Printf("invalid formula %q: %s\n", formula, err)
+			os.Exit(1)
+		}
+	}
+	if err := render.CheckPolynomial(polynomial); err != nil {
+		fmt.Printf("invalid polynomial %q: %s\n", polynomial, err)
+		os.Exit(1)
+	}
+	if bailout <= 0 {
+		fmt.Println("bailout must be positive")
+		os.Exit(1)
+	}
+	if orbitLength == 0 {
+		fmt.Println("orbit-length must be at least 1")
+		os.Exit(1)
+	}
+	if !render.IsNorm(norm) {
+		fmt.Printf("unknown bailout norm %q, expected one of %s\n", norm, strings.Join(render.Norms(), ", "))
+		os.Exit(1)
+	}
+	if !render.IsColouring(colouring) {
+		fmt.Printf("unknown colouring %q, expected one of %s\n", colouring, strings.Join(render.Colourings(), ", "))
+		os.Exit(1)
+	}
+	if !isZoomAnchor(scrollZoomTo) || !isZoomAnchor(keyZoomTo) {
+		fmt.Printf("scroll-zoom and key-zoom must be %s or %s\n", zoomToCursor, zoomToCentre)
+		os.Exit(1)
+	}
+	if contrast == 0 || contrast > 255 {
+		fmt.Println("contrast must be between 1 and 255")
+		os.Exit(1)
+	}
+	if brightness < -1 || brightness > 1 {
+		fmt.Println("brightness must be between -1 and 1")
+		os.Exit(1)
+	}
+	if levelsContrast <= 0 || gamma <= 0 {
+		fmt.Println("luminance-contrast and gamma must be positive")
+		os.Exit(1)
+	}
+	if paletteFile != "" {
+		name, g, err := palette.LoadGradient(paletteFile)
+		if err == nil {
+			err = palette.RegisterGradient(name, g)
+		}
+		if err != nil {
+			fmt.Printf("failed to load palette: %s\n", err)
+			os.Exit(1)
+		}
+		paletteName = name
+	}
+	if _, ok := palette.LookupGradient(paletteName); !ok {
+		fmt.Printf("unknown palette %q, expected one of %s\n", paletteName, strings.Join(palette.Gradients(), ", "))
+		os.Exit(1)
+	}
+	if !render.IsInterior(interior) {
+		fmt.Printf("unknown interior colouring %q, expected one of %s\n", interior, strings.Join(render.Interiors(), ", "))
+		os.Exit(1)
+	}
+	if !render.IsTrap(trap) {
+		fmt.Printf("unknown orbit trap %q, expected one of %s\n", trap, strings.Join(render.Traps(), ", "))
+		os.Exit(1)
+	}
+	if debugOverlay != "" && !render.IsOverlay(debugOverlay) {
+		fmt.Printf("unknown overlay %q, expected one of %s\n", debugOverlay, strings.Join(render.Overlays(), ", "))
+		os.Exit(1)
+	}
+	if samples == 0 {
+		fmt.Println("samples must be at least 1")
+		os.Exit(1)
+	}
+	if _, ok := lookupQuality(qualityName); !ok {
+		fmt.Printf("unknown quality %q, expected one of %s\n", qualityName, strings.Join(qualityNames(), ", "))
+		os.Exit(1)
+	}
+	if interactionResolution <= 0 || interactionResolution > 1 {
+		fmt.Println("interaction resolution must be greater than 0 and at most 1")
+		os.Exit(1)
+	}
+	if _, ok := lookupQuality(refineQualityName); !ok && refineQualityName != "" {
+		fmt.Printf("unknown refine quality %q, expected one of %s\n", refineQualityName, strings.Join(qualityNames(), ", "))
+		os.Exit(1)
+	}
+	if _, ok := lookupQuality(exportQualityName); !ok {
+		fmt.Printf("unknown export quality %q, expected one of %s\n", exportQualityName, strings.Join(qualityNames(), ", "))
+		os.Exit(1)
+	}
+	if locationName != "" {
+		if locationIndex = lookupLocation(locationName); locationIndex < 0 {
+			fmt.Printf("unknown location %q, expected one of %s\n", locationName, strings.Join(locationNames(), ", "))
+			os.Exit(1)
+		}
+	}
+	if screensaver {
+		autoExplore, adaptive = true, true
+	}
+	if openPath != "" {
+		if fromState != "" {
+			fmt.Println("only one of -open and -from can be given")
+			os.Exit(1)
+		}
+		var err error
+		if fromState, err = readImageState(openPath); err != nil {
+			fmt.Printf("failed to open image: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if fromState != "" {
+		var err error
+		if sharedBookmark, err = decodeState(fromState); err != nil {
+			fmt.Printf("invalid shared state %q: %s\n", fromState, err)
+			os.Exit(1)
+		}
+		// states shared from split view reproduce both halves
+		if sharedBookmark.Seed != nil {
+			splitView = true
+		}
+	}
+	var err error
+	if lastSession, err = loadSession(sessionPath); err != nil {
+		fmt.Printf("failed to load the last session: %s\n", err)
+	} else if restoreSession && lastSession == nil {
+		fmt.Println("there's no saved session to restore")
+	}
+	if keys, err = loadBindings(bindingsPath); err != nil {
+		fmt.Printf("failed to load key bindings: %s\n", err)
+		os.Exit(1)
+	}
+	if exportSize == 0 {
+		fmt.Println("export size must be at least 1")
+		os.Exit(1)
+	}
+	if rendererName != "cpu" && rendererName != "cpu-simd" && rendererName != "opencl" && rendererName != "gpu" {
+		fmt.Printf("unknown renderer %q\n", rendererName)
+		os.Exit(1)
+	}
+	if !render.IsPrecision(precision) {
+		fmt.Printf("unknown precision %q, expected one of %s\n", precision, strings.Join(render.Precisions(), ", "))
+		os.Exit(1)
+	}
+	if rendererName == "cpu-simd" && !render.HasSIMD() {
+		fmt.Println("this CPU doesn't support AVX2, falling back to the cpu renderer")
+	}
+	if rendererName == "opencl" {
+		if device, err := render.OpenCLDevice(); err != nil {
+			fmt.Printf("no OpenCL device available, falling back to the cpu renderer: %s\n", err)
+		} else {
+			fmt.Printf("Iterating on the %s OpenCL device\n", device)
+		}
+	}
+	if debugAddr != "" {
+		fmt.Printf("Serving profiles and metrics on %s\n", debugAddr)
+		go func() {
+			if err := serveDebug(); err != nil {
+				fmt.Printf("debug server failed: %s\n", err)
+			}
+		}()
+	}
+	logf(logInfo, "starting", "version", programVersion(), "renderer", rendererName, "simd", render.HasSIMD(),
+		"precision", precision, "quality", qualityName, "workers", len(workerAddrs))
+
+	if recordPath != "" {
+		if err := record(); err != nil {
+			fmt.Printf("failed to record zoom sequence: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if heightMapPath != "" {
+		if heightMapSize < 2 || heightMapHeight <= 0 {
+			fmt.Println("height maps must be at least 2 pixels with a positive height")
+			os.Exit(1)
+		}
+		if err := writeHeightMap(); err != nil {
+			fmt.Printf("failed to write height map: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if rawPath != "" {
+		if err := writeRaw(); err != nil {
+			fmt.Printf("failed to write escape iterations: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if deepZoomPath != "" {
+		if deepZoomSize == 0 {
+			fmt.Println("deep zoom size must be at least 1")
+			os.Exit(1)
+		}
+		if err := writeDeepZoom(); err != nil {
+			fmt.Printf("failed to write deep zoom pyramid: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if batchPath != "" {
+		if batchJobs == 0 {
+			fmt.Println("batch jobs must be at least 1")
+			os.Exit(1)
+		}
+		if err := runBatch(); err != nil {
+			fmt.Printf("batch failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if benchmark {
+		if err := bench(); err != nil {
+			fmt.Printf("benchmark failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if workerAddr != "" {
+		if err := runWorker(); err != nil {
+			fmt.Printf("worker failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if serveAddr != "" {
+		if err := serve(); err != nil {
+			fmt.Printf("tile server failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if headless {
+		if err := renderHeadless(); err != nil {
+			fmt.Printf("failed to render: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if tourCommands, err = openScript(); err != nil {
+		fmt.Printf("failed to open script: %s\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Printf("Generating Mandelbrot for %d iterations at %dx%d\n", iterations, int(windowSize), int(windowSize))
 
-	pixelgl.Run(func() {
-		start()
-	})
+	if err := runWindow(); err != nil {
+		fmt.Printf("failed to open a window: %s\n", err)
+		fmt.Println("OpenGL doesn't seem to be available, e.g. without a display or graphics drivers. Rendering the " +
+			"starting view without a window instead, which -headless does straight away next time. -record, -serve and " +
+			"-bench run without a window too.")
+		if err := renderHeadless(); err != nil {
+			fmt.Printf("failed to render: %s\n", err)
+			os.Exit(1)
+		}
+	}
 }
 
-func start() {
-	windowBounds = pixel.R(0, 0, windowSize, windowSize)
+// creates the window and runs the main loop until it's closed, returning an error if the window can't be created
+func start() error {
+	windowBounds := pixel.R(0, 0, windowSize, windowSize)
 
 	// create window config
 	cfg := pixelgl.WindowConfig{
 		Title:     "Mandelbrot",
 		Bounds:    windowBounds,
-		VSync:     false,
+		VSync:     vsync,
 		Resizable: true,
 	}
 
 	// create window
 	win, err := pixelgl.NewWindow(cfg)
 	if err != nil {
-		fmt.Printf("failed create new window: %s\n", err)
-		return
+		return err
 	}
 
-	pixelData = pixel.MakePictureData(windowBounds)
+	var gpu *gpuRenderer
+	if rendererName == "gpu" {
+		if gpu, err = newGPURenderer(windowBounds); err != nil {
+			fmt.Printf("failed to create gpu renderer, falling back to cpu: %s\n", err)
+		}
+	}
+
+	// the fractal fills the window, or its left half in split view with the Julia set on the right
+	paneBounds, juliaPane := panes(windowBounds, splitView)
+	mandelbrotBounds = startingView(windowBounds, paneBounds)
+
+	// the quality preset frames are rendered at, which can be switched while exploring
+	quality, _ := lookupQuality(qualityName)
 
 	// generate initial mandelbrot and continue to generate a fresh copy independent of the main thread
-	generate()
-	go func() {
-		for {
-			generate()
+	mandelbrotView, juliaView := newViewport(), newViewport()
+	mandelbrotView.setOverlay(debugOverlay)
+	mandelbrotView.start(quality.apply(newParams(mandelbrotBounds, paneBounds.Size())))
+	juliaView.run()
+
+	// limit update cycles to the frame rate cap, if there is one
+	var frameRateLimiter <-chan time.Time
+	if fps > 0 {
+		frameRateLimiter = time.Tick(time.Second / time.Duration(fps))
+	}
+
+	overlay := imdraw.New(nil)
+	var title string
+	hud := newHUD()
+	controls := newPanel()
+	// previously visited views, and whether the view was moving on the last update
+	var hist history
+	wasMoving := false
+	// mouse drag panning, when the last scroll event arrived, and when the last update was
+	var pan panner
+	// zooming and panning with the keys
+	var motion keyMotion
+	// navigation with a game controller
+	var pad gamepad
+	// animated jumps to bookmarks and preset locations
+	var trans transition
+	var lastScroll time.Time
+	lastFrame := time.Now()
+	// searches the view for detailed regions to zoom into
+	explore := explorer{auto: autoExplore, zoomSpeed: exploreZoomSpeed}
+	// when the view was last moved by hand, and when it last changed for any reason, e.g. by the autopilot
+	var lastMoved, lastChanged time.Time
+	lastBounds := mandelbrotBounds
+	// the arithmetic the last frame was drawn with, gpu or one of render.Precisions, and whether it was too coarse
+	// to tell the pixels apart
+	var lastRenderer string
+	var lastBlocky bool
+	if screensaver {
+		explore.zoomSpeed = screensaverZoomSpeed
+		explore.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+		hud.visible = false
+		win.SetCursorVisible(false)
+	}
+	// stops any glide or autopilot in progress, e.g. when the view jumps elsewhere
+	stopMotion := func() {
+		pan.stop()
+		motion.stop()
+		explore.stop()
+		trans.stop()
+	}
+	// moves the view to target, animating the way there over duration, or jumping straight there if it's 0
+	moveTo := func(target pixel.Rect, duration time.Duration) {
+		stopMotion()
+		hist.visit(view{mandelbrotBounds, paneBounds.Size()})
+		if trans.start(mandelbrotBounds, target, duration); !trans.active() {
+			mandelbrotBounds = target
 		}
-	}()
+	}
+	// moves the view to a bookmark or location, animating the way there unless transitions are turned off
+	transitionTo := func(target pixel.Rect) {
+		moveTo(target, transitionTime)
+	}
+	// plays the -script tour, if there is one
+	script := scriptRunner{commands: tourCommands}
 
-	// limit update cycles to 30 FPS
-	frameRateLimiter := time.Tick(time.Second / 120)
-	initialBoundsSize := mandelbrotBounds.Size()
+	// keep the session saved so that it survives the process dying, and save it one last time on quitting
+	var saver autosaver
+	defer func() {
+		saver.finish(newBookmark(mandelbrotBounds))
+	}()
+	if lastSession != nil && !lastSession.Clean && !restoreSession {
+		fmt.Printf("The last session, saved at %s at %.4gx zoom, ended unexpectedly. Press the restore-session key "+
+			"(Shift+Home by default) to restore it.\n", lastSession.Saved.Format(time.Stamp), lastSession.Bookmark.Zoom)
+	}
 
-	// initial offset to centre window over a zoomable area within the set
-	mandelbrotBounds = mandelbrotBounds.Moved(pixel.V(-0.6, -0.43))
+	// the handlers of the bound actions, in the order they run in when triggered together
+	ctl := controller{mode: modeNavigate}
+	if splitView {
+		ctl.mode = modePickSeed
+	}
+	ctl.zoomTo = func(selected pixel.Rect) {
+		stopMotion()
+		hist.visit(view{mandelbrotBounds, paneBounds.Size()})
+		mandelbrotBounds = pixel.Rect{
+			Min: windowToPlane(selected.Min, paneBounds, mandelbrotBounds),
+			Max: windowToPlane(selected.Max, paneBounds, mandelbrotBounds),
+		}
+	}
+	quit := false
+	// the quit key backs out of any mode first
+	ctl.on(actionQuit, func() {
+		quit = !ctl.cancel()
+	})
+	ctl.on(actionToggleJulia, func() {
+		// the seed follows the cursor until it's pinned each time the Julia set is opened
+		if splitView = !splitView; splitView {
+			ctl.setMode(modePickSeed)
+		} else if ctl.mode == modePickSeed {
+			ctl.setMode(modeNavigate)
+		}
+	})
+	ctl.on(actionPickSeed, func() {
+		splitView = true
+		ctl.setMode(modePickSeed)
+	})
+	ctl.on(actionMeasure, func() {
+		if ctl.mode == modeMeasure {
+			ctl.setMode(modeNavigate)
+		} else {
+			ctl.setMode(modeMeasure)
+		}
+	})
+	ctl.on(actionRestoreSession, func() {
+		if lastSession != nil {
+			transitionTo(lastSession.Bookmark.apply(paneBounds))
+			fmt.Printf("Restored the session saved at %s\n", lastSession.Saved.Format(time.Stamp))
+		} else {
+			fmt.Println("There's no saved session to restore")
+		}
+	})
+	ctl.on(actionReset, func() {
+		stopMotion()
+		hist.visit(view{mandelbrotBounds, paneBounds.Size()})
+		mandelbrotBounds = defaultView(paneBounds)
+	})
+	ctl.on(actionForward, func() {
+		if next, ok := hist.goForward(view{mandelbrotBounds, paneBounds.Size()}); ok {
+			stopMotion()
+			mandelbrotBounds = next.fit(paneBounds.Size())
+		}
+	})
+	ctl.on(actionBack, func() {
+		if prev, ok := hist.goBack(view{mandelbrotBounds, paneBounds.Size()}); ok {
+			stopMotion()
+			mandelbrotBounds = prev.fit(paneBounds.Size())
+		}
+	})
+	ctl.onRepeat(actionIterationsUp, func() {
+		iterations += iterationStep(iterations)
+	})
+	ctl.onRepeat(actionIterationsDown, func() {
+		if step := iterationStep(iterations); iterations > step {
+			iterations -= step
+		}
+	})
+	ctl.on(actionCycleFractal, func() {
+		// a custom formula takes precedence over the fractal, so drop it to show the next one
+		formula = ""
+		fractalName = nextName(render.Fractals(), fractalName)
+	})
+	ctl.on(actionCopyPoint, func() {
+		// the point under the cursor, or the centre of the view if the cursor isn't over the fractal
+		point := mandelbrotBounds.Center()
+		if paneBounds.Contains(win.MousePosition()) {
+			point = windowToPlane(win.MousePosition(), paneBounds, mandelbrotBounds)
+		}
+		if err := copyToClipboard(formatComplex(point)); err != nil {
+			fmt.Printf("failed to copy to the clipboard: %s\n", err)
+		} else {
+			fmt.Printf("Copied %s to the clipboard\n", formatComplex(point))
+		}
+	})
+	ctl.on(actionCycleColouring, func() {
+		colouring = nextName(render.Colourings(), colouring)
+	})
+	ctl.on(actionCyclePalette, func() {
+		paletteName = nextName(palette.Gradients(), paletteName)
+	})
+	ctl.on(actionCycleQuality, func() {
+		quality, _ = lookupQuality(nextName(qualityNames(), quality.name))
+		fmt.Printf("Quality: %s\n", quality.name)
+	})
+	ctl.on(actionCycleInterior, func() {
+		interior = nextName(render.Interiors(), interior)
+	})
+	ctl.on(actionToggleHUD, func() {
+		hud.visible = !hud.visible
+	})
+	ctl.on(actionToggleVSync, func() {
+		win.SetVSync(!win.VSync())
+		fmt.Printf("VSync enabled: %t\n", win.VSync())
+	})
+	ctl.on(actionCycleOverlay, func() {
+		// cycle through each overlay and then none
+		debugOverlay = nextName(append(render.Overlays(), ""), debugOverlay)
+		mandelbrotView.setOverlay(debugOverlay)
+	})
+	ctl.on(actionToggleCross, func() {
+		crosshair = !crosshair
+	})
+	ctl.on(actionToggleOrbit, func() {
+		showOrbit = !showOrbit
+	})
+	ctl.on(actionTogglePanel, func() {
+		controls.visible = !controls.visible
+	})
+	ctl.on(actionExport, func() {
+		// exports can take minutes, so keep exploring while they render
+		go func(p render.Params, b bookmark) {
+			if err := exportView(p, b); err != nil {
+				fmt.Printf("failed to export view: %s\n", err)
+			}
+		}(exportParams(mandelbrotBounds, paneBounds.Size()), newBookmark(mandelbrotBounds))
+	})
+	ctl.on(actionSaveBookmark, func() {
+		if err := saveBookmark(bookmarkPath, newBookmark(mandelbrotBounds)); err != nil {
+			fmt.Printf("failed to save bookmark: %s\n", err)
+		} else {
+			fmt.Printf("Saved bookmark to %s\n", bookmarkPath)
+		}
+	})
+	ctl.on(actionLoadBookmark, func() {
+		if b, err := loadBookmark(bookmarkPath); err != nil {
+			fmt.Printf("failed to load bookmark: %s\n", err)
+		} else {
+			transitionTo(b.apply(paneBounds))
+		}
+	})
+	ctl.on(actionShare, func() {
+		fmt.Printf("Share this view with -from=%q\n", encodeState(newBookmark(mandelbrotBounds)))
+	})
+	ctl.on(actionAutoExplore, func() {
+		explore.auto = !explore.auto
+		fmt.Printf("Autopilot enabled: %t\n", explore.auto)
+	})
+	ctl.on(actionExplore, func() {
+		if explore.suggested {
+			stopMotion()
+			hist.visit(view{mandelbrotBounds, paneBounds.Size()})
+			mandelbrotBounds = explore.target
+		} else {
+			explore.search(mandelbrotBounds, paneBounds.Size())
+		}
+	})
+	ctl.on(actionNextLocation, func() {
+		locationIndex = (locationIndex + 1) % len(locations)
+		fmt.Printf("Jumped to the %s preset location\n", locations[locationIndex].name)
+		transitionTo(locations[locationIndex].bookmark.apply(paneBounds))
+	})
 
 	// main game loop
 	for !win.Closed() {
-		scaleFactor := initialBoundsSize.ScaledXY(mandelbrotBounds.Size()).Scaled(0.001)
+		// run the handlers of the keys and gamepad buttons pressed, one of which may be to quit
+		if ctl.dispatch(win, &pad); quit {
+			return nil
+		}
+		// keep the same scale on resize so that a bigger pane reveals more of the plane rather than stretching it
+		pane, julia := panes(win.Bounds(), splitView)
+		if pane != paneBounds {
+			// the path of a transition depends on the size of the pane, so finish it straight away
+			if trans.active() {
+				trans.stop()
+				mandelbrotBounds = trans.to
+			}
+			mandelbrotBounds = resizeBounds(mandelbrotBounds, paneBounds.Size(), pane.Size())
+			paneBounds = pane
+		}
+		juliaPane = julia
+
+		now := time.Now()
+		dt := now.Sub(lastFrame).Seconds()
+		panDelta := pan.update(win, dt)
+		keyPan, keyZoom := motion.update(win, dt)
+		pad.update(win, dt, paneBounds.W())
+		lastFrame = now
+		scroll := win.MouseScroll()
+		if scroll != pixel.ZV {
+			lastScroll = now
+		}
+
+		// remember the view each time continuous movement starts so that it can be stepped back to
+		moving := pan.active() || motion.active() || pad.active() || now.Sub(lastScroll) < scrollGesture
+		// taking the controls turns the autopilot off
+		if moving {
+			explore.stop()
+			trans.stop()
+			lastMoved = now
+		}
+		moving = moving || explore.auto
+		if moving && !wasMoving {
+			hist.visit(view{mandelbrotBounds, paneBounds.Size()})
+		}
+		wasMoving = moving
 
-		// handle keyboard input
-		if win.JustPressed(pixelgl.KeyEscape) {
-			return
+		if trans.active() {
+			mandelbrotBounds = trans.update(dt)
+		}
+		saver.update(newBookmark(mandelbrotBounds), now)
+		if motion.active() {
+			mandelbrotBounds = mandelbrotBounds.Moved(keyPan.Scaled(mandelbrotBounds.W()))
+			mandelbrotBounds = zoomAbout(mandelbrotBounds, paneBounds, zoomAnchor(win, paneBounds, keyZoomTo), keyZoom)
+		}
+		// the content follows the mouse while dragging, so the view moves the opposite way
+		if panDelta != pixel.ZV {
+			unitsPerPixel := mandelbrotBounds.W() / paneBounds.W()
+			mandelbrotBounds = mandelbrotBounds.Moved(panDelta.Scaled(-unitsPerPixel))
+		}
+		if pad.active() {
+			unitsPerPixel := mandelbrotBounds.W() / paneBounds.W()
+			mandelbrotBounds = mandelbrotBounds.Moved(pad.pan.Scaled(unitsPerPixel))
+			mandelbrotBounds = mandelbrotBounds.Resized(mandelbrotBounds.Center(), mandelbrotBounds.Size().Scaled(pad.zoom))
 		}
-		if win.Pressed(pixelgl.KeyR) {
-			mandelbrotBounds = mandelbrotBounds.Resized(mandelbrotBounds.Center(), mandelbrotBounds.Size().Scaled(0.997))
-		} else if win.Pressed(pixelgl.KeyF) {
-			mandelbrotBounds = mandelbrotBounds.Resized(mandelbrotBounds.Center(), mandelbrotBounds.Size().Scaled(1.003))
+		// scrolling up, or pinching out on touchpads which report pinches as scrolling, zooms in
+		if scroll.Y != 0 {
+			anchor := zoomAnchor(win, paneBounds, scrollZoomTo)
+			mandelbrotBounds = zoomAbout(mandelbrotBounds, paneBounds, anchor, math.Pow(scrollZoomStep, -scroll.Y))
 		}
-		if win.Pressed(pixelgl.KeyA) {
-			mandelbrotBounds = mandelbrotBounds.Moved(pixel.V(-scaleFactor.X, 0))
-		} else if win.Pressed(pixelgl.KeyD) {
-			mandelbrotBounds = mandelbrotBounds.Moved(pixel.V(scaleFactor.X, 0))
+		exhausted := explore.poll()
+		// zooming any deeper would only magnify rounding errors. Perturbation resolves any depth, but the view itself is
+		// only held in float64.
+		minSpacing := render.Float64MinRelativeSpacing
+		perturbable := newParams(mandelbrotBounds, paneBounds.Size()).Perturbable()
+		if perturbable && (precision == render.PrecisionAuto || precision == render.PrecisionPerturbation) {
+			minSpacing = viewMinRelativeSpacing
 		}
-		if win.Pressed(pixelgl.KeyS) {
-			mandelbrotBounds = mandelbrotBounds.Moved(pixel.V(0, -scaleFactor.Y))
-		} else if win.Pressed(pixelgl.KeyW) {
-			mandelbrotBounds = mandelbrotBounds.Moved(pixel.V(0, scaleFactor.Y))
+		if explore.auto && !resolvable(mandelbrotBounds, mandelbrotBounds.W()/paneBounds.W(), minSpacing) {
+			fmt.Println("Reached the limit of precision")
+			explore.stop()
+			exhausted = true
+		}
+		if screensaver {
+			// start the journey over when it runs out of road, and pick it back up a while after being interrupted
+			if exhausted {
+				stopMotion()
+				mandelbrotBounds = defaultView(paneBounds)
+				explore.auto = true
+			} else if !explore.auto && now.Sub(lastMoved) > screensaverIdle {
+				explore.auto = true
+			}
+		}
+		if explore.auto {
+			if explore.suggested {
+				var reached bool
+				if mandelbrotBounds, reached = explore.approach(mandelbrotBounds, dt); reached {
+					explore.suggested = false
+				}
+			}
+			if !explore.suggested {
+				explore.search(mandelbrotBounds, paneBounds.Size())
+			}
+		}
+		// each command of the tour waits for the view to arrive and for any screenshot to be written
+		if cmd, ok := script.next(now, trans.active()); ok {
+			switch cmd.op {
+			case scriptGoto:
+				moveTo(cmd.bounds(mandelbrotBounds, paneBounds), 0)
+			case scriptZoomTo:
+				moveTo(cmd.bounds(mandelbrotBounds, paneBounds), cmd.duration)
+			case scriptLocation:
+				locationIndex = cmd.location
+				moveTo(locations[locationIndex].bookmark.apply(paneBounds), cmd.duration)
+			case scriptScreenshot:
+				script.screenshot(cmd, exportParams(mandelbrotBounds, paneBounds.Size()), newBookmark(mandelbrotBounds))
+			case scriptQuit:
+				return nil
+			}
+		}
+
+		// clicks on the panel's buttons don't reach the fractal beneath
+		ctl.updateMouse(win, paneBounds, mandelbrotBounds, controls.handleClick(win))
+
+		p := newParams(mandelbrotBounds, paneBounds.Size())
+
+		// the cpu renderer only needs to run when the shader can't handle the frame
+		useGPU := gpu != nil && gpu.canRender(p)
+		// note the precision fallbacks as they happen rather than every frame
+		renderer := p.SelectedPrecision()
+		if useGPU {
+			renderer = "gpu"
+		}
+		if renderer != lastRenderer {
+			level := logInfo
+			if precision != render.PrecisionAuto && renderer != precision && !useGPU {
+				level = logWarn
+			}
+			logf(level, "switched arithmetic", "from", lastRenderer, "to", renderer, "requested", precision,
+				"zoom", zoomLevel(mandelbrotBounds))
+			lastRenderer = renderer
+		}
+		// warn when the pixels collapse into blocks rather than letting them pass for detail
+		blocky := !useGPU && !p.Resolves()
+		if blocky && !lastBlocky {
+			logf(logWarn, "out of precision", "arithmetic", renderer, "zoom", zoomLevel(mandelbrotBounds))
+		}
+		lastBlocky = blocky
+		if !useGPU {
+			if mandelbrotBounds != lastBounds {
+				lastBounds, lastChanged = mandelbrotBounds, now
+			}
+			frameQuality := quality
+			// render coarsely while the view is moving, sharpen it once it settles, then refine it if it stays put
+			if now.Sub(lastMoved) < interactionSettle {
+				frameQuality = quality.interactive()
+			} else if now.Sub(lastChanged) > refineDelay {
+				frameQuality = quality.refined()
+			}
+			mandelbrotView.setParams(frameQuality.apply(p))
+		}
+		var jp render.Params
+		if splitView {
+			jp = juliaParams(juliaPane, juliaSeed)
+			juliaView.setParams(quality.apply(jp))
+		}
+
+		name := p.Fractal
+		if p.Formula != "" {
+			name = p.Formula
+		}
+		if t := fmt.Sprintf("Mandelbrot - %s - %d iterations", name, quality.apply(p).Iterations); t != title {
+			title = t
+			win.SetTitle(title)
 		}
 
 		// draw window and mandelbrot
 		win.Clear(colourBlack)
 
-		mandelbrotMu.RLock()
-		tempMandelbrotSprite := mandelbrotSprite
-		mandelbrotMu.RUnlock()
-		tempMandelbrotSprite.Draw(win, pixel.IM.Moved(win.Bounds().Size().Scaled(0.5)))
+		var renderTime time.Duration
+		progress := 1.0
+		activeRenderer := "cpu"
+		if p.SIMD && render.HasSIMD() {
+			activeRenderer = "cpu-simd"
+		}
+		if p.OpenCL && render.HasOpenCL() {
+			activeRenderer = "opencl"
+		}
+		if useGPU {
+			activeRenderer = "gpu"
+			gpu.draw(win, paneBounds.Center(), p)
+		} else {
+			activeRenderer += ", " + p.SelectedPrecision() + ", " + quality.name
+			renderTime, progress = mandelbrotView.draw(win, p, paneBounds.Center())
+		}
+		if splitView {
+			juliaView.draw(win, jp, juliaPane.Center())
+		}
+
+		// draw overlays
+		overlay.Clear()
+		ctl.draw(overlay, win, paneBounds, mandelbrotBounds)
+		explore.draw(overlay, paneBounds, mandelbrotBounds)
+		if showOrbit {
+			drawCursorOrbit(overlay, win.MousePosition(), paneBounds, juliaPane, quality.apply(p), quality.apply(jp))
+		}
+		if crosshair && paneBounds.Contains(win.MousePosition()) {
+			drawCrosshair(overlay, win.MousePosition(), paneBounds)
+		}
+		overlay.Draw(win)
+		hud.draw(win, hudStats{
+			centre:     mandelbrotBounds.Center(),
+			cursor:     windowToPlane(win.MousePosition(), paneBounds, mandelbrotBounds),
+			zoom:       zoomLevel(mandelbrotBounds),
+			iterations: quality.apply(p).Iterations,
+			colouring:  p.Colouring,
+			trap:       p.Trap,
+			palette:    p.Palette,
+			interior:   p.Interior,
+			renderTime: renderTime,
+			progress:   progress,
+			renderer:   activeRenderer,
+			overlay:    debugOverlay,
+			warning:    precisionWarning(p, blocky),
+			mode:       ctl.status(),
+			frame:      mandelbrotView.stats(),
+		})
+		controls.draw(win)
+		hud.tick()
 
 		win.Update()
 
-		<-frameRateLimiter
+		if frameRateLimiter != nil {
+			<-frameRateLimiter
+		}
 	}
+	return nil
 }
 
-// generates a fresh mandelbrot represented in pixel.Sprite form
-func generate() {
-	for py := 0.0; py < windowSize; py++ {
-		y := py/windowSize*(mandelbrotBounds.Max.Y-mandelbrotBounds.Min.Y) + mandelbrotBounds.Min.Y
+// returns the bounds shown in paneBounds of the window on start up, after applying any -location, -load, -from or -open
+func startingView(windowBounds, paneBounds pixel.Rect) pixel.Rect {
+	// initial offset to centre window over a zoomable area within the set
+	bounds := resizeBounds(mandelbrotBounds.Moved(initialOffset), windowBounds.Size(), paneBounds.Size())
+	if locationIndex >= 0 {
+		bounds = locations[locationIndex].bookmark.apply(paneBounds)
+	}
+	if loadPath != "" {
+		if b, err := loadBookmark(loadPath); err != nil {
+			fmt.Printf("failed to load bookmark: %s\n", err)
+		} else {
+			bounds = b.apply(paneBounds)
+		}
+	}
+	if fromState != "" {
+		bounds = sharedBookmark.apply(paneBounds)
+	}
+	if restoreSession && lastSession != nil {
+		bounds = lastSession.Bookmark.apply(paneBounds)
+	}
+	return bounds
+}
 
-		for px := 0.0; px < windowSize; px++ {
-			x := px/windowSize*(mandelbrotBounds.Max.X-mandelbrotBounds.Min.X) + mandelbrotBounds.Min.X
-			z := complex(x, y)
+// returns the default view of the whole set filling pane, which Home resets to wherever the view started
+func defaultView(pane pixel.Rect) pixel.Rect {
+	size := pane.Size()
+	return centredRect(initialOffset, size.Scaled(defaultSpan/math.Min(size.X, size.Y)))
+}
 
-			// set individual pixel image data
-			i := pixelData.Index(pixel.V(px, py))
-			pixelData.Pix[i] = processPixel(z)
-		}
+// returns the matrix which draws a sprite rendered for the view from where it would appear in the view to, so
+// that the previous frame is stretched and shifted to approximate a new view while it renders
+func spriteMatrix(from, to render.Params, windowCentre pixel.Vec) pixel.Matrix {
+	if to.Scale <= 0 || from.Scale <= 0 {
+		return pixel.IM.Moved(windowCentre)
 	}
+	offset := from.Centre - to.Centre
+	return pixel.IM.
+		Scaled(pixel.ZV, from.Scale/to.Scale).
+		Moved(windowCentre.Add(pixel.V(real(offset), imag(offset)).Scaled(1 / to.Scale)))
+}
 
-	newSprite := pixel.NewSprite(pixelData, pixelData.Bounds())
-	mandelbrotMu.Lock()
-	mandelbrotSprite = newSprite
-	mandelbrotMu.Unlock()
+// maps the plane bounds shown in a window of oldSize onto a window of newSize, preserving the centre and the plane units per pixel
+func resizeBounds(bounds pixel.Rect, oldSize, newSize pixel.Vec) pixel.Rect {
+	if oldSize.X <= 0 || oldSize.Y <= 0 || newSize.X <= 0 || newSize.Y <= 0 {
+		return bounds
+	}
+	unitsPerPixel := bounds.Size().ScaledXY(pixel.V(1/oldSize.X, 1/oldSize.Y))
+	return bounds.Resized(bounds.Center(), newSize.ScaledXY(unitsPerPixel))
 }
 
-func processPixel(c complex128) color.RGBA {
-	var z complex128
+// reports whether pixels spaced scale apart within bounds can be told apart by arithmetic which resolves differences
+// down to minRelativeSpacing relative to the magnitude of the coordinates
+func resolvable(bounds pixel.Rect, scale, minRelativeSpacing float64) bool {
+	magnitude := math.Max(math.Max(math.Abs(bounds.Min.X), math.Abs(bounds.Max.X)), math.Max(math.Abs(bounds.Min.Y), math.Abs(bounds.Max.Y)))
+	return scale >= math.Max(magnitude, 1)*minRelativeSpacing
+}
 
-	for n := uint8(0); n < uint8(iterations); n++ {
-		z = z*z + c
+// returns the magnification of bounds relative to the default view
+func zoomLevel(bounds pixel.Rect) float64 {
+	return defaultSpan / math.Min(bounds.W(), bounds.H())
+}
 
-		if cmplx.Abs(z) > 16 {
-			return color.RGBA{
-				R: 60 - colourContrast*n,
-				G: 180 - colourContrast*n,
-				B: colourContrast * n,
-				A: 255,
-			}
+// returns a rect of the given size centred on centre
+func centredRect(centre, size pixel.Vec) pixel.Rect {
+	half := size.Scaled(0.5)
+	return pixel.Rect{Min: centre.Sub(half), Max: centre.Add(half)}
+}
+
+// scales the base iteration limit logarithmically with zoom so that detail isn't lost to points which never escape
+func adaptiveIterations(base uint, zoom float64) uint {
+	if zoom <= 1 {
+		return base
+	}
+	return base + uint(iterationsPerZoomDoubling*math.Log2(zoom))
+}
+
+// the amount the iteration limit is adjusted by per key press, roughly 10% of the current value
+func iterationStep(n uint) uint {
+	if n < 10 {
+		return 1
+	}
+	return n / 10
+}
+
+// returns the name following name in names, wrapping around at the end
+func nextName(names []string, name string) string {
+	for i, n := range names {
+		if n == name {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+// returns the name preceding name in names, wrapping around at the start
+func prevName(names []string, name string) string {
+	for i, n := range names {
+		if n == name {
+			return names[(i+len(names)-1)%len(names)]
 		}
 	}
-	return colourBlack
+	return names[0]
 }
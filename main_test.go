@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestResizeBounds(t *testing.T) {
+	square := pixel.R(-2, -2, 2, 2)
+	tests := []struct {
+		name             string
+		bounds           pixel.Rect
+		oldSize, newSize pixel.Vec
+		want             pixel.Rect
+	}{
+		{name: "unchanged", bounds: square, oldSize: pixel.V(800, 800), newSize: pixel.V(800, 800), want: square},
+		{name: "wider", bounds: square, oldSize: pixel.V(800, 800), newSize: pixel.V(1600, 800),
+			want: pixel.R(-4, -2, 4, 2)},
+		{name: "taller", bounds: square, oldSize: pixel.V(800, 800), newSize: pixel.V(800, 1200),
+			want: pixel.R(-2, -3, 2, 3)},
+		{name: "smaller", bounds: square, oldSize: pixel.V(800, 800), newSize: pixel.V(400, 200),
+			want: pixel.R(-1, -0.5, 1, 0.5)},
+		{name: "back to square", bounds: pixel.R(-4, -2, 4, 2), oldSize: pixel.V(1600, 800),
+			newSize: pixel.V(800, 800), want: square},
+		{name: "off centre", bounds: pixel.R(0, 1, 2, 2), oldSize: pixel.V(400, 200), newSize: pixel.V(200, 200),
+			want: pixel.R(0.5, 1, 1.5, 2)},
+		{name: "minimised", bounds: square, oldSize: pixel.V(800, 800), newSize: pixel.V(0, 0), want: square},
+		{name: "from nothing", bounds: square, oldSize: pixel.V(0, 800), newSize: pixel.V(800, 800), want: square},
+	}
+	for _, tt := range tests {
+		got := resizeBounds(tt.bounds, tt.oldSize, tt.newSize)
+		if got != tt.want {
+			t.Errorf("%s: expected %v resized from %v to %v to be %v, got %v", tt.name, tt.bounds, tt.oldSize,
+				tt.newSize, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+const (
+	// the eight bytes every PNG starts with
+	pngSignature = "\x89PNG\r\n\x1a\n"
+	// the length of the IEND chunk which ends every PNG: its length, type and CRC, with no data
+	iendLength = 12
+	// the tEXt keyword of the shareable state embedded in exported images, which restores their view exactly
+	stateKeyword = "Mandelbrot State"
+	// the longest chunk the PNG spec allows
+	maxPNGChunk = 1<<31 - 1
+	// the longest tEXt chunk read, far longer than any state but short enough to read into memory
+	maxPNGText = 1 << 20
+)
+
+// an image exported with embedded metadata to restore the view of on start up
+var openPath string
+
+// pngText is a tEXt chunk of a PNG, a keyword and its Latin-1 text
+type pngText struct {
+	keyword, text string
+}
+
+// describes the render of an exported image as PNG text chunks: the render parameters for reading at a glance, and the
+// shareable state of the bookmark for restoring the view exactly
+func exportMetadata(p render.Params, b bookmark, renderTime time.Duration) []pngText {
+	text := []pngText{
+		{"Software", "mandelbrot " + programVersion()},
+		{"Centre", formatPoint(b.Centre)},
+		{"Zoom", formatFloat(b.Zoom)},
+		{"Iterations", strconv.Itoa(p.Iterations)},
+		{"Fractal", p.Fractal},
+	}
+	if p.Formula != "" {
+		text = append(text, pngText{"Formula", p.Formula})
+	}
+	if p.Fractal == "newton" && p.Formula == "" {
+		text = append(text, pngText{"Polynomial", p.Polynomial})
+	}
+	return append(text,
+		pngText{"Colouring", p.Colouring},
+		pngText{"Palette", p.Palette},
+		pngText{"Render Time", renderTime.Round(time.Millisecond).String()},
+		pngText{stateKeyword, encodeState(b)},
+	)
+}
+
+// returns the module version the program was built from, which is "(devel)" for builds from a local checkout
+func programVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// pngTextWriter passes an encoded PNG through to w, holding back its final IEND chunk so that text chunks can be added
+// once the image data has been written, e.g. the time it took to render
+type pngTextWriter struct {
+	w    io.Writer
+	held []byte
+}
+
+func (t *pngTextWriter) Write(b []byte) (int, error) {
+	t.held = append(t.held, b...)
+	if len(t.held) <= iendLength {
+		return len(b), nil
+	}
+	if _, err := t.w.Write(t.held[:len(t.held)-iendLength]); err != nil {
+		return 0, err
+	}
+	t.held = append(t.held[:0], t.held[len(t.held)-iendLength:]...)
+	return len(b), nil
+}
+
+// writes the text chunks followed by the held back IEND chunk, ending the PNG
+func (t *pngTextWriter) finish(text []pngText) error {
+	if len(t.held) != iendLength || string(t.held[4:8]) != "IEND" {
+		return fmt.Errorf("expected the PNG to end with an IEND chunk")
+	}
+	for _, c := range text {
+		if err := writePNGChunk(t.w, "tEXt", []byte(c.keyword+"\x00"+c.text)); err != nil {
+			return err
+		}
+	}
+	_, err := t.w.Write(t.held)
+	return err
+}
+
+// writes a PNG chunk: the length of its data, its type, the data and a CRC of the type and data
+func writePNGChunk(w io.Writer, kind string, data []byte) error {
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(data)))
+	chunk.WriteString(kind)
+	chunk.Write(data)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()[4:]))
+	_, err := w.Write(chunk.Bytes())
+	return err
+}
+
+// reads the shareable state embedded in an image exported by exportView
+func readImageState(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	text, err := readPNGText(bufio.NewReader(f))
+	if err != nil {
+		return "", fmt.Errorf("invalid PNG %s: %s", path, err)
+	}
+	state, ok := text[stateKeyword]
+	if !ok {
+		return "", fmt.Errorf("%s has no embedded view, only images exported by mandelbrot can be opened", path)
+	}
+	return state, nil
+}
+
+// reads the text chunks of a PNG, keyed by their keywords
+func readPNGText(r io.Reader) (map[string]string, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil || string(signature) != pngSignature {
+		return nil, fmt.Errorf("not a PNG")
+	}
+
+	text := make(map[string]string)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		length, kind := binary.BigEndian.Uint32(header[:4]), string(header[4:])
+		if length > maxPNGChunk {
+			return nil, fmt.Errorf("invalid PNG %s chunk length %d", kind, length)
+		}
+		switch {
+		case kind == "IEND":
+			return text, nil
+		case kind == "tEXt" && length <= maxPNGText:
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			if keyword, value, ok := bytes.Cut(data, []byte{0}); ok {
+				text[string(keyword)] = string(value)
+			}
+		default:
+			// the image data and any text too long to be state are of no interest, so skip them
+			if _, err := io.CopyN(ioutil.Discard, r, int64(length)); err != nil {
+				return nil, err
+			}
+		}
+		// skip the CRC
+		if _, err := io.CopyN(ioutil.Discard, r, 4); err != nil {
+			return nil, err
+		}
+	}
+}
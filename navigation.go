@@ -0,0 +1,218 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+const (
+	// how much each notch of the scroll wheel zooms, or the equivalent distance of a touchpad pinch or scroll
+	scrollZoomStep = 1.15
+	// how long after the last scroll event a scroll gesture is considered over, so that it's one step in the history
+	scrollGesture = 300 * time.Millisecond
+	// how much of the drag velocity each frame's movement replaces, smoothing out jitter at the end of a drag
+	dragSmoothing = 0.3
+	// the fraction of its speed a released drag keeps each second as it glides to a stop
+	glideDecay = 0.02
+	// the speed in window pixels per second below which a glide stops
+	minGlideSpeed = 5
+	// how many times over the zoom keys zoom the view per second, the same at any depth
+	keyZoomRate = 1.5
+	// the speed in window widths per second the pan keys move the view
+	keyPanSpeed = 0.5
+	// the time in seconds the key movement takes to get most of the way up to speed or to a stop, easing it in and out
+	keyEasing = 0.12
+	// the zoom and pan speeds, as fractions of full speed, below which released keys stop the view
+	minKeySpeed = 0.01
+	// how far transitions between distant views zoom out on the way, with larger values zooming out further to pan
+	// less. Around the square root of 2 looks the most natural.
+	transitionCurvature = 1.4
+)
+
+// how long transitions to bookmarks and preset locations take, or 0 to jump straight there
+var transitionTime time.Duration
+
+// panner pans the view by dragging with the right mouse button. Released drags keep gliding in the same direction and
+// slow to a stop, like a map application.
+type panner struct {
+	dragging bool
+	// the mouse position on the previous frame of the drag
+	last pixel.Vec
+	// the drag or glide velocity in window pixels per second
+	velocity pixel.Vec
+}
+
+// returns the distance in window pixels the content of the view should move this frame, dt seconds after the last
+func (pn *panner) update(win *pixelgl.Window, dt float64) pixel.Vec {
+	pos := win.MousePosition()
+	if win.JustPressed(pixelgl.MouseButtonRight) {
+		pn.dragging, pn.last, pn.velocity = true, pos, pixel.ZV
+		return pixel.ZV
+	}
+
+	if pn.dragging {
+		delta := pos.Sub(pn.last)
+		pn.last = pos
+		if dt > 0 {
+			pn.velocity = pixel.Lerp(pn.velocity, delta.Scaled(1/dt), dragSmoothing)
+		}
+		if !win.Pressed(pixelgl.MouseButtonRight) {
+			pn.dragging = false
+		}
+		return delta
+	}
+
+	if pn.velocity.Len() < minGlideSpeed {
+		pn.velocity = pixel.ZV
+		return pixel.ZV
+	}
+	delta := pn.velocity.Scaled(dt)
+	pn.velocity = pn.velocity.Scaled(math.Pow(glideDecay, dt))
+	return delta
+}
+
+// reports whether the view is being dragged or is still gliding
+func (pn *panner) active() bool {
+	return pn.dragging || pn.velocity != pixel.ZV
+}
+
+// stops any glide in progress, e.g. when the view jumps elsewhere
+func (pn *panner) stop() {
+	pn.velocity = pixel.ZV
+}
+
+// scales bounds by factor about the window position pos, keeping the point under pos in place
+func zoomAbout(bounds, windowBounds pixel.Rect, pos pixel.Vec, factor float64) pixel.Rect {
+	anchor := windowToPlane(pos, windowBounds, bounds)
+	return pixel.Rect{
+		Min: anchor.Add(bounds.Min.Sub(anchor).Scaled(factor)),
+		Max: anchor.Add(bounds.Max.Sub(anchor).Scaled(factor)),
+	}
+}
+
+// keyMotion moves the view with the zoom and pan keys. Zooming is a constant speed on a log scale and panning a constant
+// number of window widths per second, so both feel the same at any depth and frame rate, and they ease in and out
+// rather than starting and stopping dead.
+type keyMotion struct {
+	// the zoom speed as the log of the zoom factor per second, positive zooming out
+	zoom float64
+	// the pan velocity in window widths per second
+	velocity pixel.Vec
+}
+
+// returns how far the view should pan this frame, dt seconds after the last, in window widths, and the factor to scale
+// it by
+func (m *keyMotion) update(win *pixelgl.Window, dt float64) (pixel.Vec, float64) {
+	var zoom float64
+	if keys.pressed(win, actionZoomIn) {
+		zoom = -math.Log(keyZoomRate)
+	} else if keys.pressed(win, actionZoomOut) {
+		zoom = math.Log(keyZoomRate)
+	}
+	var velocity pixel.Vec
+	if keys.pressed(win, actionPanLeft) {
+		velocity.X = -keyPanSpeed
+	} else if keys.pressed(win, actionPanRight) {
+		velocity.X = keyPanSpeed
+	}
+	if keys.pressed(win, actionPanDown) {
+		velocity.Y = -keyPanSpeed
+	} else if keys.pressed(win, actionPanUp) {
+		velocity.Y = keyPanSpeed
+	}
+
+	// close the same fraction of the gap to the target speeds every second, whatever the frame rate
+	ease := 1 - math.Exp(-dt/keyEasing)
+	m.zoom += (zoom - m.zoom) * ease
+	m.velocity = pixel.Lerp(m.velocity, velocity, ease)
+	if zoom == 0 && math.Abs(m.zoom) < minKeySpeed*math.Log(keyZoomRate) {
+		m.zoom = 0
+	}
+	if velocity == pixel.ZV && m.velocity.Len() < minKeySpeed*keyPanSpeed {
+		m.velocity = pixel.ZV
+	}
+	return m.velocity.Scaled(dt), math.Exp(m.zoom * dt)
+}
+
+// reports whether the keys are moving the view, including while it eases to a stop
+func (m *keyMotion) active() bool {
+	return m.zoom != 0 || m.velocity != pixel.ZV
+}
+
+// stops the key movement in its tracks, e.g. when the view jumps elsewhere
+func (m *keyMotion) stop() {
+	m.zoom, m.velocity = 0, pixel.ZV
+}
+
+// transition animates the view to a bookmark or preset location along the smooth zoom and pan path of van Wijk and
+// Nuij, zooming out far enough on the way that the view seems to move at a steady speed, rather than jumping.
+type transition struct {
+	from, to pixel.Rect
+	// the seconds elapsed out of the total duration
+	elapsed, duration float64
+	// the length of the path, and the parameter r0 of the zoom along it, or whether it only zooms
+	length, r0 float64
+	zoomOnly   bool
+	running    bool
+}
+
+// starts moving the view from one set of bounds to another of the same aspect ratio over the given duration
+func (t *transition) start(from, to pixel.Rect, duration time.Duration) {
+	*t = transition{from: from, to: to, duration: duration.Seconds()}
+	w0, w1 := from.W(), to.W()
+	u1 := to.Center().Sub(from.Center()).Len()
+	rho := transitionCurvature
+	if u1 <= 1e-9*math.Min(w0, w1) {
+		// no pan worth animating, so zoom at a steady rate, centring along the way
+		t.zoomOnly = true
+		t.length = math.Abs(math.Log(w1/w0)) / rho
+	} else {
+		b0 := (w1*w1 - w0*w0 + rho*rho*rho*rho*u1*u1) / (2 * w0 * rho * rho * u1)
+		b1 := (w1*w1 - w0*w0 - rho*rho*rho*rho*u1*u1) / (2 * w1 * rho * rho * u1)
+		t.r0 = -math.Asinh(b0)
+		t.length = (-math.Asinh(b1) - t.r0) / rho
+	}
+	t.running = t.duration > 0 && t.length > 0
+}
+
+// returns the bounds of the view dt seconds after the last update, easing in and out of the path
+func (t *transition) update(dt float64) pixel.Rect {
+	t.elapsed += dt
+	f := t.elapsed / t.duration
+	if f >= 1 || !t.running {
+		t.running = false
+		return t.to
+	}
+	return t.at(t.length * f * f * (3 - 2*f))
+}
+
+// returns the bounds a distance s along the path
+func (t *transition) at(s float64) pixel.Rect {
+	rho := transitionCurvature
+	w0, w1 := t.from.W(), t.to.W()
+	var w, travelled float64
+	if t.zoomOnly {
+		w = w0 * math.Pow(w1/w0, s/t.length)
+		travelled = s / t.length
+	} else {
+		u1 := t.to.Center().Sub(t.from.Center()).Len()
+		w = w0 * math.Cosh(t.r0) / math.Cosh(rho*s+t.r0)
+		u := w0 / (rho * rho) * (math.Cosh(t.r0)*math.Tanh(rho*s+t.r0) - math.Sinh(t.r0))
+		travelled = u / u1
+	}
+	centre := pixel.Lerp(t.from.Center(), t.to.Center(), travelled)
+	return centredRect(centre, t.to.Size().Scaled(w/w1))
+}
+
+// reports whether the view is on its way to a bookmark or location
+func (t *transition) active() bool {
+	return t.running
+}
+
+// abandons the transition where it is, e.g. when the view is moved by hand
+func (t *transition) stop() {
+	t.running = false
+}
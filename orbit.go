@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	// whether to draw the orbit of the point under the cursor, and the most iterations of it to draw
+	showOrbit   bool
+	orbitLength uint
+
+	orbitColour = pixel.RGB(1, 0.3, 0.3)
+	// drawn beneath the orbit so that it stands out against light regions too
+	orbitShadow = pixel.RGBA{A: 0.6}
+)
+
+// draws the orbit of the point under the cursor, over the fractal in pane rendered with p or, in split view, the Julia
+// set in juliaPane rendered with jp
+func drawCursorOrbit(imd *imdraw.IMDraw, pos pixel.Vec, pane, juliaPane pixel.Rect, p, jp render.Params) {
+	bounds := mandelbrotBounds
+	switch {
+	case pane.Contains(pos):
+	case juliaPane.Area() > 0 && juliaPane.Contains(pos):
+		pane, bounds, p = juliaPane, juliaBounds(juliaPane), jp
+	default:
+		return
+	}
+	if uint(p.Iterations) > orbitLength {
+		p.Iterations = int(orbitLength)
+	}
+	c := windowToPlane(pos, pane, bounds)
+	orbit := render.Orbit(p, complex(c.X, c.Y))
+	points := make([]pixel.Vec, len(orbit))
+	for i, z := range orbit {
+		points[i] = planeToWindow(pixel.V(real(z), imag(z)), pane, bounds)
+	}
+	drawOrbit(imd, points)
+}
+
+// draws the points of an orbit in window space joined up in order, marking each one
+func drawOrbit(imd *imdraw.IMDraw, points []pixel.Vec) {
+	for _, line := range []struct {
+		colour    pixel.RGBA
+		thickness float64
+	}{{orbitShadow, 3}, {orbitColour, 1}} {
+		imd.Color = line.colour
+		if len(points) > 1 {
+			imd.Push(points...)
+			imd.Line(line.thickness)
+		}
+		imd.Push(points...)
+		imd.Circle(2, line.thickness)
+	}
+}
@@ -0,0 +1,187 @@
+package palette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadGradient reads a gradient from the file at path, parsing it by its extension as described by ParseGradient, and
+// names it after the file without its extension.
+func LoadGradient(path string) (string, Gradient, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	g, err := ParseGradient(data, filepath.Ext(path))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid palette file %s: %s", path, err)
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), g, nil
+}
+
+// ParseGradient parses a gradient in the format given by a file extension:
+//
+//   - .json, a list of stops such as [{"pos": 0, "colour": "#000764"}, {"pos": 1, "colour": "#ffffff"}]
+//   - .csv, a line per stop of either r,g,b or pos,r,g,b with components from 0 to 255, or #rrggbb or pos,#rrggbb
+//   - .map, a Fractint colour map of a line per colour of r g b, optionally followed by a comment
+//
+// Stops without positions are spread evenly along the gradient, and stops with them must be in order from 0 to 1.
+func ParseGradient(data []byte, ext string) (Gradient, error) {
+	var stops []fileStop
+	var err error
+	switch strings.ToLower(ext) {
+	case ".json":
+		stops, err = parseJSONStops(data)
+	case ".csv":
+		stops, err = parseCSVStops(data)
+	case ".map":
+		stops, err = parseMapStops(data)
+	default:
+		return nil, fmt.Errorf("unknown palette format %q, expected .json, .csv or .map", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newFileGradient(stops)
+}
+
+// fileStop is a gradient stop read from a palette file, which may leave its position out
+type fileStop struct {
+	Pos    *float64 `json:"pos"`
+	Colour string   `json:"colour"`
+	colour color.RGBA
+}
+
+// places the stops along a gradient, spreading them evenly if they don't have positions
+func newFileGradient(stops []fileStop) (Gradient, error) {
+	if len(stops) == 0 {
+		return nil, fmt.Errorf("no colours")
+	}
+	g := make(Gradient, len(stops))
+	for i, s := range stops {
+		if (s.Pos == nil) != (stops[0].Pos == nil) {
+			return nil, fmt.Errorf("stop %d: either every stop or none of them need a position", i+1)
+		}
+		g[i].Colour = s.colour
+		switch {
+		case s.Pos != nil:
+			g[i].Pos = *s.Pos
+		case len(stops) > 1:
+			g[i].Pos = float64(i) / float64(len(stops)-1)
+		}
+		if g[i].Pos < 0 || g[i].Pos > 1 {
+			return nil, fmt.Errorf("stop %d: position %g is outside of 0 to 1", i+1, g[i].Pos)
+		}
+		if i > 0 && g[i].Pos < g[i-1].Pos {
+			return nil, fmt.Errorf("stop %d: position %g is before the previous stop", i+1, g[i].Pos)
+		}
+	}
+	return g, nil
+}
+
+func parseJSONStops(data []byte) ([]fileStop, error) {
+	var stops []fileStop
+	if err := json.Unmarshal(data, &stops); err != nil {
+		return nil, err
+	}
+	for i := range stops {
+		c, err := parseHexColour(stops[i].Colour)
+		if err != nil {
+			return nil, fmt.Errorf("stop %d: %s", i+1, err)
+		}
+		stops[i].colour = c
+	}
+	return stops, nil
+}
+
+func parseCSVStops(data []byte) ([]fileStop, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var stops []fileStop
+	for line := 1; ; line++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return stops, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var s fileStop
+		// a position comes first when there's one more field than the colour needs
+		hex := strings.HasPrefix(fields[len(fields)-1], "#")
+		if hex && len(fields) == 2 || !hex && len(fields) == 4 {
+			pos, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid position %q", line, fields[0])
+			}
+			s.Pos, fields = &pos, fields[1:]
+		}
+		if hex && len(fields) == 1 {
+			s.colour, err = parseHexColour(fields[0])
+		} else if !hex && len(fields) == 3 {
+			s.colour, err = parseRGB(fields)
+		} else {
+			err = fmt.Errorf("expected r,g,b or #rrggbb optionally preceded by a position")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		stops = append(stops, s)
+	}
+}
+
+func parseMapStops(data []byte) ([]fileStop, error) {
+	var stops []fileStop
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected r g b", line)
+		}
+		c, err := parseRGB(fields[:3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		stops = append(stops, fileStop{colour: c})
+	}
+	return stops, scanner.Err()
+}
+
+// parses a colour written as #rrggbb
+func parseHexColour(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// parses a colour written as its red, green and blue components from 0 to 255
+func parseRGB(fields []string) (color.RGBA, error) {
+	var rgb [3]uint8
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid colour component %q, expected 0 to 255", f)
+		}
+		rgb[i] = uint8(v)
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
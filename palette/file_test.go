@@ -0,0 +1,85 @@
+package palette
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseGradient(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+	orange := color.RGBA{255, 170, 0, 255}
+	tests := []struct {
+		name, ext, data string
+		want            Gradient
+	}{
+		{"json", ".json", `[{"pos": 0, "colour": "#000000"}, {"pos": 0.25, "colour": "#ffaa00"}, {"pos": 1, "colour": "#ffffff"}]`,
+			Gradient{{0, black}, {0.25, orange}, {1, white}}},
+		{"json without positions", ".json", `[{"colour": "#000000"}, {"colour": "#ffaa00"}, {"colour": "#ffffff"}]`,
+			Gradient{{0, black}, {0.5, orange}, {1, white}}},
+		{"csv", ".csv", "0, 0, 0, 0\n0.25, 255, 170, 0\n1, 255, 255, 255\n", Gradient{{0, black}, {0.25, orange}, {1, white}}},
+		{"csv without positions", ".csv", "0,0,0\n255,170,0\n255,255,255", Gradient{{0, black}, {0.5, orange}, {1, white}}},
+		{"csv hex", ".CSV", "0,#000000\n0.25,#ffaa00\n1,#ffffff\n", Gradient{{0, black}, {0.25, orange}, {1, white}}},
+		{"fractint map", ".map", "0 0 0 black\n255 170 0   orange\n\n255 255 255\n", Gradient{{0, black}, {0.5, orange}, {1, white}}},
+		{"single colour", ".csv", "#ffaa00", Gradient{{0, orange}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseGradient([]byte(tt.data), tt.ext)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+
+	for _, tt := range []struct{ name, ext, data string }{
+		{"unknown format", ".txt", "#000000"},
+		{"empty", ".csv", ""},
+		{"bad colour", ".json", `[{"colour": "red"}]`},
+		{"out of range component", ".map", "0 0 256"},
+		{"out of order", ".csv", "0.5,#000000\n0.25,#ffffff"},
+		{"position past the end", ".csv", "0,#000000\n2,#ffffff"},
+		{"some positions", ".json", `[{"pos": 0, "colour": "#000000"}, {"colour": "#ffffff"}]`},
+		{"wrong field count", ".csv", "1,2"},
+	} {
+		if _, err := ParseGradient([]byte(tt.data), tt.ext); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestRegisterGradient(t *testing.T) {
+	defer func(saved []namedGradient) { gradients = saved }(gradients)
+	if err := RegisterGradient("fire", Gradient{{0, color.RGBA{}}}); err == nil {
+		t.Error("expected replacing a built in gradient to fail")
+	}
+
+	g := Gradient{{0, color.RGBA{1, 2, 3, 255}}, {1, color.RGBA{4, 5, 6, 255}}}
+	if err := RegisterGradient("test-custom", g); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	names := Gradients()
+	if names[len(names)-1] != "test-custom" {
+		t.Errorf("expected the registered gradient to be listed last, got %v", names)
+	}
+	if got, ok := LookupGradient("test-custom"); !ok || got[1] != g[1] {
+		t.Errorf("expected to look up the registered gradient, got %v", got)
+	}
+
+	// registering again replaces it
+	g2 := Gradient{{0, color.RGBA{7, 8, 9, 255}}}
+	if err := RegisterGradient("test-custom", g2); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, _ := LookupGradient("test-custom"); len(got) != 1 || len(Gradients()) != len(names) {
+		t.Errorf("expected the gradient to be replaced, got %v", got)
+	}
+}
@@ -0,0 +1,160 @@
+package palette
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Stop is a colour at a position along a gradient.
+type Stop struct {
+	Pos    float64
+	Colour color.RGBA
+}
+
+// Gradient is a continuous palette made up of colour stops ordered by position, spanning positions 0 to 1.
+type Gradient []Stop
+
+// At returns the colour at position t along the gradient, linearly interpolating between the neighbouring stops.
+// Positions outside of [0, 1] are clamped.
+func (g Gradient) At(t float64) color.RGBA {
+	if len(g) == 0 {
+		return Interior
+	}
+	t = math.Max(0, math.Min(1, t))
+
+	// find the first stop after t
+	i := sort.Search(len(g), func(i int) bool { return g[i].Pos > t })
+	if i == 0 {
+		return g[0].Colour
+	}
+	if i == len(g) {
+		return g[len(g)-1].Colour
+	}
+
+	a, b := g[i-1], g[i]
+	f := (t - a.Pos) / (b.Pos - a.Pos)
+	return color.RGBA{
+		R: lerp(a.Colour.R, b.Colour.R, f),
+		G: lerp(a.Colour.G, b.Colour.G, f),
+		B: lerp(a.Colour.B, b.Colour.B, f),
+		A: lerp(a.Colour.A, b.Colour.A, f),
+	}
+}
+
+func lerp(a, b uint8, f float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*f))
+}
+
+// guards gradients, which can have gradients added while images are coloured
+var gradientsMu sync.RWMutex
+
+// a gradient and the name it's picked by
+type namedGradient struct {
+	name     string
+	gradient Gradient
+}
+
+// the built in and registered gradients, in cycling order
+var gradients = []namedGradient{
+	{name: "ultra", gradient: Gradient{
+		{Pos: 0, Colour: color.RGBA{0, 7, 100, 255}},
+		{Pos: 0.16, Colour: color.RGBA{32, 107, 203, 255}},
+		{Pos: 0.42, Colour: color.RGBA{237, 255, 255, 255}},
+		{Pos: 0.6425, Colour: color.RGBA{255, 170, 0, 255}},
+		{Pos: 0.8575, Colour: color.RGBA{0, 2, 0, 255}},
+		{Pos: 1, Colour: color.RGBA{0, 7, 100, 255}},
+	}},
+	{name: "fire", gradient: Gradient{
+		{Pos: 0, Colour: color.RGBA{0, 0, 0, 255}},
+		{Pos: 0.3, Colour: color.RGBA{160, 20, 0, 255}},
+		{Pos: 0.6, Colour: color.RGBA{255, 150, 0, 255}},
+		{Pos: 1, Colour: color.RGBA{255, 255, 220, 255}},
+	}},
+	{name: "lime", gradient: Gradient{
+		{Pos: 0, Colour: color.RGBA{60, 180, 0, 255}},
+		{Pos: 0.5, Colour: color.RGBA{0, 50, 128, 255}},
+		{Pos: 1, Colour: color.RGBA{60, 180, 255, 255}},
+	}},
+	{name: "grey", gradient: Gradient{
+		{Pos: 0, Colour: color.RGBA{0, 0, 0, 255}},
+		{Pos: 1, Colour: color.RGBA{255, 255, 255, 255}},
+	}},
+	// the perceptually uniform matplotlib colour maps, whose lightness rises steadily so that bands read the same to
+	// colour blind eyes and in greyscale
+	{name: "viridis", gradient: evenStops(
+		color.RGBA{68, 1, 84, 255}, color.RGBA{72, 40, 120, 255}, color.RGBA{62, 73, 137, 255},
+		color.RGBA{49, 104, 142, 255}, color.RGBA{38, 130, 142, 255}, color.RGBA{31, 158, 137, 255},
+		color.RGBA{53, 183, 121, 255}, color.RGBA{110, 206, 88, 255}, color.RGBA{181, 222, 43, 255},
+		color.RGBA{253, 231, 37, 255},
+	)},
+	{name: "magma", gradient: evenStops(
+		color.RGBA{0, 0, 4, 255}, color.RGBA{24, 15, 61, 255}, color.RGBA{68, 15, 118, 255},
+		color.RGBA{114, 31, 129, 255}, color.RGBA{158, 47, 127, 255}, color.RGBA{205, 64, 113, 255},
+		color.RGBA{241, 96, 93, 255}, color.RGBA{253, 150, 104, 255}, color.RGBA{254, 202, 141, 255},
+		color.RGBA{252, 253, 191, 255},
+	)},
+	// cividis also reads the same to those without red-green colour vision
+	{name: "cividis", gradient: evenStops(
+		color.RGBA{0, 34, 78, 255}, color.RGBA{18, 53, 112, 255}, color.RGBA{59, 73, 108, 255},
+		color.RGBA{87, 93, 109, 255}, color.RGBA{112, 113, 115, 255}, color.RGBA{138, 134, 120, 255},
+		color.RGBA{165, 156, 116, 255}, color.RGBA{195, 179, 105, 255}, color.RGBA{225, 204, 85, 255},
+		color.RGBA{254, 232, 56, 255},
+	)},
+}
+
+// returns a gradient of the colours spaced evenly from 0 to 1
+func evenStops(colours ...color.RGBA) Gradient {
+	g := make(Gradient, len(colours))
+	for i, c := range colours {
+		g[i] = Stop{Pos: float64(i) / float64(len(colours)-1), Colour: c}
+	}
+	return g
+}
+
+// the number of built in gradients at the start of gradients
+var builtinGradients = len(gradients)
+
+// Gradients returns the names of the built in and registered gradients.
+func Gradients() []string {
+	gradientsMu.RLock()
+	defer gradientsMu.RUnlock()
+	names := make([]string, len(gradients))
+	for i, g := range gradients {
+		names[i] = g.name
+	}
+	return names
+}
+
+// LookupGradient returns the named built in or registered gradient.
+func LookupGradient(name string) (Gradient, bool) {
+	gradientsMu.RLock()
+	defer gradientsMu.RUnlock()
+	for _, g := range gradients {
+		if g.name == name {
+			return g.gradient, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterGradient adds g to the gradients under name, after the built in ones, so that it can be picked by name like
+// them. Registering a name again replaces its gradient, but the built in gradients can't be replaced.
+func RegisterGradient(name string, g Gradient) error {
+	gradientsMu.Lock()
+	defer gradientsMu.Unlock()
+	for i, existing := range gradients {
+		if existing.name != name {
+			continue
+		}
+		if i < builtinGradients {
+			return fmt.Errorf("%q is the name of a built in gradient", name)
+		}
+		gradients[i].gradient = g
+		return nil
+	}
+	gradients = append(gradients, namedGradient{name: name, gradient: g})
+	return nil
+}
@@ -0,0 +1,23 @@
+package palette
+
+// Equalise builds a lookup table mapping escape iterations to positions in [0, 1] according to the cumulative
+// distribution of escapes, so that colours are spread evenly across the points in a frame whatever the zoom level or
+// iteration limit. histogram[n] is the number of points which escaped on iteration n.
+func Equalise(histogram []int) []float64 {
+	table := make([]float64, len(histogram))
+
+	var total int
+	for _, count := range histogram {
+		total += count
+	}
+	if total == 0 {
+		return table
+	}
+
+	var cumulative int
+	for n, count := range histogram {
+		cumulative += count
+		table[n] = float64(cumulative) / float64(total)
+	}
+	return table
+}
@@ -0,0 +1,58 @@
+package palette
+
+import (
+	"image/color"
+	"math"
+)
+
+// Levels adjusts the brightness, contrast and gamma of colours, to tune images to the display they're shown on. The
+// zero value leaves colours unchanged.
+type Levels struct {
+	// Brightness is added to each channel, from -1 for black to 1 for white.
+	Brightness float64
+	// Contrast scales the distance of each channel from mid grey. Zero means 1.
+	Contrast float64
+	// Gamma brightens the mid tones when above 1 and darkens them when below, leaving black and white alone. Zero
+	// means 1.
+	Gamma float64
+}
+
+// Identity reports whether the levels leave colours unchanged.
+func (l Levels) Identity() bool {
+	return l.Brightness == 0 && (l.Contrast == 0 || l.Contrast == 1) && (l.Gamma == 0 || l.Gamma == 1)
+}
+
+// adjusts a channel in [0, 1], applying the gamma, then the contrast, then the brightness
+func (l Levels) channel(v float64) float64 {
+	if l.Gamma != 0 {
+		v = math.Pow(v, 1/l.Gamma)
+	}
+	if l.Contrast != 0 {
+		v = (v-0.5)*l.Contrast + 0.5
+	}
+	return math.Max(0, math.Min(1, v+l.Brightness))
+}
+
+// Adjuster returns a function which applies the levels to colours, leaving their alpha and fully transparent colours
+// alone. The adjustment of opaque colours is looked up from a table built up front, as colouring
+// images adjusts every sample.
+func (l Levels) Adjuster() func(c color.RGBA) color.RGBA {
+	var table [256]uint8
+	for i := range table {
+		table[i] = uint8(math.Round(l.channel(float64(i)/255) * 255))
+	}
+	return func(c color.RGBA) color.RGBA {
+		switch c.A {
+		case 255:
+			return color.RGBA{R: table[c.R], G: table[c.G], B: table[c.B], A: 255}
+		case 0:
+			return c
+		}
+		// colours are alpha premultiplied, so adjust the colour the alpha scales
+		a := float64(c.A)
+		adjust := func(v uint8) uint8 {
+			return uint8(math.Round(l.channel(float64(v)/a) * a))
+		}
+		return color.RGBA{R: adjust(c.R), G: adjust(c.G), B: adjust(c.B), A: c.A}
+	}
+}
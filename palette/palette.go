@@ -0,0 +1,60 @@
+// Package palette maps escape-time results to colours.
+package palette
+
+import "image/color"
+
+// Contrast is the default shade step between consecutive escape iterations.
+const Contrast = 20
+
+// Interior is the colour of points which never escape. It's opaque so that exported images show the set as the window
+// does, rather than as a hole.
+var Interior = color.RGBA{0, 0, 0, 255}
+
+// Escape colours a point by the iteration it escaped on, stepping the shade by the default Contrast.
+func Escape(n int) color.RGBA {
+	return EscapeContrast(n, Contrast)
+}
+
+// EscapeContrast colours a point by the iteration it escaped on, stepping the shade by contrast each iteration. The
+// colour bands repeat every 256/contrast iterations, so the shade is computed at full width and explicitly wrapped
+// rather than relying on uint8 overflow, and any iteration limit produces the same banding.
+func EscapeContrast(n, contrast int) color.RGBA {
+	shade := n * contrast % 256
+	return color.RGBA{
+		R: uint8((60 + 256 - shade) % 256),
+		G: uint8((180 + 256 - shade) % 256),
+		B: uint8(shade),
+		A: 255,
+	}
+}
+
+// the colours the interior shades range between
+var (
+	interiorDark  = color.RGBA{0, 0, 0, 255}
+	interiorLight = color.RGBA{30, 60, 140, 255}
+)
+
+// InteriorShade colours a point inside the set by a shade t in [0, 1], from black at 0 to deep blue at 1.
+func InteriorShade(t float64) color.RGBA {
+	return Gradient{{Pos: 0, Colour: interiorDark}, {Pos: 1, Colour: interiorLight}}.At(t)
+}
+
+// the tints used for each period, cycled through for periods longer than the table
+var periodTints = []color.RGBA{
+	{40, 40, 90, 255},
+	{90, 30, 60, 255},
+	{30, 80, 60, 255},
+	{90, 70, 20, 255},
+	{50, 30, 90, 255},
+	{20, 70, 90, 255},
+	{80, 40, 20, 255},
+}
+
+// PeriodColour colours a point inside the set by the period of the cycle its orbit settles into. Points where no cycle
+// was found are black.
+func PeriodColour(period int) color.RGBA {
+	if period <= 0 {
+		return interiorDark
+	}
+	return periodTints[(period-1)%len(periodTints)]
+}
@@ -0,0 +1,122 @@
+package palette
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	if c := Escape(0); c.R != 60 || c.G != 180 || c.B != 0 || c.A != 255 {
+		t.Errorf("Escape(0) = %v, want {60 180 0 255}", c)
+	}
+
+	// 64 iterations shift the shade by a whole multiple of 256, so the bands repeat regardless of iteration count
+	for _, n := range []int{1, 13, 255, 256, 5000} {
+		if a, b := Escape(n), Escape(n+64); a != b {
+			t.Errorf("Escape(%d) = %v but Escape(%d) = %v, expected the bands to repeat", n, a, n+64, b)
+		}
+	}
+}
+
+func TestEscapeContrast(t *testing.T) {
+	if a, b := EscapeContrast(7, Contrast), Escape(7); a != b {
+		t.Errorf("EscapeContrast with the default contrast = %v, want %v", a, b)
+	}
+	// with a contrast of 1 every shade from 0 to 255 is used before the bands repeat
+	if c := EscapeContrast(100, 1); c.B != 100 {
+		t.Errorf("EscapeContrast(100, 1) has blue %d, want 100", c.B)
+	}
+	if a, b := EscapeContrast(3, 1), EscapeContrast(3+256, 1); a != b {
+		t.Errorf("EscapeContrast(3, 1) = %v but EscapeContrast(259, 1) = %v, expected the bands to repeat", a, b)
+	}
+}
+
+func TestGradientAt(t *testing.T) {
+	g := Gradient{
+		{Pos: 0, Colour: color.RGBA{0, 0, 0, 255}},
+		{Pos: 0.5, Colour: color.RGBA{200, 100, 0, 255}},
+		{Pos: 1, Colour: color.RGBA{200, 100, 100, 255}},
+	}
+	tests := []struct {
+		t    float64
+		want color.RGBA
+	}{
+		{t: -1, want: color.RGBA{0, 0, 0, 255}},
+		{t: 0, want: color.RGBA{0, 0, 0, 255}},
+		{t: 0.25, want: color.RGBA{100, 50, 0, 255}},
+		{t: 0.5, want: color.RGBA{200, 100, 0, 255}},
+		{t: 0.75, want: color.RGBA{200, 100, 50, 255}},
+		{t: 1, want: color.RGBA{200, 100, 100, 255}},
+		{t: 2, want: color.RGBA{200, 100, 100, 255}},
+	}
+	for _, tt := range tests {
+		if got := g.At(tt.t); got != tt.want {
+			t.Errorf("At(%g) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestGradients(t *testing.T) {
+	for _, name := range Gradients() {
+		g, ok := LookupGradient(name)
+		if !ok {
+			t.Fatalf("LookupGradient(%q) failed for a listed gradient", name)
+		}
+		if g[0].Pos != 0 || g[len(g)-1].Pos != 1 {
+			t.Errorf("gradient %q doesn't span 0 to 1", name)
+		}
+		for i := 1; i < len(g); i++ {
+			if g[i].Pos < g[i-1].Pos {
+				t.Errorf("gradient %q stops are out of order", name)
+			}
+		}
+	}
+}
+
+func TestEqualise(t *testing.T) {
+	table := Equalise([]int{2, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	want := []float64{0.5, 0.75, 0.75, 1, 1, 1, 1, 1, 1, 1}
+	if len(table) != len(want) {
+		t.Fatalf("expected table of length %d, got %d", len(want), len(table))
+	}
+	for n := range want {
+		if table[n] != want[n] {
+			t.Errorf("table[%d] = %g, want %g", n, table[n], want[n])
+		}
+	}
+}
+
+func TestEqualiseEmpty(t *testing.T) {
+	for n, pos := range Equalise(make([]int, 5)) {
+		if pos != 0 {
+			t.Errorf("table[%d] = %g for an empty histogram, want 0", n, pos)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		levels Levels
+		in     color.RGBA
+		want   color.RGBA
+	}{
+		{levels: Levels{}, in: color.RGBA{10, 128, 250, 255}, want: color.RGBA{10, 128, 250, 255}},
+		{levels: Levels{Brightness: 0.2}, in: color.RGBA{0, 100, 250, 255}, want: color.RGBA{51, 151, 255, 255}},
+		{levels: Levels{Contrast: 1.5}, in: color.RGBA{0, 64, 191, 255}, want: color.RGBA{0, 32, 223, 255}},
+		{levels: Levels{Gamma: 2}, in: color.RGBA{0, 64, 255, 255}, want: color.RGBA{0, 128, 255, 255}},
+		// opaque black is adjusted like any other colour, transparent colours are left alone and the colour of
+		// translucent ones is adjusted beneath their alpha
+		{levels: Levels{Brightness: 0.2}, in: color.RGBA{0, 0, 0, 255}, want: color.RGBA{51, 51, 51, 255}},
+		{levels: Levels{Brightness: 0.5}, in: color.RGBA{}, want: color.RGBA{}},
+		{levels: Levels{Gamma: 2}, in: color.RGBA{0, 32, 128, 128}, want: color.RGBA{0, 64, 128, 128}},
+	}
+	for _, tt := range tests {
+		if got := tt.levels.Adjuster()(tt.in); got != tt.want {
+			t.Errorf("%+v adjusted %v to %v, want %v", tt.levels, tt.in, got, tt.want)
+		}
+	}
+	if !(Levels{Contrast: 1, Gamma: 1}).Identity() || (Levels{Gamma: 2}).Identity() {
+		t.Error("Identity only expected to be true of levels which leave colours unchanged")
+	}
+}
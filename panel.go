@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/jemgunay/mandelbrot/palette"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	panelWidth      = 220
+	panelRowPadding = 6
+	panelButtonSize = 14
+	// the step the brightness, luminance contrast and gamma controls adjust by
+	levelsStep = 0.05
+)
+
+var (
+	panelButtonColour      = pixel.RGB(0.3, 0.3, 0.35)
+	panelButtonHoverColour = pixel.RGB(0.45, 0.45, 0.55)
+)
+
+// control is a setting adjustable from the panel with decrement and increment buttons
+type control struct {
+	label    string
+	value    func() string
+	dec, inc func()
+}
+
+// panel is a toggleable overlay in the top right corner of the window for adjusting settings while exploring
+type panel struct {
+	visible  bool
+	txt      *text.Text
+	shapes   *imdraw.IMDraw
+	controls []control
+}
+
+func newPanel() *panel {
+	return &panel{
+		txt:    text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII)),
+		shapes: imdraw.New(nil),
+		controls: []control{
+			{
+				label: "iterations",
+				value: func() string { return fmt.Sprint(iterations) },
+				dec: func() {
+					if step := iterationStep(iterations); iterations > step {
+						iterations -= step
+					}
+				},
+				inc: func() { iterations += iterationStep(iterations) },
+			},
+			{
+				label: "bailout",
+				value: func() string { return fmt.Sprintf("%g", bailout) },
+				dec:   func() { bailout = math.Max(bailout/2, 1) },
+				inc:   func() { bailout *= 2 },
+			},
+			{
+				label: "palette",
+				value: func() string { return paletteName },
+				dec:   func() { paletteName = prevName(palette.Gradients(), paletteName) },
+				inc:   func() { paletteName = nextName(palette.Gradients(), paletteName) },
+			},
+			{
+				label: "contrast",
+				value: func() string { return fmt.Sprint(contrast) },
+				dec: func() {
+					if contrast > 1 {
+						contrast--
+					}
+				},
+				inc: func() {
+					if contrast < 255 {
+						contrast++
+					}
+				},
+			},
+			{
+				label: "brightness",
+				value: func() string { return fmt.Sprintf("%+.2f", brightness) },
+				dec:   func() { brightness = math.Max(stepLevel(brightness, -1), -1) },
+				inc:   func() { brightness = math.Min(stepLevel(brightness, 1), 1) },
+			},
+			{
+				label: "lum contrast",
+				value: func() string { return fmt.Sprintf("%.2f", levelsContrast) },
+				dec:   func() { levelsContrast = math.Max(stepLevel(levelsContrast, -1), levelsStep) },
+				inc:   func() { levelsContrast = stepLevel(levelsContrast, 1) },
+			},
+			{
+				label: "gamma",
+				value: func() string { return fmt.Sprintf("%.2f", gamma) },
+				dec:   func() { gamma = math.Max(stepLevel(gamma, -1), levelsStep) },
+				inc:   func() { gamma = stepLevel(gamma, 1) },
+			},
+			{
+				label: "exponent",
+				value: func() string { return fmt.Sprintf("%.2f", exponent) },
+				dec:   func() { exponent = math.Max(exponent-0.25, 1) },
+				inc:   func() { exponent += 0.25 },
+			},
+		},
+	}
+}
+
+// returns v moved by steps of levelsStep, landing on a whole step so that stepping back and forth returns to the same
+// values
+func stepLevel(v, steps float64) float64 {
+	return (math.Round(v/levelsStep) + steps) * levelsStep
+}
+
+// returns the bounds of the panel and of each control's decrement and increment buttons
+func (p *panel) layout(windowBounds pixel.Rect) (pixel.Rect, [][2]pixel.Rect) {
+	rowHeight := p.txt.LineHeight + panelRowPadding
+	max := windowBounds.Max.Sub(pixel.V(hudMargin, hudMargin))
+	bounds := pixel.R(max.X-panelWidth, max.Y-rowHeight*float64(len(p.controls))-panelRowPadding, max.X, max.Y)
+
+	buttons := make([][2]pixel.Rect, len(p.controls))
+	for i := range p.controls {
+		// centre the buttons vertically in the row
+		top := max.Y - panelRowPadding - rowHeight*float64(i)
+		y := top - (rowHeight-panelRowPadding)/2 - panelButtonSize/2
+		inc := pixel.R(max.X-panelRowPadding-panelButtonSize, y, max.X-panelRowPadding, y+panelButtonSize)
+		dec := inc.Moved(pixel.V(-panelButtonSize-panelRowPadding, 0))
+		buttons[i] = [2]pixel.Rect{dec, inc}
+	}
+	return bounds, buttons
+}
+
+// handles clicks on the panel's buttons, reporting whether the click landed on the panel and shouldn't be handled by
+// anything underneath it
+func (p *panel) handleClick(win *pixelgl.Window) bool {
+	if !p.visible || !win.JustPressed(pixelgl.MouseButtonLeft) {
+		return false
+	}
+
+	mouse := win.MousePosition()
+	bounds, buttons := p.layout(win.Bounds())
+	if !bounds.Contains(mouse) {
+		return false
+	}
+	for i, c := range p.controls {
+		if buttons[i][0].Contains(mouse) {
+			c.dec()
+		} else if buttons[i][1].Contains(mouse) {
+			c.inc()
+		}
+	}
+	return true
+}
+
+// draws the panel if it is visible
+func (p *panel) draw(win *pixelgl.Window) {
+	if !p.visible {
+		return
+	}
+
+	mouse := win.MousePosition()
+	bounds, buttons := p.layout(win.Bounds())
+
+	p.shapes.Clear()
+	p.shapes.Color = hudBackgroundColour
+	p.shapes.Push(bounds.Min, bounds.Max)
+	p.shapes.Rectangle(0)
+	for _, pair := range buttons {
+		for _, b := range pair {
+			p.shapes.Color = panelButtonColour
+			if b.Contains(mouse) {
+				p.shapes.Color = panelButtonHoverColour
+			}
+			p.shapes.Push(b.Min, b.Max)
+			p.shapes.Rectangle(0)
+		}
+	}
+	p.shapes.Draw(win)
+
+	p.txt.Clear()
+	// offset from the centre of a row to the baseline, which centres capitals vertically
+	centreToBaseline := (p.txt.Atlas().Ascent() - p.txt.Atlas().Descent()) / 2
+	for i, c := range p.controls {
+		dec, inc := buttons[i][0], buttons[i][1]
+		baseline := dec.Center().Y - centreToBaseline
+
+		p.txt.Dot = pixel.V(bounds.Min.X+panelRowPadding, baseline)
+		fmt.Fprintf(p.txt, "%-12s %s", c.label, c.value())
+		for sign, b := range map[string]pixel.Rect{"-": dec, "+": inc} {
+			p.txt.Dot = pixel.V(b.Center().X-p.txt.BoundsOf(sign).W()/2, baseline)
+			fmt.Fprint(p.txt, sign)
+		}
+	}
+	p.txt.DrawColorMask(win, pixel.IM, hudTextColour)
+}
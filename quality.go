@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	// the names of the quality presets used while exploring and for exports
+	qualityName       string
+	exportQualityName string
+
+	// the resolution rendered at while the view is moved by hand, and how long after the last movement the full
+	// resolution is restored
+	interactionResolution float64
+	interactionSettle     time.Duration
+
+	// the quality preset a still view is refined to once it has stayed put for refineDelay, or empty to keep it at the
+	// exploring quality
+	refineQualityName string
+	refineDelay       time.Duration
+)
+
+// qualityPreset bundles the settings which trade render time for fidelity, so that navigation can stay fluid while
+// stills get the full treatment
+type qualityPreset struct {
+	name string
+	// the fraction of the window's pixels rendered along each axis, the frame being stretched to fit
+	resolution float64
+	// the multiple of the iteration limit iterated
+	iterations float64
+	// the anti-aliasing samples per axis, and whether only edges take them, or 0 to keep the -samples and -edge-aa
+	// settings
+	samples     int
+	sampleEdges bool
+}
+
+// the quality presets, from the fastest to the finest
+var qualityPresets = []qualityPreset{
+	// a quarter of the pixels at half the iterations, for fluid navigation on slow machines or deep views
+	{name: "draft", resolution: 0.5, iterations: 0.5, samples: 1},
+	{name: "normal", resolution: 1, iterations: 1},
+	{name: "high", resolution: 1, iterations: 2, samples: 2, sampleEdges: true},
+	{name: "export", resolution: 1, iterations: 2, samples: 3},
+}
+
+// returns the quality preset with the given name, if there is one
+func lookupQuality(name string) (qualityPreset, bool) {
+	for _, q := range qualityPresets {
+		if q.name == name {
+			return q, true
+		}
+	}
+	return qualityPreset{}, false
+}
+
+// returns the names of the quality presets, from the fastest to the finest
+func qualityNames() []string {
+	names := make([]string, len(qualityPresets))
+	for i, q := range qualityPresets {
+		names[i] = q.name
+	}
+	return names
+}
+
+// returns the preset to render with while the view is being moved by hand, lowering the resolution so that frames
+// keep up with the movement on slow machines
+func (q qualityPreset) interactive() qualityPreset {
+	if interactionResolution < q.resolution {
+		q.resolution = interactionResolution
+	}
+	return q
+}
+
+// returns the preset to render a view with once it has been still for refineDelay, which is the finer of q and the
+// -refine-quality preset
+func (q qualityPreset) refined() qualityPreset {
+	refine, ok := lookupQuality(refineQualityName)
+	if !ok || qualityIndex(refine.name) <= qualityIndex(q.name) {
+		return q
+	}
+	return refine
+}
+
+// returns the position of the named preset in qualityPresets, or -1 if there isn't one
+func qualityIndex(name string) int {
+	for i, q := range qualityPresets {
+		if q.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// returns the params of a render of the same view as p at the preset's quality
+func (q qualityPreset) apply(p render.Params) render.Params {
+	if q.resolution < 1 && p.Width > 0 && p.Height > 0 {
+		// round the size up so that the frame still covers the view
+		p.Scale /= q.resolution
+		p.Width = int(math.Ceil(float64(p.Width) * q.resolution))
+		p.Height = int(math.Ceil(float64(p.Height) * q.resolution))
+	}
+	if p.Iterations = int(math.Round(float64(p.Iterations) * q.iterations)); p.Iterations < 1 {
+		p.Iterations = 1
+	}
+	if q.samples > 0 {
+		p.Samples, p.SampleEdges = q.samples, q.sampleEdges
+	}
+	return p
+}
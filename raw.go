@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	rawPath   string
+	rawSmooth bool
+)
+
+// renders the view the window would start at to rawPath as its raw escape iterations rather than colours, as a 16-bit
+// greyscale PNG, CSV or NumPy array depending on its extension, at the export size
+func writeRaw() error {
+	ext := strings.ToLower(filepath.Ext(rawPath))
+	if ext != ".png" && ext != ".csv" && ext != ".npy" {
+		return fmt.Errorf("unknown raw format %q, expected .png, .csv or .npy", ext)
+	}
+
+	windowBounds := pixel.R(0, 0, windowSize, windowSize)
+	bounds := startingView(windowBounds, windowBounds)
+	p := exportParams(bounds, windowBounds.Size())
+	// a sample per pixel, so that values on either side of the set's boundary aren't blended into ones on neither
+	p.Samples, p.SampleEdges = 1, false
+	if rawSmooth {
+		p.Colouring = render.ColouringSmooth
+	}
+
+	fmt.Printf("Rendering %dx%d escape iterations to %s\n", p.Width, p.Height, rawPath)
+	samples, err := iterateImage(context.Background(), p)
+	if err != nil {
+		return err
+	}
+	field := samples.EscapeField()
+
+	f, err := os.Create(rawPath)
+	if err != nil {
+		return err
+	}
+	switch ext {
+	case ".csv":
+		err = field.WriteCSV(f)
+	case ".npy":
+		err = field.WriteNPY(f)
+	default:
+		err = png.Encode(f, field.Gray16())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(rawPath)
+		return err
+	}
+	fmt.Printf("Wrote %s with an iteration limit of %d\n", rawPath, p.Iterations)
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	recordPath   string
+	recordFrames uint
+	recordTarget pixel.Vec
+	recordZoom   float64
+	recordFPS    uint
+	// the multibrot exponents a recording sweeps between, e.g. 2:5, instead of zooming
+	recordExponent string
+)
+
+// frameWriter consumes the frames of a recorded zoom sequence in order
+type frameWriter interface {
+	writeFrame(img *image.RGBA) error
+	close() error
+}
+
+// renders a zoom sequence from the initial view to recordTarget at recordZoom magnification, or a sweep of the
+// multibrot's exponent across recordExponent at the initial view, and writes it to recordPath
+func record() error {
+	if recordFrames < 2 {
+		return fmt.Errorf("at least 2 frames are required, got %d", recordFrames)
+	}
+	if recordZoom <= 0 {
+		return fmt.Errorf("zoom must be positive, got %g", recordZoom)
+	}
+	if recordFPS == 0 {
+		return fmt.Errorf("frame rate must be positive")
+	}
+	var fromExponent, toExponent float64
+	if recordExponent != "" {
+		var err error
+		if fromExponent, toExponent, err = parseExponentRange(recordExponent); err != nil {
+			return err
+		}
+	}
+
+	w, err := newFrameWriter(recordPath)
+	if err != nil {
+		return err
+	}
+
+	start := mandelbrotBounds.Moved(initialOffset)
+	size := pixel.V(windowSize, windowSize)
+	if recordExponent != "" {
+		fmt.Printf("Recording %d frames sweeping the multibrot exponent from %g to %g into %s\n", recordFrames,
+			fromExponent, toExponent, recordPath)
+	} else {
+		fmt.Printf("Recording %d frames zooming to %gx at (%g, %g) into %s\n", recordFrames, recordZoom, recordTarget.X, recordTarget.Y, recordPath)
+	}
+
+	workStats := render.ReadWorkStats()
+	for i := uint(0); i < recordFrames; i++ {
+		t := float64(i) / float64(recordFrames-1)
+		var p render.Params
+		if recordExponent != "" {
+			// the exponent only shapes the multibrot, so sweeps render it whatever the fractal
+			p = newParams(start, size)
+			p.Fractal, p.Formula = "multibrot", ""
+			p.Exponent = fromExponent + (toExponent-fromExponent)*t
+		} else {
+			p = newParams(zoomBounds(start, recordTarget, recordZoom, t), size)
+		}
+		frameStart := time.Now()
+		img, err := renderImage(context.Background(), p)
+		if err != nil {
+			w.close()
+			return err
+		}
+		stats := render.ReadWorkStats()
+		logf(logDebug, "recorded frame", "frame", i, "iterations", p.Iterations, "precision", p.SelectedPrecision(),
+			"render_time", time.Since(frameStart), "utilisation", stats.Sub(workStats).Utilisation())
+		workStats = stats
+		if err := w.writeFrame(img); err != nil {
+			w.close()
+			return fmt.Errorf("failed to write frame %d: %s", i, err)
+		}
+		fmt.Printf("\rRendered frame %d/%d", i+1, recordFrames)
+	}
+	fmt.Println()
+
+	return w.close()
+}
+
+// parses a range of multibrot exponents written as from:to, e.g. 2:5
+func parseExponentRange(s string) (float64, float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		from, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		to, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 == nil && err2 == nil {
+			if from < 1 || to < 1 {
+				return 0, 0, fmt.Errorf("exponents must be at least 1, got %s", s)
+			}
+			return from, to, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid exponent range %q, expected from:to, e.g. 2:5", s)
+}
+
+// interpolates between start (t=0) and a view of the target magnified by zoom (t=1). The view size shrinks exponentially
+// so that the zoom speed appears constant, and the centre converges on the target at the same rate.
+func zoomBounds(start pixel.Rect, target pixel.Vec, zoom, t float64) pixel.Rect {
+	scale := math.Pow(zoom, -t)
+
+	progress := 1.0
+	if zoom != 1 {
+		progress = (1 - scale) / (1 - 1/zoom)
+	}
+	centre := pixel.Lerp(start.Center(), target, progress)
+
+	return start.Resized(start.Center(), start.Size().Scaled(scale)).Moved(centre.Sub(start.Center()))
+}
+
+// picks a frame writer based on the output path: an animated GIF for .gif, numbered PNGs for .png and an ffmpeg encoded
+// video for anything else
+func newFrameWriter(path string) (frameWriter, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif":
+		// GIF frame delays are measured in hundredths of a second, so can't play any faster
+		if recordFPS > 100 {
+			return nil, fmt.Errorf("GIFs can't play faster than 100 fps, got %d", recordFPS)
+		}
+		return &gifWriter{path: path}, nil
+	case ".png":
+		// insert a frame number if the path doesn't specify where it goes
+		if !strings.Contains(path, "%") {
+			ext := filepath.Ext(path)
+			path = strings.TrimSuffix(path, ext) + "_%04d" + ext
+		}
+		return &pngWriter{pattern: path}, nil
+	default:
+		return newFFmpegWriter(path)
+	}
+}
+
+// writes each frame to its own PNG file, named by formatting the frame number into pattern
+type pngWriter struct {
+	pattern string
+	count   int
+}
+
+func (w *pngWriter) writeFrame(img *image.RGBA) error {
+	f, err := os.Create(fmt.Sprintf(w.pattern, w.count))
+	if err != nil {
+		return err
+	}
+	w.count++
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (w *pngWriter) close() error {
+	return nil
+}
+
+// accumulates paletted frames in memory and writes them as a looping animated GIF on close
+type gifWriter struct {
+	path string
+	anim gif.GIF
+}
+
+func (w *gifWriter) writeFrame(img *image.RGBA) error {
+	paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+
+	w.anim.Image = append(w.anim.Image, paletted)
+	// GIF frame delays are measured in whole hundredths of a second, so round to the nearest
+	delay := int(math.Round(100 / float64(recordFPS)))
+	if delay < 1 {
+		delay = 1
+	}
+	w.anim.Delay = append(w.anim.Delay, delay)
+	return nil
+}
+
+func (w *gifWriter) close() error {
+	f, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &w.anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// streams PNG encoded frames into an ffmpeg process which encodes them into a video
+type ffmpegWriter struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+func newFFmpegWriter(path string) (*ffmpegWriter, error) {
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error",
+		"-f", "image2pipe", "-framerate", fmt.Sprint(recordFPS), "-i", "-",
+		"-pix_fmt", "yuv420p", path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %s", err)
+	}
+	return &ffmpegWriter{cmd: cmd, stdin: stdin}, nil
+}
+
+func (w *ffmpegWriter) writeFrame(img *image.RGBA) error {
+	return png.Encode(w.stdin, img)
+}
+
+func (w *ffmpegWriter) close() error {
+	w.stdin.Close()
+	return w.cmd.Wait()
+}
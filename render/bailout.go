@@ -0,0 +1,77 @@
+package render
+
+import "math"
+
+// DefaultBailout is the escape radius used when Params.Bailout is zero.
+const DefaultBailout = 16
+
+// the supported bailout norms, measuring how far an orbit is from the origin when testing whether it has escaped
+const (
+	// NormEuclidean is the usual distance from the origin, |z|.
+	NormEuclidean = "euclidean"
+	// NormManhattan is the sum of the absolute real and imaginary components, which squares off the escape bands.
+	NormManhattan = "manhattan"
+	// NormImaginary only considers the imaginary component, stretching the escape bands into horizontal stripes.
+	NormImaginary = "imaginary"
+)
+
+var norms = []string{NormEuclidean, NormManhattan, NormImaginary}
+
+// Norms returns the names of the supported bailout norms.
+func Norms() []string {
+	return append([]string(nil), norms...)
+}
+
+// IsNorm reports whether name is a supported bailout norm.
+func IsNorm(name string) bool {
+	for _, n := range norms {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normKind identifies a bailout norm without string comparisons in the iteration loop
+type normKind int
+
+const (
+	euclidean normKind = iota
+	manhattan
+	imaginaryOnly
+)
+
+// bailout describes the escape test of a render
+type bailout struct {
+	kind normKind
+	// the escape radius, squared for the euclidean norm so that no square root is needed
+	limit float64
+}
+
+func newBailout(p Params) bailout {
+	r := p.Bailout
+	if r == 0 {
+		r = DefaultBailout
+	}
+
+	switch p.Norm {
+	case NormManhattan:
+		return bailout{kind: manhattan, limit: r}
+	case NormImaginary:
+		return bailout{kind: imaginaryOnly, limit: r}
+	default:
+		return bailout{kind: euclidean, limit: r * r}
+	}
+}
+
+// reports whether z has escaped
+func (b bailout) escaped(z complex128) bool {
+	switch b.kind {
+	case manhattan:
+		return math.Abs(real(z))+math.Abs(imag(z)) > b.limit
+	case imaginaryOnly:
+		return math.Abs(imag(z)) > b.limit
+	default:
+		return real(z)*real(z)+imag(z)*imag(z) > b.limit
+	}
+}
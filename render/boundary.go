@@ -0,0 +1,134 @@
+package render
+
+import "image"
+
+// the smallest rectangle width or height worth tracing the border of, below which a rectangle is simply iterated
+const minTraceSize = 4
+
+// reports whether the image described by p can skip the interiors of rectangles with a uniform border. Every level set
+// of the mandelbrot's escape iteration is connected, so the samples inside a rectangle whose border samples are all
+// identical are identical too, short of detail finer than a pixel. The sets of other fractals aren't always connected.
+func canTrace(p Params) bool {
+	// a rectangle as large as the whole set could have it inside a border which is entirely outside
+	return p.Trace && p.samplesPerAxis() == 1 && hasFastPath(p) && p.Scale*workTileSize < 1
+}
+
+// tracer iterates a tile of an image by Mariani-Silver subdivision: it iterates the border of a rectangle, fills the
+// rectangle with the border's sample if every border sample is identical, and otherwise splits it into quarters which
+// share their inner edges, and traces each of those in turn. Large areas of the set's interior or of a single escape
+// band are filled after iterating only their borders.
+type tracer struct {
+	p         Params
+	samples   []Sample
+	escapeRow func(points []complex128, samples []Sample)
+
+	tile image.Rectangle
+	// which pixels of the tile have samples, indexed from the top left of the tile
+	known []bool
+	// the pixels being iterated, those of them without samples yet, and their offsets from the centre and samples
+	pixels  []image.Point
+	pending []image.Point
+	points  []complex128
+	results []Sample
+}
+
+func newTracer(p Params, samples []Sample, escapeRow func(points []complex128, samples []Sample)) *tracer {
+	return &tracer{p: p, samples: samples, escapeRow: escapeRow, known: make([]bool, workTileSize*workTileSize)}
+}
+
+// iterates or fills every pixel of the tile
+func (t *tracer) trace(tile image.Rectangle) {
+	t.tile = tile
+	for i := range t.known {
+		t.known[i] = false
+	}
+	t.traceRect(tile)
+}
+
+func (t *tracer) traceRect(r image.Rectangle) {
+	if r.Dx() <= minTraceSize || r.Dy() <= minTraceSize {
+		t.iterate(r)
+		return
+	}
+
+	// iterate the border, top and bottom rows then the left and right columns between them
+	t.pixels = t.pixels[:0]
+	for x := r.Min.X; x < r.Max.X; x++ {
+		t.pixels = append(t.pixels, image.Pt(x, r.Min.Y), image.Pt(x, r.Max.Y-1))
+	}
+	for y := r.Min.Y + 1; y < r.Max.Y-1; y++ {
+		t.pixels = append(t.pixels, image.Pt(r.Min.X, y), image.Pt(r.Max.X-1, y))
+	}
+	t.iteratePixels()
+
+	first := t.samples[t.index(r.Min)]
+	uniform := true
+	for _, px := range t.pixels {
+		if t.samples[t.index(px)] != first {
+			uniform = false
+			break
+		}
+	}
+	if uniform {
+		inner := r.Inset(1)
+		for y := inner.Min.Y; y < inner.Max.Y; y++ {
+			for x := inner.Min.X; x < inner.Max.X; x++ {
+				t.samples[t.index(image.Pt(x, y))] = first
+				t.known[t.tileIndex(image.Pt(x, y))] = true
+			}
+		}
+		return
+	}
+
+	// the quarters overlap along the middle row and column, so each has the others' shared edges already iterated
+	mid := image.Pt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+	t.traceRect(image.Rect(r.Min.X, r.Min.Y, mid.X+1, mid.Y+1))
+	t.traceRect(image.Rect(mid.X, r.Min.Y, r.Max.X, mid.Y+1))
+	t.traceRect(image.Rect(r.Min.X, mid.Y, mid.X+1, r.Max.Y))
+	t.traceRect(image.Rect(mid.X, mid.Y, r.Max.X, r.Max.Y))
+}
+
+// iterates every pixel of r which doesn't have a sample yet
+func (t *tracer) iterate(r image.Rectangle) {
+	t.pixels = t.pixels[:0]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			t.pixels = append(t.pixels, image.Pt(x, y))
+		}
+	}
+	t.iteratePixels()
+}
+
+// iterates the pixels in t.pixels which don't have samples yet in a single batch, so that several are iterated at
+// once where SIMD applies
+func (t *tracer) iteratePixels() {
+	t.pending, t.points = t.pending[:0], t.points[:0]
+	for _, px := range t.pixels {
+		if !t.known[t.tileIndex(px)] {
+			t.pending = append(t.pending, px)
+			t.points = append(t.points, t.p.pixelOffset(float64(px.X)+0.5, float64(px.Y)+0.5))
+		}
+	}
+	if len(t.points) == 0 {
+		return
+	}
+	if cap(t.results) < len(t.points) {
+		t.results = make([]Sample, len(t.points))
+	}
+	results := t.results[:len(t.points)]
+	t.escapeRow(t.points, results)
+	for i, px := range t.pending {
+		t.samples[t.index(px)] = results[i]
+		t.known[t.tileIndex(px)] = true
+	}
+}
+
+// returns the index of the sample of the pixel at px in the image
+func (t *tracer) index(px image.Point) int {
+	return px.Y*t.p.Width + px.X
+}
+
+// returns the index of the pixel at px within the tile
+func (t *tracer) tileIndex(px image.Point) int {
+	return (px.Y-t.tile.Min.Y)*workTileSize + px.X - t.tile.Min.X
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	// a view of the boundary with both the interior of the set and wide escape bands
+	p := testParams()
+	p.Width, p.Height = 160, 120
+	p.Scale = 2.5 / 160
+	p.Iterations = 200
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.Trace = true
+	if !canTrace(p) {
+		t.Fatal("expected the view to be traceable")
+	}
+	got, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// only detail finer than a pixel can be lost
+	differ := 0
+	for i := range want {
+		if got[i] != want[i] {
+			differ++
+		}
+	}
+	if differ > len(want)/100 {
+		t.Errorf("expected at most 1%% of samples to differ when tracing, got %d of %d", differ, len(want))
+	}
+
+	// only the border of a tile inside the set is iterated
+	p.Centre, p.Scale = complex(-0.2, 0), 0.001
+	iterated := 0
+	samples := make([]Sample, p.Width*p.Height)
+	newTracer(p, samples, func(points []complex128, samples []Sample) {
+		iterated += len(points)
+		for i, d := range points {
+			samples[i] = escapeMandelbrot(p.Centre+d, p, 0)
+		}
+	}).trace(image.Rect(0, 0, workTileSize, workTileSize))
+	if border := 4 * (workTileSize - 1); iterated != border {
+		t.Errorf("expected only the %d border pixels of an interior tile to be iterated, got %d", border, iterated)
+	}
+	for y := 0; y < workTileSize; y++ {
+		for x := 0; x < workTileSize; x++ {
+			if s := samples[y*p.Width+x]; s.N != p.Iterations {
+				t.Fatalf("expected (%d, %d) to be filled with the interior, got %+v", x, y, s)
+			}
+		}
+	}
+
+	// other fractals' sets aren't connected, and anti-aliased pixels have several samples
+	p.Fractal = "burning-ship"
+	if canTrace(p) {
+		t.Error("expected the burning ship not to be traceable")
+	}
+	p.Fractal, p.Samples = "mandelbrot", 2
+	if canTrace(p) {
+		t.Error("expected anti-aliased images not to be traceable")
+	}
+}
+
+func BenchmarkTrace(b *testing.B) {
+	for _, trace := range []bool{false, true} {
+		name := "iterate"
+		if trace {
+			name = "trace"
+		}
+		b.Run(name, func(b *testing.B) {
+			// the period 3 mini mandelbrot, whose interior isn't skipped by the cardioid and bulb checks
+			p := testParams()
+			p.Centre = complex(-1.7549, 0)
+			p.Width, p.Height = 320, 240
+			p.Scale = 0.04 / 320
+			p.Iterations = 1000
+			p.Trace = trace
+			for i := 0; i < b.N; i++ {
+				if _, err := iterateSamples(context.Background(), p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// the built in colouring algorithms
+const (
+	// ColouringBands cycles through shades with each escape iteration.
+	ColouringBands = "bands"
+	// ColouringSmooth runs through the palette with the fractional escape iteration, blending the bands together.
+	ColouringSmooth = "smooth"
+	// ColouringHistogram spreads the palette across the frame according to the distribution of escape iterations, so
+	// colours stay balanced at any zoom level or iteration limit.
+	ColouringHistogram = "histogram"
+	// ColouringOrbitTrap colours by the closest approach of each orbit to a trap shape, one of Traps.
+	ColouringOrbitTrap = "orbit-trap"
+	// ColouringDistance shades by the estimated distance from each point to the set, outlining its filaments.
+	ColouringDistance = "distance"
+	// ColouringRoots gives each root of the newton fractal's polynomial its own colour from the palette, darkening the
+	// longer each point takes to converge on it. Other fractals have no roots, so are shaded by escape speed alone.
+	ColouringRoots = "roots"
+)
+
+// the distance in pixels over which the interior distance glow fades to black
+const distanceGlow = 24
+
+// Colourer is a colouring algorithm. Colourings are looked up by name from those registered with RegisterColouring,
+// so new ones can be added without changing the render loop.
+type Colourer interface {
+	// Needs reports the escape data the colouring uses beyond each sample's escape iteration. The data is only
+	// gathered for colourings which need it.
+	Needs() Needs
+	// Colour creates a func colouring the samples of the frame described by p. histogram counts the samples of the
+	// whole frame escaping on each iteration if the colouring needs it, and is nil otherwise. Samples which didn't
+	// escape are only passed to the func while the interior colouring is InteriorFlat, otherwise the interior
+	// colouring is used for them.
+	Colour(p Params, histogram []int) func(s Sample) color.RGBA
+}
+
+// Needs is a set of the escape data a Colourer uses.
+type Needs uint
+
+const (
+	// NeedsHistogram counts the escape iterations across the whole frame before colouring it.
+	NeedsHistogram Needs = 1 << iota
+	// NeedsSmooth computes Sample.Smooth while iterating.
+	NeedsSmooth
+	// NeedsTrap computes Sample.Trap while iterating.
+	NeedsTrap
+	// NeedsDistance computes Sample.Distance while iterating.
+	NeedsDistance
+)
+
+// the needs which are gathered while iterating, rather than computed from the iterated samples
+const iterationNeeds = NeedsSmooth | NeedsTrap | NeedsDistance
+
+// the registered colourings in cycling order, and the colourer of each
+var (
+	colourings = []string{ColouringBands, ColouringSmooth, ColouringHistogram, ColouringOrbitTrap, ColouringDistance,
+		ColouringRoots}
+	colourers = map[string]Colourer{
+		ColouringBands:     bandsColourer{},
+		ColouringSmooth:    smoothColourer{},
+		ColouringHistogram: histogramColourer{},
+		ColouringOrbitTrap: trapColourer{},
+		ColouringDistance:  distanceColourer{},
+		ColouringRoots:     rootsColourer{},
+	}
+)
+
+// RegisterColouring adds a colouring algorithm under name, after the built in colourings in cycling order. It isn't
+// safe to call concurrently with rendering, so it should be called from an init function. It panics if name is empty
+// or already registered.
+func RegisterColouring(name string, c Colourer) {
+	if name == "" {
+		panic("render: colouring name must not be empty")
+	}
+	if _, ok := colourers[name]; ok {
+		panic(fmt.Sprintf("render: colouring %q is already registered", name))
+	}
+	colourings = append(colourings, name)
+	colourers[name] = c
+}
+
+// Colourings returns the names of the registered colouring algorithms.
+func Colourings() []string {
+	return append([]string(nil), colourings...)
+}
+
+// IsColouring reports whether name is a registered colouring algorithm.
+func IsColouring(name string) bool {
+	_, ok := colourers[name]
+	return ok
+}
+
+// returns the named colourer, or ColouringBands if name is empty
+func lookupColourer(name string) Colourer {
+	if name == "" {
+		name = ColouringBands
+	}
+	return colourers[name]
+}
+
+// returns the escape data used by the colouring of p
+func (p Params) needs() Needs {
+	c := lookupColourer(p.Colouring)
+	if c == nil {
+		return 0
+	}
+	return c.Needs()
+}
+
+// creates a histogram of escape iterations for colourings which depend on the whole frame, or nil for those which don't
+func newHistogram(p Params) []int {
+	if p.needs()&NeedsHistogram == 0 {
+		return nil
+	}
+	return make([]int, p.Iterations)
+}
+
+// counts the escape iterations of the samples into histogram, if it isn't nil
+func addToHistogram(histogram []int, p Params, samples []Sample) {
+	if histogram == nil {
+		return
+	}
+	for _, s := range samples {
+		if s.Escaped(p) {
+			histogram[s.N]++
+		}
+	}
+}
+
+// creates a func mapping samples to colours adjusted by the levels of p, given the histogram of the whole frame from
+// newHistogram
+func newColourer(p Params, histogram []int) func(s Sample) color.RGBA {
+	colour := lookupColourer(p.Colouring).Colour(p, histogram)
+	if p.Interior != "" && p.Interior != InteriorFlat {
+		exterior, interior := colour, newInteriorColourer(p)
+		colour = func(s Sample) color.RGBA {
+			if !s.Escaped(p) {
+				return interior(s.Shade)
+			}
+			return exterior(s)
+		}
+	}
+	if p.Levels.Identity() {
+		return colour
+	}
+
+	// the flat interior stays as it is, but colourings such as orbit traps colour points inside the set themselves
+	adjust := p.Levels.Adjuster()
+	flat := p.Interior == "" || p.Interior == InteriorFlat
+	return func(s Sample) color.RGBA {
+		c := colour(s)
+		if flat && !s.Escaped(p) && c == palette.Interior {
+			return c
+		}
+		return adjust(c)
+	}
+}
+
+// bandsColourer implements ColouringBands
+type bandsColourer struct{}
+
+func (bandsColourer) Needs() Needs {
+	return 0
+}
+
+func (bandsColourer) Colour(p Params, _ []int) func(s Sample) color.RGBA {
+	contrast := p.contrast()
+	return func(s Sample) color.RGBA {
+		if !s.Escaped(p) {
+			return palette.Interior
+		}
+		return palette.EscapeContrast(s.N, contrast)
+	}
+}
+
+// histogramColourer implements ColouringHistogram
+type histogramColourer struct{}
+
+func (histogramColourer) Needs() Needs {
+	return NeedsHistogram
+}
+
+func (histogramColourer) Colour(p Params, histogram []int) func(s Sample) color.RGBA {
+	gradient := lookupGradient(p.Palette)
+	table := palette.Equalise(histogram)
+	return func(s Sample) color.RGBA {
+		if !s.Escaped(p) {
+			return palette.Interior
+		}
+		return gradient.At(table[s.N])
+	}
+}
+
+// returns the shade step between escape iterations, defaulting to palette.Contrast
+func (p Params) contrast() int {
+	if p.Contrast == 0 {
+		return palette.Contrast
+	}
+	return p.Contrast
+}
+
+// creates a func mapping the interior shades of points which never escape to colours
+func newInteriorColourer(p Params) func(shade float64) color.RGBA {
+	switch p.Interior {
+	case InteriorOrbit:
+		return palette.InteriorShade
+
+	case InteriorPeriod:
+		return func(shade float64) color.RGBA {
+			return palette.PeriodColour(int(shade))
+		}
+
+	case InteriorDistance:
+		return func(shade float64) color.RGBA {
+			// shade is the distance to the boundary in pixels, glowing brightest next to the boundary
+			if shade <= 0 {
+				return palette.InteriorShade(0)
+			}
+			return palette.InteriorShade(math.Exp(-shade / distanceGlow))
+		}
+
+	default:
+		return func(float64) color.RGBA {
+			return palette.Interior
+		}
+	}
+}
+
+// returns the named gradient, or the first gradient if name is empty
+func lookupGradient(name string) palette.Gradient {
+	if name == "" {
+		name = palette.Gradients()[0]
+	}
+	g, _ := palette.LookupGradient(name)
+	return g
+}
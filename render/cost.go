@@ -0,0 +1,114 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// the debug overlays TileCache.Overlay can draw
+const (
+	// how long each tile took to iterate
+	OverlayTileTime = "tile-time"
+	// how many iterations each pixel took
+	OverlayIterations = "iterations"
+)
+
+var overlays = []string{OverlayTileTime, OverlayIterations}
+
+// Overlays returns the names of the debug overlays in cycling order.
+func Overlays() []string {
+	return append([]string(nil), overlays...)
+}
+
+// IsOverlay reports whether name is a debug overlay.
+func IsOverlay(name string) bool {
+	for _, o := range overlays {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// the opacity of overlays drawn over a frame, so that the frame still shows through
+const overlayOpacity = 0.6
+
+// the colours of overlays, from the cheapest to the costliest
+var costGradient = palette.Gradient{
+	{Pos: 0, Colour: color.RGBA{R: 0, G: 0, B: 160, A: 255}},
+	{Pos: 0.35, Colour: color.RGBA{R: 0, G: 200, B: 200, A: 255}},
+	{Pos: 0.7, Colour: color.RGBA{R: 240, G: 220, B: 0, A: 255}},
+	{Pos: 1, Colour: color.RGBA{R: 230, G: 0, B: 0, A: 255}},
+}
+
+// Overlay returns a translucent heat map of the cost of the frame described by p, for drawing over it after tc has
+// rendered it. OverlayTileTime tints each tile by how long it took to iterate relative to the slowest tile of the
+// frame. Tiles reused from earlier frames show how long they took when they were computed, and tiles mirrored from
+// their reflection cost nothing. OverlayIterations tints each pixel by its escape iteration on a log scale, which is
+// how much work it would be without boundary tracing. Pixels of tiles which aren't cached are transparent.
+func (tc *TileCache) Overlay(p Params, kind string) *image.RGBA {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+	p.Precision = p.SelectedPrecision()
+	if p.Validate() != nil || cacheKey(p) != tc.key {
+		return img
+	}
+
+	frame, tileBounds := frameTiles(p)
+	var slowest time.Duration
+	for pos, cost := range tc.costs {
+		if pos.In(tileBounds) && cost > slowest {
+			slowest = cost
+		}
+	}
+	count := p.samplesPerAxis() * p.samplesPerAxis()
+	top := math.Log1p(float64(p.Iterations))
+
+	for ty := tileBounds.Min.Y; ty < tileBounds.Max.Y; ty++ {
+		for tx := tileBounds.Min.X; tx < tileBounds.Max.X; tx++ {
+			pos := image.Pt(tx, ty)
+			tile, ok := tc.tiles[pos]
+			if !ok {
+				continue
+			}
+			var tileCost float64
+			if slowest > 0 {
+				tileCost = float64(tc.costs[pos]) / float64(slowest)
+			}
+
+			tileRect := image.Rect(tx*tileSize, ty*tileSize, (tx+1)*tileSize, (ty+1)*tileSize)
+			overlap := tileRect.Intersect(frame)
+			for y := overlap.Min.Y; y < overlap.Max.Y; y++ {
+				for x := overlap.Min.X; x < overlap.Max.X; x++ {
+					cost := tileCost
+					if kind == OverlayIterations {
+						i := ((y-tileRect.Min.Y)*tileSize + x - tileRect.Min.X) * count
+						var n float64
+						for _, s := range tile[i : i+count] {
+							n += float64(s.N)
+						}
+						cost = math.Log1p(n/float64(count)) / top
+					}
+					img.SetRGBA(x-frame.Min.X, y-frame.Min.Y, translucent(costGradient.At(cost), overlayOpacity))
+				}
+			}
+		}
+	}
+	return img
+}
+
+// returns c at the given opacity, premultiplied
+func translucent(c color.RGBA, opacity float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R) * opacity),
+		G: uint8(float64(c.G) * opacity),
+		B: uint8(float64(c.B) * opacity),
+		A: uint8(255 * opacity),
+	}
+}
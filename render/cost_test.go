@@ -0,0 +1,49 @@
+package render
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTileCacheOverlay(t *testing.T) {
+	tc := NewTileCache()
+	p := gridParams()
+	// nothing has been rendered yet, so there's nothing to overlay
+	if img := tc.Overlay(p, OverlayTileTime); img.RGBAAt(0, 0).A != 0 {
+		t.Errorf("expected an empty cache to overlay nothing, got %v", img.RGBAAt(0, 0))
+	}
+	if _, err := tc.Render(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, kind := range Overlays() {
+		img := tc.Overlay(p, kind)
+		if img.Bounds().Dx() != p.Width || img.Bounds().Dy() != p.Height {
+			t.Fatalf("expected a %dx%d %s overlay, got %v", p.Width, p.Height, kind, img.Bounds())
+		}
+		for y := 0; y < p.Height; y++ {
+			for x := 0; x < p.Width; x++ {
+				if a := img.RGBAAt(x, y).A; a != uint8(255*overlayOpacity) {
+					t.Fatalf("expected every pixel of the %s overlay to be translucent, got alpha %d at %d,%d", kind,
+						a, x, y)
+				}
+			}
+		}
+	}
+
+	// the centre of the view is inside the set, which takes the most iterations, and the corners escape straight away
+	img := tc.Overlay(p, OverlayIterations)
+	want := translucent(costGradient.At(1), overlayOpacity)
+	if got := img.RGBAAt(p.Width/2, p.Height/2); got != want {
+		t.Errorf("expected the set to be the costliest colour %v, got %v", want, got)
+	}
+	if got := img.RGBAAt(0, 0); got == want {
+		t.Errorf("expected the corner to be cheaper than the set")
+	}
+
+	// overlays of frames iterated differently than the cached tiles are empty
+	p.Iterations *= 2
+	if img := tc.Overlay(p, OverlayTileTime); img.RGBAAt(0, 0).A != 0 {
+		t.Errorf("expected frames which aren't cached to overlay nothing, got %v", img.RGBAAt(0, 0))
+	}
+}
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"context"
+	"image"
+)
+
+// the difference in escape iterations between neighbouring pixels beyond which they are considered to be on an edge
+const edgeThreshold = 1
+
+// iterates the image described by p, only supersampling pixels on edges. The samples are laid out as by
+// iterateSamples, with the single sample of each pixel off an edge repeated to fill its share of the buffer. escape
+// iterates the point at the given offset from the centre of the image.
+func iterateEdgeSamples(ctx context.Context, p Params, escape func(d complex128) Sample) ([]Sample, error) {
+	n := p.samplesPerAxis()
+
+	// sample every pixel centre first, including a border of pixels around the image so that edges along the sides of
+	// the image are detected the same as anywhere else, e.g. when rendering a frame in tiles
+	stride := p.Width + 2
+	centres := make([]Sample, stride*(p.Height+2))
+	err := forEachTile(ctx, stride, p.Height+2, func() func(tile image.Rectangle) {
+		return func(tile image.Rectangle) {
+			for y := tile.Min.Y; y < tile.Max.Y; y++ {
+				for x := tile.Min.X; x < tile.Max.X; x++ {
+					centres[y*stride+x] = escape(p.pixelOffset(float64(x)-0.5, float64(y)-0.5))
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the jittered subpixel offsets, one per cell of an n x n grid across the pixel
+	offsets := make([][2]float64, 0, n*n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			offsets = append(offsets, [2]float64{
+				(float64(i) + jitter(i, j, 0)) / float64(n),
+				(float64(j) + jitter(i, j, 1)) / float64(n),
+			})
+		}
+	}
+
+	count := n * n
+	samples := make([]Sample, p.Width*p.Height*count)
+	err = forEachTile(ctx, p.Width, p.Height, func() func(tile image.Rectangle) {
+		return func(tile image.Rectangle) {
+			for py := tile.Min.Y; py < tile.Max.Y; py++ {
+				for px := tile.Min.X; px < tile.Max.X; px++ {
+					pixel := samples[(py*p.Width+px)*count : (py*p.Width+px+1)*count]
+					if !onEdge(centres, stride, px+1, py+1) {
+						centre := centres[(py+1)*stride+px+1]
+						for i := range pixel {
+							pixel[i] = centre
+						}
+						continue
+					}
+					for i, o := range offsets {
+						pixel[i] = escape(p.pixelOffset(float64(px)+o[0], float64(py)+o[1]))
+					}
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// reports whether the escape iteration of the centre sample at (x, y) differs significantly from any of its eight
+// neighbours. Neighbouring pixels one iteration apart are common far from the set, where the bands are wide and smooth.
+func onEdge(centres []Sample, stride, x, y int) bool {
+	n := centres[y*stride+x].N
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if d := centres[(y+dy)*stride+x+dx].N - n; d > edgeThreshold || d < -edgeThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returns a pseudo-random offset in [0.25, 0.75) within cell (i, j) of the subpixel grid along the axis. The offsets
+// break up the regular grid which would otherwise alias with regular structure in the set, but are the same for every
+// pixel so that renders are reproducible however an image is divided up.
+func jitter(i, j, axis int) float64 {
+	// mix the inputs with a multiplicative hash
+	h := uint32(i)*0x9e3779b1 ^ uint32(j)*0x85ebca77 ^ uint32(axis)*0xc2b2ae3d
+	h ^= h >> 15
+	h *= 0x2c1b3c6d
+	h ^= h >> 12
+	return 0.25 + 0.5*float64(h&0xffff)/0x10000
+}
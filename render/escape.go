@@ -0,0 +1,96 @@
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EscapeField is the raw escape iteration of each pixel of an image, for post-processing outside of the built in
+// colourings. Points which didn't escape have the iteration limit. Values are in row-major order with the top row of
+// the image first.
+type EscapeField struct {
+	Width, Height int
+	Iterations    int
+	Values        []float64
+}
+
+// EscapeField returns the escape iteration of each pixel, averaging the samples of anti-aliased pixels. Samples
+// iterated with a colouring which needs NeedsSmooth have fractional escape iterations, and others whole ones.
+func (s *Samples) EscapeField() *EscapeField {
+	p := s.Params
+	count := p.samplesPerAxis() * p.samplesPerAxis()
+	smooth := p.needs()&NeedsSmooth != 0
+
+	f := &EscapeField{Width: p.Width, Height: p.Height, Iterations: p.Iterations}
+	f.Values = make([]float64, p.Width*p.Height)
+	for i := range f.Values {
+		var sum float64
+		for _, sample := range s.samples[i*count : (i+1)*count] {
+			sum += escapeValue(sample, p.Iterations, smooth)
+		}
+		f.Values[i] = sum / float64(count)
+	}
+	return f
+}
+
+// returns the escape iteration of a sample, fractional if smooth and the sample escaped
+func escapeValue(sample Sample, iterations int, smooth bool) float64 {
+	if smooth && sample.N < iterations {
+		return sample.Smooth
+	}
+	return float64(sample.N)
+}
+
+// Gray16 returns the field as a 16-bit greyscale image, scaled so that 0 iterations is black and the iteration limit is
+// white.
+func (f *EscapeField) Gray16() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, f.Width, f.Height))
+	for i, v := range f.Values {
+		v = math.Max(0, math.Min(1, v/float64(f.Iterations)))
+		img.SetGray16(i%f.Width, i/f.Width, color.Gray16{Y: uint16(math.Round(v * math.MaxUint16))})
+	}
+	return img
+}
+
+// WriteCSV writes the field to w as comma separated values, a line per row of the image.
+func (f *EscapeField) WriteCSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var line []byte
+	for y := 0; y < f.Height; y++ {
+		line = line[:0]
+		for x, v := range f.Values[y*f.Width : (y+1)*f.Width] {
+			if x > 0 {
+				line = append(line, ',')
+			}
+			line = strconv.AppendFloat(line, v, 'g', -1, 64)
+		}
+		line = append(line, '\n')
+		bw.Write(line)
+	}
+	return bw.Flush()
+}
+
+// WriteNPY writes the field to w as a NumPy .npy array of float64 with a shape of (height, width), which numpy.load
+// reads directly.
+func (f *EscapeField) WriteNPY(w io.Writer) error {
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", f.Height, f.Width)
+	// the magic string, version and header length take 10 bytes, and the header is padded with spaces and ends in a
+	// newline so that the data is aligned to 64 bytes
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(bw, binary.LittleEndian, uint16(len(header)))
+	bw.WriteString(header)
+	if err := binary.Write(bw, binary.LittleEndian, f.Values); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
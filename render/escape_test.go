@@ -0,0 +1,67 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEscapeField(t *testing.T) {
+	p := testParams()
+	p.Width, p.Height = 12, 9
+	p.Scale = 3.0 / 12
+	p.Colouring = ColouringSmooth
+	s, err := Iterate(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := s.EscapeField()
+	if len(f.Values) != f.Width*f.Height {
+		t.Fatalf("expected %d values, got %d", f.Width*f.Height, len(f.Values))
+	}
+	// the centre of the view is inside the set, and the corners escape straight away
+	centre, corner := f.Values[f.Height/2*f.Width+f.Width/2], f.Values[0]
+	if centre != float64(p.Iterations) || corner >= 5 {
+		t.Errorf("expected the set to reach the iteration limit and the corners to escape early, got %g and %g", centre,
+			corner)
+	}
+
+	img := f.Gray16()
+	if y := img.Gray16At(f.Width/2, f.Height/2).Y; y != math.MaxUint16 {
+		t.Errorf("expected the set to be white, got %d", y)
+	}
+
+	var csv bytes.Buffer
+	if err := f.WriteCSV(&csv); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != f.Height || strings.Count(lines[0], ",") != f.Width-1 {
+		t.Errorf("expected %d lines of %d values, got %d lines", f.Height, f.Width, len(lines))
+	}
+
+	var npy bytes.Buffer
+	if err := f.WriteNPY(&npy); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data := npy.Bytes()
+	if !bytes.HasPrefix(data, []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("expected the npy magic string, got %q", data[:8])
+	}
+	headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+	if (10+headerLen)%64 != 0 || !strings.Contains(string(data[10:10+headerLen]), "'shape': (9, 12)") {
+		t.Errorf("unexpected npy header %q", data[10:10+headerLen])
+	}
+	values := make([]float64, f.Width*f.Height)
+	if err := binary.Read(bytes.NewReader(data[10+headerLen:]), binary.LittleEndian, values); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := range values {
+		if values[i] != f.Values[i] {
+			t.Fatalf("expected value %d to be %g, got %g", i, f.Values[i], values[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Region is a cell of an image scored by how much detail it shows.
+type Region struct {
+	// Centre is the centre of the cell on the complex plane.
+	Centre complex128
+	// Detail is the variance of the logarithm of the smooth escape iterations across the cell, counting points inside
+	// the set as escaping at the iteration limit. Cells straddling intricate parts of the boundary of the set score
+	// highest, and those entirely inside or far outside it score close to zero.
+	Detail float64
+}
+
+// DetailedRegions divides the image described by p into a grid of n by n cells, ordered from the most to the least
+// detailed. Only the viewport and iteration settings of p are used, so it's best given a low resolution version of the
+// view to keep the search quick.
+func DetailedRegions(ctx context.Context, p Params, n int) ([]Region, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if n < 1 || n > p.Width || n > p.Height {
+		return nil, fmt.Errorf("grid size must be between 1 and the image size")
+	}
+
+	p.Colouring, p.Interior, p.Samples, p.SampleEdges = ColouringSmooth, InteriorFlat, 1, false
+	samples, err := iterateSamples(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// accumulate the sum and sum of squares of each cell's values for their variance
+	type cell struct {
+		sum, sumSquares float64
+		count           int
+	}
+	cells := make([]cell, n*n)
+	for i, s := range samples {
+		v := float64(p.Iterations)
+		if s.Escaped(p) {
+			v = s.Smooth
+		}
+		v = math.Log1p(v)
+
+		x, y := i%p.Width, i/p.Width
+		c := &cells[(y*n/p.Height)*n+x*n/p.Width]
+		c.sum += v
+		c.sumSquares += v * v
+		c.count++
+	}
+
+	regions := make([]Region, 0, n*n)
+	for i, c := range cells {
+		mean := c.sum / float64(c.count)
+		cx, cy := float64(i%n)+0.5, float64(i/n)+0.5
+		regions = append(regions, Region{
+			Centre: p.PixelToPlane(cx*float64(p.Width)/float64(n), cy*float64(p.Height)/float64(n)),
+			// rounding can leave uniform cells very slightly negative
+			Detail: math.Max(0, c.sumSquares/float64(c.count)-mean*mean),
+		})
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Detail > regions[j].Detail
+	})
+	return regions, nil
+}
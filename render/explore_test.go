@@ -0,0 +1,34 @@
+package render
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetailedRegions(t *testing.T) {
+	// the right of the view is inside the main cardioid and the left straddles the boundary of the set around the
+	// seahorse valley
+	p := Params{Centre: complex(-0.6, 0.1), Scale: 0.5 / 64, Width: 64, Height: 64, Iterations: 100, Fractal: "mandelbrot"}
+	regions, err := DetailedRegions(context.Background(), p, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(regions) != 16 {
+		t.Fatalf("expected 16 regions, got %d", len(regions))
+	}
+	for i := 1; i < len(regions); i++ {
+		if regions[i].Detail > regions[i-1].Detail {
+			t.Fatalf("expected regions ordered by detail, got %v", regions)
+		}
+	}
+	if best := regions[0].Centre; real(best) > real(p.Centre) {
+		t.Errorf("expected the most detailed region to be left of centre, got %v", best)
+	}
+	if worst := regions[len(regions)-1]; worst.Detail > 1e-9 {
+		t.Errorf("expected a region entirely inside the set with no detail, got %v", worst)
+	}
+
+	if _, err := DetailedRegions(context.Background(), p, 0); err == nil {
+		t.Error("expected an error for an empty grid")
+	}
+}
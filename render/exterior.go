@@ -0,0 +1,88 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"math/cmplx"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// returns the power z is raised to by the fractal's formula each iteration
+func (p Params) degree() float64 {
+	if p.Fractal == "multibrot" && p.Formula == "" {
+		return p.Exponent
+	}
+	return 2
+}
+
+// returns the derivative of the fractal's formula at z with respect to z. The burning ship and tricorn fold z before
+// squaring it, which leaves the magnitude of the derivative unchanged, so they share the mandelbrot's.
+func (p Params) derivative(z complex128) complex128 {
+	d := p.degree()
+	if d == 2 {
+		return 2 * z
+	}
+	if z == 0 {
+		return 0
+	}
+	return complex(d, 0) * cmplx.Pow(z, complex(d-1, 0))
+}
+
+// returns the fractional escape iteration of a point which escaped on iteration n at z. The further past the escape
+// radius z landed, the earlier in the iteration the point effectively escaped.
+func smoothIteration(p Params, n int, z complex128) float64 {
+	r, d := cmplx.Abs(z), p.degree()
+	// small escape radii and degrees leave the logarithms undefined
+	if r <= 1 || d <= 1 {
+		return float64(n)
+	}
+	return math.Max(0, float64(n)+1-math.Log(math.Log(r))/math.Log(d))
+}
+
+// estimates the distance on the plane from a point to the set, given the point z its orbit escaped at and the
+// derivative dz of the orbit with respect to the point
+func exteriorDistance(z, dz complex128) float64 {
+	r, m := cmplx.Abs(z), cmplx.Abs(dz)
+	if m == 0 {
+		return 0
+	}
+	return r * math.Log(r) / m
+}
+
+// smoothColourer implements ColouringSmooth. The palette repeats as often as the ColouringBands shades do at the same
+// contrast, running forwards then backwards so that gradients with different colours at each end don't show seams.
+type smoothColourer struct{}
+
+func (smoothColourer) Needs() Needs {
+	return NeedsSmooth
+}
+
+func (smoothColourer) Colour(p Params, _ []int) func(s Sample) color.RGBA {
+	gradient := lookupGradient(p.Palette)
+	cycle := 256 / float64(p.contrast())
+	return func(s Sample) color.RGBA {
+		if !s.Escaped(p) {
+			return palette.Interior
+		}
+		t := math.Mod(s.Smooth/cycle, 1)
+		return gradient.At(1 - math.Abs(1-2*t))
+	}
+}
+
+// distanceColourer implements ColouringDistance, running through the palette from the boundary of the set outwards
+type distanceColourer struct{}
+
+func (distanceColourer) Needs() Needs {
+	return NeedsDistance
+}
+
+func (distanceColourer) Colour(p Params, _ []int) func(s Sample) color.RGBA {
+	gradient := lookupGradient(p.Palette)
+	return func(s Sample) color.RGBA {
+		if !s.Escaped(p) {
+			return palette.Interior
+		}
+		return gradient.At(1 - math.Exp(-s.Distance/distanceGlow))
+	}
+}
@@ -0,0 +1,399 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// the functions custom formulas can call
+var formulaFuncs = map[string]func(complex128) complex128{
+	"sin":  cmplx.Sin,
+	"cos":  cmplx.Cos,
+	"tan":  cmplx.Tan,
+	"sinh": cmplx.Sinh,
+	"cosh": cmplx.Cosh,
+	"tanh": cmplx.Tanh,
+	"exp":  cmplx.Exp,
+	"log":  cmplx.Log,
+	"sqrt": cmplx.Sqrt,
+	"conj": cmplx.Conj,
+	"abs": func(z complex128) complex128 {
+		return complex(cmplx.Abs(z), 0)
+	},
+	"re": func(z complex128) complex128 {
+		return complex(real(z), 0)
+	},
+	"im": func(z complex128) complex128 {
+		return complex(imag(z), 0)
+	},
+	// folds z into the first quadrant, as the burning ship does
+	"fold": func(z complex128) complex128 {
+		return complex(math.Abs(real(z)), math.Abs(imag(z)))
+	},
+}
+
+// the named constants custom formulas can use
+var formulaConsts = map[string]complex128{
+	"i":  1i,
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var (
+	// formulas compiled so far, keyed by their source, as each tile of a frame looks its formula up again
+	compiledFormulas   = make(map[string]formula)
+	compiledFormulasMu sync.Mutex
+)
+
+// CheckFormula reports whether source is a valid Params.Formula, returning the problem with it if not.
+func CheckFormula(source string) error {
+	_, err := compileFormula(source)
+	return err
+}
+
+// compiles a custom formula in z and c, such as "z^3 + c*sin(z)", into a func which evaluates it. Formulas support
+// + - * / and ^ with the usual precedence, parentheses, numbers, the constants i, pi and e, and the functions in
+// formulaFuncs.
+func compileFormula(source string) (formula, error) {
+	compiledFormulasMu.Lock()
+	defer compiledFormulasMu.Unlock()
+
+	if f, ok := compiledFormulas[source]; ok {
+		return f, nil
+	}
+	tokens, err := tokenise(source)
+	if err != nil {
+		return nil, err
+	}
+	fp := &formulaParser{tokens: tokens}
+	n, err := fp.expression()
+	if err != nil {
+		return nil, err
+	}
+	if t := fp.peek(); t.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+
+	e := n.compile()
+	f := func(z, c complex128, _ float64) complex128 {
+		return e(z, c)
+	}
+	compiledFormulas[source] = f
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+// token is a lexical element of a formula, at the byte offset pos of the source
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenise(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		r := rune(source[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(source) && (unicode.IsDigit(rune(source[j])) || source[j] == '.') {
+				j++
+			}
+			// exponents in scientific notation, e.g. 1e-3
+			if j < len(source) && source[j] == 'e' {
+				k := j + 1
+				if k < len(source) && (source[k] == '+' || source[k] == '-') {
+					k++
+				}
+				if k < len(source) && unicode.IsDigit(rune(source[k])) {
+					for j = k; j < len(source) && unicode.IsDigit(rune(source[j])); j++ {
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(source) && (unicode.IsLetter(rune(source[j])) || unicode.IsDigit(rune(source[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:j], pos: i})
+			i = j
+		case strings.ContainsRune("+-*/^()", r):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, i+1)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, text: "end of formula", pos: len(source)}), nil
+}
+
+// formulaParser is a recursive descent parser over the tokens of a formula
+type formulaParser struct {
+	tokens []token
+	next   int
+}
+
+func (fp *formulaParser) peek() token {
+	return fp.tokens[fp.next]
+}
+
+func (fp *formulaParser) take() token {
+	t := fp.tokens[fp.next]
+	if t.kind != tokenEnd {
+		fp.next++
+	}
+	return t
+}
+
+// reports whether the next token is the operator op, consuming it if so
+func (fp *formulaParser) accept(op string) bool {
+	if t := fp.peek(); t.kind == tokenOperator && t.text == op {
+		fp.next++
+		return true
+	}
+	return false
+}
+
+// expression = term {("+" | "-") term}
+func (fp *formulaParser) expression() (node, error) {
+	n, err := fp.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if fp.accept("+") {
+			op = "+"
+		} else if fp.accept("-") {
+			op = "-"
+		} else {
+			return n, nil
+		}
+		rhs, err := fp.term()
+		if err != nil {
+			return nil, err
+		}
+		n = binaryNode{op: op, lhs: n, rhs: rhs}
+	}
+}
+
+// term = unary {("*" | "/") unary}
+func (fp *formulaParser) term() (node, error) {
+	n, err := fp.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if fp.accept("*") {
+			op = "*"
+		} else if fp.accept("/") {
+			op = "/"
+		} else {
+			return n, nil
+		}
+		rhs, err := fp.unary()
+		if err != nil {
+			return nil, err
+		}
+		n = binaryNode{op: op, lhs: n, rhs: rhs}
+	}
+}
+
+// unary = ("-" | "+") unary | power
+func (fp *formulaParser) unary() (node, error) {
+	if fp.accept("-") {
+		n, err := fp.unary()
+		if err != nil {
+			return nil, err
+		}
+		// fold negative numbers into constants, so that powers like z^-2 still take the fast path
+		if k, ok := n.(constNode); ok {
+			return -k, nil
+		}
+		return binaryNode{op: "-", lhs: constNode(0), rhs: n}, nil
+	}
+	if fp.accept("+") {
+		return fp.unary()
+	}
+	return fp.power()
+}
+
+// power = primary ["^" unary], so that powers associate to the right and bind tighter than a leading minus
+func (fp *formulaParser) power() (node, error) {
+	n, err := fp.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !fp.accept("^") {
+		return n, nil
+	}
+	exponent, err := fp.unary()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: "^", lhs: n, rhs: exponent}, nil
+}
+
+// primary = number | "z" | "c" | constant | function "(" expression ")" | "(" expression ")"
+func (fp *formulaParser) primary() (node, error) {
+	t := fp.take()
+	switch {
+	case t.kind == tokenNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos+1)
+		}
+		return constNode(complex(v, 0)), nil
+
+	case t.kind == tokenIdent:
+		if t.text == "z" || t.text == "c" {
+			return varNode(t.text), nil
+		}
+		if v, ok := formulaConsts[t.text]; ok {
+			return constNode(v), nil
+		}
+		fn, ok := formulaFuncs[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown name %q at position %d", t.text, t.pos+1)
+		}
+		if !fp.accept("(") {
+			return nil, fmt.Errorf("expected ( after %s at position %d", t.text, t.pos+1)
+		}
+		arg, err := fp.parenthesised()
+		if err != nil {
+			return nil, err
+		}
+		return callNode{fn: fn, arg: arg}, nil
+
+	case t.kind == tokenOperator && t.text == "(":
+		return fp.parenthesised()
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+// parses the rest of a parenthesised expression after its opening parenthesis
+func (fp *formulaParser) parenthesised() (node, error) {
+	n, err := fp.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !fp.accept(")") {
+		t := fp.peek()
+		return nil, fmt.Errorf("expected ) at position %d, got %q", t.pos+1, t.text)
+	}
+	return n, nil
+}
+
+// expr evaluates a compiled formula or part of one
+type expr func(z, c complex128) complex128
+
+// node is a node of a parsed formula
+type node interface {
+	compile() expr
+}
+
+type constNode complex128
+
+func (n constNode) compile() expr {
+	v := complex128(n)
+	return func(_, _ complex128) complex128 {
+		return v
+	}
+}
+
+type varNode string
+
+func (n varNode) compile() expr {
+	if n == "z" {
+		return func(z, _ complex128) complex128 {
+			return z
+		}
+	}
+	return func(_, c complex128) complex128 {
+		return c
+	}
+}
+
+type callNode struct {
+	fn  func(complex128) complex128
+	arg node
+}
+
+func (n callNode) compile() expr {
+	fn, arg := n.fn, n.arg.compile()
+	return func(z, c complex128) complex128 {
+		return fn(arg(z, c))
+	}
+}
+
+type binaryNode struct {
+	op       string
+	lhs, rhs node
+}
+
+func (n binaryNode) compile() expr {
+	lhs, rhs := n.lhs.compile(), n.rhs.compile()
+	switch n.op {
+	case "+":
+		return func(z, c complex128) complex128 {
+			return lhs(z, c) + rhs(z, c)
+		}
+	case "-":
+		return func(z, c complex128) complex128 {
+			return lhs(z, c) - rhs(z, c)
+		}
+	case "*":
+		return func(z, c complex128) complex128 {
+			return lhs(z, c) * rhs(z, c)
+		}
+	case "/":
+		return func(z, c complex128) complex128 {
+			return lhs(z, c) / rhs(z, c)
+		}
+	}
+
+	// repeated multiplication is much cheaper than the general power for small whole exponents
+	if k, ok := n.rhs.(constNode); ok {
+		if d := real(k); imag(k) == 0 && d == math.Trunc(d) && math.Abs(d) >= 1 && math.Abs(d) <= 16 {
+			m := int(math.Abs(d))
+			return func(z, c complex128) complex128 {
+				base := lhs(z, c)
+				p := base
+				for i := 1; i < m; i++ {
+					p *= base
+				}
+				if d < 0 {
+					return 1 / p
+				}
+				return p
+			}
+		}
+	}
+	return func(z, c complex128) complex128 {
+		base := lhs(z, c)
+		// the general power is undefined at zero
+		if base == 0 {
+			return 0
+		}
+		return cmplx.Pow(base, rhs(z, c))
+	}
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"context"
+	"math/cmplx"
+	"testing"
+)
+
+func TestCompileFormula(t *testing.T) {
+	z, c := complex(0.3, -0.7), complex(-0.5, 0.25)
+	tests := []struct {
+		formula string
+		want    complex128
+	}{
+		{formula: "z^2 + c", want: z*z + c},
+		{formula: "z^3 + c*sin(z)", want: z*z*z + c*cmplx.Sin(z)},
+		{formula: "z^-2 + c", want: 1/(z*z) + c},
+		{formula: "-z^2", want: -(z * z)},
+		{formula: "2^3^2", want: 512},
+		{formula: "(z + 1) * (z - 1) / 2", want: (z + 1) * (z - 1) / 2},
+		{formula: "z^2.5 + c", want: cmplx.Pow(z, 2.5) + c},
+		{formula: "conj(z)^2 + c", want: cmplx.Conj(z)*cmplx.Conj(z) + c},
+		{formula: "exp(i*pi) + 1.5e-1", want: cmplx.Exp(1i*3.141592653589793) + 0.15},
+	}
+	for _, tt := range tests {
+		f, err := compileFormula(tt.formula)
+		if err != nil {
+			t.Errorf("unexpected error compiling %q: %s", tt.formula, err)
+			continue
+		}
+		if got := f(z, c, 0); cmplx.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("expected %q to evaluate to %v, got %v", tt.formula, tt.want, got)
+		}
+	}
+}
+
+func TestCompileFormulaErrors(t *testing.T) {
+	for _, formula := range []string{"", "z^", "z + y", "sin z", "(z + c", "z + c)", "z $ c", "2 z", "1.2.3"} {
+		if _, err := compileFormula(formula); err == nil {
+			t.Errorf("expected an error compiling %q", formula)
+		}
+	}
+}
+
+func TestFormulaMatchesFractal(t *testing.T) {
+	p := testParams()
+	p.Fractal = "burning-ship"
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.Fractal, p.Formula = "mandelbrot", "fold(z)^2 + c"
+	got, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+
+	p.Formula = "z^"
+	if err := p.Validate(); err == nil {
+		t.Error("expected an invalid formula to fail validation")
+	}
+}
@@ -0,0 +1,97 @@
+package render
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// formula advances the orbit z of the point c by one iteration
+type formula func(z, c complex128, exponent float64) complex128
+
+// fractal is a named escape-time formula
+type fractal struct {
+	name    string
+	iterate formula
+}
+
+// the supported fractals, in the order reported by Fractals
+var fractals = []fractal{
+	{name: "mandelbrot", iterate: mandelbrot},
+	{name: "burning-ship", iterate: burningShip},
+	{name: "tricorn", iterate: tricorn},
+	{name: "multibrot", iterate: multibrot},
+	// the newton fractal converges on roots rather than escaping, so it's iterated by escapeNewton instead
+	{name: "newton"},
+}
+
+// z^2 + c
+func mandelbrot(z, c complex128, _ float64) complex128 {
+	return z*z + c
+}
+
+// (|Re(z)| + i|Im(z)|)^2 + c
+func burningShip(z, c complex128, _ float64) complex128 {
+	z = complex(math.Abs(real(z)), math.Abs(imag(z)))
+	return z*z + c
+}
+
+// conj(z)^2 + c, also known as the mandelbar
+func tricorn(z, c complex128, _ float64) complex128 {
+	z = cmplx.Conj(z)
+	return z*z + c
+}
+
+// z^d + c
+func multibrot(z, c complex128, d float64) complex128 {
+	// repeated multiplication is much cheaper than the general power for whole exponents
+	if n := int(d); float64(n) == d && n >= 1 && n <= 16 {
+		p := z
+		for i := 1; i < n; i++ {
+			p *= z
+		}
+		return p + c
+	}
+	if z == 0 {
+		return c
+	}
+	return cmplx.Pow(z, complex(d, 0)) + c
+}
+
+// Fractals returns the names of the supported fractals.
+func Fractals() []string {
+	names := make([]string, len(fractals))
+	for i, f := range fractals {
+		names[i] = f.name
+	}
+	return names
+}
+
+// IsFractal reports whether name is a supported fractal.
+func IsFractal(name string) bool {
+	_, ok := lookupFractal(name)
+	return ok
+}
+
+// returns the formula p iterates: its custom Formula if it has one, otherwise its Fractal's. p must be valid.
+func (p Params) formula() formula {
+	if p.Formula != "" {
+		f, _ := compileFormula(p.Formula)
+		return f
+	}
+	f, _ := lookupFractal(p.Fractal)
+	return f
+}
+
+// reports whether p renders the newton fractal, which custom formulas take precedence over
+func (p Params) isNewton() bool {
+	return p.Fractal == "newton" && p.Formula == ""
+}
+
+func lookupFractal(name string) (formula, bool) {
+	for _, f := range fractals {
+		if f.name == name {
+			return f.iterate, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,303 @@
+package render
+
+import (
+	"context"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden images in testdata/golden with the images rendered")
+
+const (
+	// how far a channel of a pixel may stray from its golden image, and the fraction of pixels which may stray
+	// further. Floating point rounds differently between architectures, e.g. where multiplies and adds are fused, and
+	// between the vector and scalar paths, which tips the odd point near the boundary of a band over to the next.
+	goldenChannelTolerance = 8
+	goldenPixelTolerance   = 0.02
+)
+
+// returns the params of a golden image: a 48x32 view of the mandelbrot span wide, snapped to the pixel grid so that
+// the tile cache renders the same view
+func goldenParams(centre complex128, span float64) Params {
+	return SnapToGrid(Params{
+		Centre:     centre,
+		Scale:      span / 48,
+		Width:      48,
+		Height:     32,
+		Iterations: 200,
+		Fractal:    "mandelbrot",
+	})
+}
+
+// the images kept in testdata/golden, small enough to render in a moment but between them covering each fractal,
+// colouring, interior and precision
+var goldenCases = []struct {
+	name   string
+	modify func(p *Params)
+}{
+	{name: "mandelbrot", modify: func(p *Params) {}},
+	{name: "mandelbrot-samples", modify: func(p *Params) { p.Samples = 3 }},
+	{name: "seahorse-smooth", modify: func(p *Params) {
+		*p = goldenParams(complex(-0.7453, 0.1127), 0.01)
+		p.Iterations, p.Colouring, p.Palette = 500, ColouringSmooth, "ultra"
+	}},
+	{name: "seahorse-histogram", modify: func(p *Params) {
+		*p = goldenParams(complex(-0.7453, 0.1127), 0.05)
+		p.Colouring, p.Palette = ColouringHistogram, "fire"
+	}},
+	{name: "mandelbrot-distance", modify: func(p *Params) { p.Colouring, p.Palette = ColouringDistance, "grey" }},
+	{name: "mandelbrot-orbit-trap", modify: func(p *Params) {
+		p.Colouring, p.Trap, p.Palette = ColouringOrbitTrap, TrapRing, "lime"
+	}},
+	{name: "mandelbrot-period", modify: func(p *Params) { p.Interior = InteriorPeriod }},
+	{name: "mandelbrot-manhattan", modify: func(p *Params) { p.Norm, p.Bailout = NormManhattan, 4 }},
+	{name: "mandelbrot-levels", modify: func(p *Params) {
+		p.Colouring, p.Palette = ColouringSmooth, "viridis"
+		p.Levels = palette.Levels{Brightness: 0.1, Contrast: 1.2, Gamma: 0.8}
+	}},
+	{name: "burning-ship", modify: func(p *Params) {
+		*p = goldenParams(complex(-1.76, -0.03), 0.12)
+		p.Fractal = "burning-ship"
+	}},
+	{name: "tricorn", modify: func(p *Params) { p.Fractal = "tricorn" }},
+	{name: "multibrot", modify: func(p *Params) { p.Fractal, p.Exponent = "multibrot", 3 }},
+	{name: "formula", modify: func(p *Params) { p.Formula = "z^3 + c*sin(z)" }},
+	{name: "julia", modify: func(p *Params) {
+		*p = goldenParams(0, 3.2)
+		p.Julia, p.Seed = true, complex(-0.123, 0.745)
+	}},
+	{name: "newton", modify: func(p *Params) {
+		*p = goldenParams(0, 3)
+		p.Fractal, p.Colouring = "newton", ColouringRoots
+	}},
+	{name: "perturbation", modify: func(p *Params) {
+		*p = goldenParams(complex(-0.743643887037151, 0.13182590420533), 3e-12)
+		p.Iterations, p.Colouring, p.Palette = 2000, ColouringSmooth, "magma"
+	}},
+}
+
+// returns the params of the i'th golden image
+func goldenCase(i int) Params {
+	p := goldenParams(complex(-0.5, 0), 3)
+	goldenCases[i].modify(&p)
+	return p
+}
+
+func TestGolden(t *testing.T) {
+	for i, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			img, err := Render(context.Background(), goldenCase(i))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			path := filepath.Join("testdata", "golden", tc.name+".png")
+			if *updateGolden {
+				writeGolden(t, path, img)
+				return
+			}
+			assertNearGolden(t, img, readGolden(t, path))
+		})
+	}
+}
+
+// every route through the renderer must give the same images as Render, whether it iterates in parallel, in
+// strips, in tiles, with vector instructions or progressively
+func TestGoldenPaths(t *testing.T) {
+	paths := map[string]func(p Params) (*image.RGBA, error){
+		"simd": func(p Params) (*image.RGBA, error) {
+			p.SIMD = true
+			return Render(context.Background(), p)
+		},
+		"single threaded": func(p Params) (*image.RGBA, error) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+			return Render(context.Background(), p)
+		},
+		"tile cache": func(p Params) (*image.RGBA, error) {
+			return NewTileCache().Render(context.Background(), p)
+		},
+		"strips": func(p Params) (*image.RGBA, error) {
+			r, err := NewStripRenderer(p, 7)
+			if err != nil {
+				return nil, err
+			}
+			img := image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+			for i := 0; i < r.Len(); i++ {
+				strip, err := r.Strip(context.Background(), i)
+				if err != nil {
+					return nil, err
+				}
+				draw.Draw(img, strip.Bounds(), strip, strip.Bounds().Min, draw.Src)
+			}
+			return img, nil
+		},
+		"split and joined": func(p Params) (*image.RGBA, error) {
+			var strips []*Samples
+			for _, sp := range Split(p, 3) {
+				s, err := Iterate(context.Background(), sp)
+				if err != nil {
+					return nil, err
+				}
+				strips = append(strips, s)
+			}
+			s, err := Join(p, strips)
+			if err != nil {
+				return nil, err
+			}
+			return s.Image(), nil
+		},
+		"progressive": func(p Params) (*image.RGBA, error) {
+			if !CanProgress(p) {
+				return Render(context.Background(), p)
+			}
+			pr, err := NewProgressive(p)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := pr.Step(context.Background(), time.Hour); err != nil {
+				return nil, err
+			}
+			return pr.Image(), nil
+		},
+	}
+	if *updateGolden {
+		t.Skip("the golden images are being rewritten")
+	}
+	for name, render := range paths {
+		for i, tc := range goldenCases {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				img, err := render(goldenCase(i))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				assertNearGolden(t, img, readGolden(t, filepath.Join("testdata", "golden", tc.name+".png")))
+			})
+		}
+	}
+}
+
+// checks got matches the golden image want to within the tolerances
+func assertNearGolden(t *testing.T, got *image.RGBA, want image.Image) {
+	t.Helper()
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("image bounds %v, want %v", got.Bounds(), want.Bounds())
+	}
+	var strays int
+	b := want.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g, w := got.RGBAAt(x, y), want.At(x, y)
+			wr, wg, wb, wa := w.RGBA()
+			for _, d := range []int{
+				int(g.R) - int(wr>>8), int(g.G) - int(wg>>8), int(g.B) - int(wb>>8), int(g.A) - int(wa>>8),
+			} {
+				if d > goldenChannelTolerance || d < -goldenChannelTolerance {
+					strays++
+					break
+				}
+			}
+		}
+	}
+	if limit := int(goldenPixelTolerance * float64(b.Dx()*b.Dy())); strays > limit {
+		t.Fatalf("%d pixels differ from the golden image, at most %d may", strays, limit)
+	}
+}
+
+func readGolden(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open the golden image, write it with -update: %s", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("failed to decode the golden image %s: %s", path, err)
+	}
+	return img
+}
+
+func writeGolden(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("failed to encode %s: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// returns params of a random view for the coordinate mapping properties
+func randomParams(r *rand.Rand) Params {
+	return Params{
+		Centre: complex(r.Float64()*4-2, r.Float64()*4-2),
+		Scale:  math.Pow(10, -r.Float64()*8),
+		Width:  1 + r.Intn(2000),
+		Height: 1 + r.Intn(2000),
+	}
+}
+
+func TestPixelToPlaneProperties(t *testing.T) {
+	config := &quick.Config{Rand: rand.New(rand.NewSource(1))}
+	// points are rounded to float64 relative to the magnitude of the coordinates across the image
+	near := func(a, b complex128, p Params) bool {
+		magnitude := cmplx.Abs(p.Centre) + float64(p.Width+p.Height)*p.Scale
+		return cmplx.Abs(a-b) <= 1e-9*p.Scale+1e-15*magnitude
+	}
+
+	// moving a pixel right moves Scale along the real axis, and a pixel down Scale down the imaginary one
+	steps := func(seed int64, x, y float64) bool {
+		p := randomParams(rand.New(rand.NewSource(seed)))
+		x, y = math.Mod(x, float64(p.Width)), math.Mod(y, float64(p.Height))
+		c := p.PixelToPlane(x, y)
+		return near(p.PixelToPlane(x+1, y)-c, complex(p.Scale, 0), p) &&
+			near(p.PixelToPlane(x, y+1)-c, complex(0, -p.Scale), p)
+	}
+	if err := quick.Check(steps, config); err != nil {
+		t.Errorf("expected each pixel to step Scale across the plane: %s", err)
+	}
+
+	// the centre of the image is Centre, and opposite corners mirror each other about it
+	centred := func(seed int64, x, y float64) bool {
+		p := randomParams(rand.New(rand.NewSource(seed)))
+		x, y = math.Mod(x, float64(p.Width)), math.Mod(y, float64(p.Height))
+		w, h := float64(p.Width), float64(p.Height)
+		mirrored := p.PixelToPlane(x, y) + p.PixelToPlane(w-x, h-y)
+		return near(p.PixelToPlane(w/2, h/2), p.Centre, p) && near(mirrored, 2*p.Centre, p)
+	}
+	if err := quick.Check(centred, config); err != nil {
+		t.Errorf("expected the image to be centred on Centre: %s", err)
+	}
+
+	// the grid snapped to is a whole number of pixels from the origin and less than a pixel from the centre asked for
+	snapped := func(seed int64) bool {
+		p := randomParams(rand.New(rand.NewSource(seed)))
+		s := SnapToGrid(p)
+		corner := s.PixelToPlane(0, 0) / complex(s.Scale, 0)
+		whole := math.Abs(real(corner)-math.Round(real(corner))) < 1e-6 &&
+			math.Abs(imag(corner)-math.Round(imag(corner))) < 1e-6
+		return whole && cmplx.Abs(s.Centre-p.Centre) <= p.Scale && SnapToGrid(s) == s
+	}
+	if err := quick.Check(snapped, config); err != nil {
+		t.Errorf("expected SnapToGrid to snap to the nearest whole pixel: %s", err)
+	}
+}
@@ -0,0 +1,198 @@
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// HeightMap is the escape time field of an image as heights from 0 to 1, rising towards the set and levelling off at 1
+// inside it. Heights are in row-major order with the top row of the image first.
+type HeightMap struct {
+	Width, Height int
+	Heights       []float64
+}
+
+// HeightMap returns the escape time of each pixel as a height, averaging the samples of anti-aliased pixels. Escape
+// times are on a log scale, so that the slopes far from the set aren't flattened by the cliffs next to it. Samples
+// iterated with a colouring which needs NeedsSmooth rise smoothly rather than in terraces.
+func (s *Samples) HeightMap() *HeightMap {
+	p := s.Params
+	count := p.samplesPerAxis() * p.samplesPerAxis()
+	smooth := p.needs()&NeedsSmooth != 0
+	top := math.Log1p(float64(p.Iterations))
+
+	h := &HeightMap{Width: p.Width, Height: p.Height, Heights: make([]float64, p.Width*p.Height)}
+	for i := range h.Heights {
+		var sum float64
+		for _, sample := range s.samples[i*count : (i+1)*count] {
+			sum += math.Min(1, math.Log1p(escapeValue(sample, p.Iterations, smooth))/top)
+		}
+		h.Heights[i] = sum / float64(count)
+	}
+	return h
+}
+
+// returns the height at (x, y), clamping positions outside the map to its edges
+func (h *HeightMap) at(x, y int) float64 {
+	x = clampInt(x, 0, h.Width-1)
+	y = clampInt(y, 0, h.Height-1)
+	return h.Heights[y*h.Width+x]
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Relief renders the height map as shaded relief, coloured by height with the named palette.Gradients gradient and lit
+// from the top left as if the heights rose exaggeration pixels at their highest.
+func (h *HeightMap) Relief(gradient string, exaggeration float64) *image.RGBA {
+	g, ok := palette.LookupGradient(gradient)
+	if !ok {
+		g, _ = palette.LookupGradient(palette.Gradients()[0])
+	}
+	// a light from the top left, part way up the sky
+	lx, ly, lz := -1.0, 1.0, 1.0
+	l := math.Sqrt(lx*lx + ly*ly + lz*lz)
+	lx, ly, lz = lx/l, ly/l, lz/l
+
+	img := image.NewRGBA(image.Rect(0, 0, h.Width, h.Height))
+	for y := 0; y < h.Height; y++ {
+		for x := 0; x < h.Width; x++ {
+			// the slope from central differences, with y flipped so that it increases up the image
+			dx := (h.at(x+1, y) - h.at(x-1, y)) / 2 * exaggeration
+			dy := (h.at(x, y-1) - h.at(x, y+1)) / 2 * exaggeration
+			// the surface normal is (-dx, -dy, 1), normalised
+			n := math.Sqrt(dx*dx + dy*dy + 1)
+			shade := math.Max(0, (-dx*lx-dy*ly+lz)/n)
+			// keep some ambient light so that slopes facing away from the light aren't black
+			shade = 0.25 + 0.75*shade
+
+			c := g.At(h.at(x, y))
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(c.R) * shade),
+				G: uint8(float64(c.G) * shade),
+				B: uint8(float64(c.B) * shade),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+// a triangle of a mesh, as the indices of its vertices ordered anticlockwise seen from outside the solid
+type triangle [3]int
+
+// returns the vertices and triangles of the height map as a closed solid for 3D printing, one unit per pixel. The
+// surface rises from base units above the bottom of the solid to base + height units at the highest, and each pixel is
+// a vertex of both the surface and the flat bottom, joined by walls around the edges.
+func (h *HeightMap) solid(height, base float64) ([][3]float64, []triangle) {
+	w, d := h.Width, h.Height
+	vertices := make([][3]float64, 0, 2*w*d)
+	for _, top := range []bool{true, false} {
+		for y := 0; y < d; y++ {
+			for x := 0; x < w; x++ {
+				z := 0.0
+				if top {
+					z = base + h.at(x, y)*height
+				}
+				// the top row of the image is at the far side of the solid
+				vertices = append(vertices, [3]float64{float64(x), float64(d - 1 - y), z})
+			}
+		}
+	}
+	topIndex := func(x, y int) int { return y*w + x }
+	bottomIndex := func(x, y int) int { return w*d + y*w + x }
+
+	var triangles []triangle
+	// quad appends two triangles joining a, b, c and d, ordered anticlockwise
+	quad := func(a, b, c, d int) {
+		triangles = append(triangles, triangle{a, b, c}, triangle{a, c, d})
+	}
+	for y := 0; y+1 < d; y++ {
+		for x := 0; x+1 < w; x++ {
+			// y increases down the image but the solid's y axis points the other way, which reverses the winding
+			quad(topIndex(x, y+1), topIndex(x+1, y+1), topIndex(x+1, y), topIndex(x, y))
+			quad(bottomIndex(x, y), bottomIndex(x+1, y), bottomIndex(x+1, y+1), bottomIndex(x, y+1))
+		}
+	}
+	for x := 0; x+1 < w; x++ {
+		// the near wall along the bottom row of the image, then the far wall along the top row
+		quad(bottomIndex(x, d-1), bottomIndex(x+1, d-1), topIndex(x+1, d-1), topIndex(x, d-1))
+		quad(bottomIndex(x+1, 0), bottomIndex(x, 0), topIndex(x, 0), topIndex(x+1, 0))
+	}
+	for y := 0; y+1 < d; y++ {
+		// the left wall, then the right
+		quad(bottomIndex(0, y), bottomIndex(0, y+1), topIndex(0, y+1), topIndex(0, y))
+		quad(bottomIndex(w-1, y+1), bottomIndex(w-1, y), topIndex(w-1, y), topIndex(w-1, y+1))
+	}
+	return vertices, triangles
+}
+
+// WriteOBJ writes the height map to w as a Wavefront OBJ mesh of a closed solid, one unit per pixel, whose surface
+// rises from base units to base + height units above its flat bottom.
+func (h *HeightMap) WriteOBJ(w io.Writer, height, base float64) error {
+	if h.Width < 2 || h.Height < 2 {
+		return fmt.Errorf("a mesh needs a height map of at least 2x2 pixels")
+	}
+	vertices, triangles := h.solid(height, base)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# mandelbrot height map, %dx%d\n", h.Width, h.Height)
+	for _, v := range vertices {
+		fmt.Fprintf(bw, "v %g %g %g\n", v[0], v[1], v[2])
+	}
+	// OBJ indices start at 1
+	for _, t := range triangles {
+		fmt.Fprintf(bw, "f %d %d %d\n", t[0]+1, t[1]+1, t[2]+1)
+	}
+	return bw.Flush()
+}
+
+// WriteSTL writes the height map to w as a binary STL mesh of the same solid as WriteOBJ.
+func (h *HeightMap) WriteSTL(w io.Writer, height, base float64) error {
+	if h.Width < 2 || h.Height < 2 {
+		return fmt.Errorf("a mesh needs a height map of at least 2x2 pixels")
+	}
+	vertices, triangles := h.solid(height, base)
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 80)
+	copy(header, fmt.Sprintf("mandelbrot height map, %dx%d", h.Width, h.Height))
+	bw.Write(header)
+	binary.Write(bw, binary.LittleEndian, uint32(len(triangles)))
+
+	// each triangle is its normal, its three vertices and an unused attribute count, all in single precision
+	var record [12]float32
+	for _, t := range triangles {
+		a, b, c := vertices[t[0]], vertices[t[1]], vertices[t[2]]
+		n := cross(sub3(b, a), sub3(c, a))
+		if l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2]); l > 0 {
+			n = [3]float64{n[0] / l, n[1] / l, n[2] / l}
+		}
+		for i, v := range [][3]float64{n, a, b, c} {
+			record[i*3], record[i*3+1], record[i*3+2] = float32(v[0]), float32(v[1]), float32(v[2])
+		}
+		binary.Write(bw, binary.LittleEndian, record)
+		binary.Write(bw, binary.LittleEndian, uint16(0))
+	}
+	return bw.Flush()
+}
+
+func sub3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func testHeightMap(t *testing.T) *HeightMap {
+	p := testParams()
+	p.Width, p.Height = 12, 9
+	p.Scale = 3.0 / 12
+	p.Colouring = ColouringSmooth
+	s, err := Iterate(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return s.HeightMap()
+}
+
+func TestHeightMap(t *testing.T) {
+	h := testHeightMap(t)
+	if len(h.Heights) != h.Width*h.Height {
+		t.Fatalf("expected %d heights, got %d", h.Width*h.Height, len(h.Heights))
+	}
+	for i, v := range h.Heights {
+		if v < 0 || v > 1 {
+			t.Fatalf("expected heights from 0 to 1, got %g at %d", v, i)
+		}
+	}
+	// the centre of the view is inside the set, and the corners are far outside it
+	if centre, corner := h.at(h.Width/2, h.Height/2), h.at(0, 0); centre != 1 || corner >= 0.5 {
+		t.Errorf("expected the set to be at the top and the corners low, got %g and %g", centre, corner)
+	}
+
+	img := h.Relief("fire", 20)
+	if img.Bounds().Dx() != h.Width || img.Bounds().Dy() != h.Height {
+		t.Errorf("expected a %dx%d relief, got %v", h.Width, h.Height, img.Bounds())
+	}
+}
+
+func TestHeightMapSolid(t *testing.T) {
+	h := testHeightMap(t)
+	vertices, triangles := h.solid(5, 1)
+
+	// a closed solid uses every edge twice, once in each direction
+	edges := make(map[[2]int]int)
+	for _, tri := range triangles {
+		for i := range tri {
+			edges[[2]int{tri[i], tri[(i+1)%3]}]++
+		}
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]int{e[1], e[0]}] != 1 {
+			t.Fatalf("expected edge %v to be used once in each direction, got %d and %d", e, n, edges[[2]int{e[1], e[0]}])
+		}
+	}
+
+	// triangles wound anticlockwise from outside enclose a positive volume, which is at least that of the base
+	var volume float64
+	for _, tri := range triangles {
+		a, b, c := vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]
+		n := cross(b, c)
+		volume += (a[0]*n[0] + a[1]*n[1] + a[2]*n[2]) / 6
+	}
+	if base := float64((h.Width - 1) * (h.Height - 1)); volume < base {
+		t.Errorf("expected a volume of at least %g, got %g", base, volume)
+	}
+
+	var obj bytes.Buffer
+	if err := h.WriteOBJ(&obj, 5, 1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v, f := strings.Count(obj.String(), "\nv "), strings.Count(obj.String(), "\nf "); v != len(vertices) || f != len(triangles) {
+		t.Errorf("expected %d vertices and %d faces in the OBJ, got %d and %d", len(vertices), len(triangles), v, f)
+	}
+
+	var stl bytes.Buffer
+	if err := h.WriteSTL(&stl, 5, 1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := binary.LittleEndian.Uint32(stl.Bytes()[80:]); int(n) != len(triangles) || stl.Len() != 84+50*len(triangles) {
+		t.Errorf("expected %d triangles in %d bytes of STL, got %d in %d", len(triangles), 84+50*len(triangles), n, stl.Len())
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"math/cmplx"
+)
+
+// the supported interior colouring modes, shading points which never escape
+const (
+	// InteriorFlat leaves the interior a flat colour.
+	InteriorFlat = "flat"
+	// InteriorOrbit shades by the magnitude of the orbit once the iteration limit is reached.
+	InteriorOrbit = "orbit"
+	// InteriorPeriod tints by the period of the cycle the orbit settles into.
+	InteriorPeriod = "period"
+	// InteriorDistance shades by the estimated distance to the boundary of the set. The estimate relies on the
+	// derivative of the mandelbrot formula, so other fractals, custom formulas and Julia sets fall back to InteriorOrbit.
+	InteriorDistance = "distance"
+)
+
+var interiors = []string{InteriorFlat, InteriorOrbit, InteriorPeriod, InteriorDistance}
+
+const (
+	// the longest cycle looked for by period detection
+	maxPeriod = 64
+	// how close an orbit must return to a previous point to be considered periodic
+	periodEpsilon = 1e-10
+	// how close an orbit must return to a previous point to stop iterating it early as a point inside the set. This
+	// is tighter than periodEpsilon as slowly escaping points near the boundary would otherwise be caught too.
+	periodicityEpsilon = 1e-13
+	// newton steps used to refine a point on an attracting cycle before estimating distance
+	cycleRefinements = 8
+)
+
+// Interiors returns the names of the supported interior colouring modes.
+func Interiors() []string {
+	return append([]string(nil), interiors...)
+}
+
+// IsInterior reports whether name is a supported interior colouring mode.
+func IsInterior(name string) bool {
+	for _, i := range interiors {
+		if i == name {
+			return true
+		}
+	}
+	return false
+}
+
+// computes the interior shade of the point c whose orbit reached z at the iteration limit
+func interiorShade(p Params, z, c complex128, iterate formula) float64 {
+	switch p.Interior {
+	case InteriorOrbit:
+		return cmplx.Abs(z) / 2
+
+	case InteriorPeriod:
+		return float64(period(z, c, iterate, p.Exponent))
+
+	case InteriorDistance:
+		if p.Fractal != "mandelbrot" || p.Formula != "" || p.Julia {
+			return cmplx.Abs(z) / 2
+		}
+		// measure in pixels so that the shading looks the same at any zoom level
+		return interiorDistance(z, c) / p.Scale
+	}
+	return 0
+}
+
+// returns the period of the cycle the orbit z has settled into, or 0 if no cycle of at most maxPeriod is found
+func period(z, c complex128, iterate formula, exponent float64) int {
+	w := z
+	for k := 1; k <= maxPeriod; k++ {
+		w = iterate(w, c, exponent)
+		if cmplx.Abs(w-z) < periodEpsilon {
+			return k
+		}
+	}
+	return 0
+}
+
+// estimates the distance from c to the boundary of the mandelbrot set, given a point z of the orbit of c near its
+// attracting cycle. Returns 0 if the cycle can't be found.
+func interiorDistance(z, c complex128) float64 {
+	p := period(z, c, mandelbrot, 2)
+	if p == 0 {
+		return 0
+	}
+
+	// refine z onto the cycle by solving f^p(z) = z with newton's method
+	for i := 0; i < cycleRefinements; i++ {
+		w, dz := z, complex(1, 0)
+		for j := 0; j < p; j++ {
+			dz = 2 * w * dz
+			w = w*w + c
+		}
+		if dz == 1 {
+			break
+		}
+		z -= (w - z) / (dz - 1)
+	}
+
+	// derivatives of f^p with respect to z and c along the cycle
+	var dc, dzdz, dcdz complex128
+	dz := complex(1, 0)
+	for j := 0; j < p; j++ {
+		dcdz = 2 * (z*dcdz + dz*dc)
+		dzdz = 2 * (dz*dz + z*dzdz)
+		dz = 2 * z * dz
+		dc = 2*z*dc + 1
+		z = z*z + c
+	}
+
+	// only attracting cycles give a meaningful estimate
+	m := cmplx.Abs(dz)
+	if m >= 1 {
+		return 0
+	}
+	return (1 - m*m) / cmplx.Abs(dcdz+dzdz*dc/(1-dz))
+}
@@ -0,0 +1,3 @@
+// Package opencl iterates the mandelbrot on an OpenCL device in double precision for the render package. It's only
+// built with the opencl build tag, linking against the system's OpenCL driver, and is empty otherwise.
+package opencl
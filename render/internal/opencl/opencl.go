@@ -0,0 +1,221 @@
+//go:build opencl && cgo
+
+package opencl
+
+/*
+#cgo CFLAGS: -DCL_TARGET_OPENCL_VERSION=120
+#cgo linux LDFLAGS: -lOpenCL
+#cgo windows LDFLAGS: -lOpenCL
+#cgo darwin LDFLAGS: -framework OpenCL
+
+#include <stdlib.h>
+
+#ifdef __APPLE__
+#include <OpenCL/opencl.h>
+#else
+#include <CL/cl.h>
+#endif
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// the kernel iterating the mandelbrot for one sample per work item in double precision. It mirrors the render
+// package's escapeMandelbrot operation for operation, with contraction into fused multiply-adds turned off so that the
+// rounding matches too.
+const kernelSource = `
+#pragma OPENCL EXTENSION cl_khr_fp64 : enable
+#pragma OPENCL FP_CONTRACT OFF
+
+__kernel void escape(__global int *out, const double centreRe, const double centreIm, const double scale,
+		const double limit, const int width, const int height, const int y0, const int n, const int iterations) {
+	int i = get_global_id(0);
+	int pixel = i / (n * n), s = i % (n * n);
+	double px = (double)(pixel % width) + ((double)(s % n) + 0.5) / (double)n;
+	double py = (double)(y0 + pixel / width) + ((double)(s / n) + 0.5) / (double)n;
+	double cr = centreRe + (px - (double)width / 2) * scale;
+	double ci = centreIm + -((py - (double)height / 2) * scale);
+
+	// points in the main cardioid or period 2 bulb never escape
+	double ci2 = ci * ci;
+	double q = (cr - 0.25) * (cr - 0.25) + ci2;
+	if (q * (q + cr - 0.25) <= ci2 / 4 || (cr + 1) * (cr + 1) + ci2 <= 1.0 / 16) {
+		out[i] = iterations;
+		return;
+	}
+
+	double x = 0, y = 0, x2 = 0, y2 = 0;
+	for (int k = 0; k < iterations; k++) {
+		y = 2 * x * y + ci;
+		x = x2 - y2 + cr;
+		x2 = x * x;
+		y2 = y * y;
+		if (x2 + y2 > limit) {
+			out[i] = k;
+			return;
+		}
+	}
+	out[i] = iterations;
+}
+`
+
+// Batch describes a launch of the kernel over the samples of a band of rows of an image
+type Batch struct {
+	Centre            complex128
+	Scale, Limit      float64
+	Width, Height     int
+	Y0, Rows, Samples int
+	Iterations        int
+}
+
+// openCL is the device the kernel runs on, set up the first time it's needed
+var openCL struct {
+	once sync.Once
+	name string
+	err  error
+	// launches share the kernel's arguments, so only one runs at a time
+	mu      sync.Mutex
+	context C.cl_context
+	queue   C.cl_command_queue
+	kernel  C.cl_kernel
+}
+
+// Device returns the name of the OpenCL device, or why there isn't one which supports double precision.
+func Device() (string, error) {
+	openCL.once.Do(func() {
+		openCL.err = initOpenCL()
+	})
+	return openCL.name, openCL.err
+}
+
+// picks the first device which supports double precision, preferring GPUs, and builds the kernel for it
+func initOpenCL() error {
+	var count C.cl_uint
+	if status := C.clGetPlatformIDs(0, nil, &count); status != C.CL_SUCCESS || count == 0 {
+		return fmt.Errorf("no opencl platforms are installed")
+	}
+	platforms := make([]C.cl_platform_id, count)
+	C.clGetPlatformIDs(count, &platforms[0], nil)
+
+	var device C.cl_device_id
+	found := false
+	for _, kind := range []C.cl_device_type{C.CL_DEVICE_TYPE_GPU, C.CL_DEVICE_TYPE_ALL} {
+		for _, platform := range platforms {
+			var n C.cl_uint
+			if C.clGetDeviceIDs(platform, kind, 0, nil, &n) != C.CL_SUCCESS || n == 0 {
+				continue
+			}
+			devices := make([]C.cl_device_id, n)
+			C.clGetDeviceIDs(platform, kind, n, &devices[0], nil)
+			for _, d := range devices {
+				var fp64 C.cl_device_fp_config
+				size := C.size_t(unsafe.Sizeof(fp64))
+				C.clGetDeviceInfo(d, C.CL_DEVICE_DOUBLE_FP_CONFIG, size, unsafe.Pointer(&fp64), nil)
+				if fp64 != 0 && !found {
+					device, found = d, true
+				}
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no opencl device supports double precision")
+	}
+
+	var status C.cl_int
+	context := C.clCreateContext(nil, 1, &device, nil, nil, &status)
+	if status != C.CL_SUCCESS {
+		return clError("clCreateContext", status)
+	}
+	queue := C.clCreateCommandQueue(context, device, 0, &status)
+	if status != C.CL_SUCCESS {
+		return clError("clCreateCommandQueue", status)
+	}
+
+	source := C.CString(kernelSource)
+	defer C.free(unsafe.Pointer(source))
+	program := C.clCreateProgramWithSource(context, 1, &source, nil, &status)
+	if status != C.CL_SUCCESS {
+		return clError("clCreateProgramWithSource", status)
+	}
+	if status := C.clBuildProgram(program, 1, &device, nil, nil, nil); status != C.CL_SUCCESS {
+		return clError("clBuildProgram", status)
+	}
+	entry := C.CString("escape")
+	defer C.free(unsafe.Pointer(entry))
+	kernel := C.clCreateKernel(program, entry, &status)
+	if status != C.CL_SUCCESS {
+		return clError("clCreateKernel", status)
+	}
+
+	var name [256]C.char
+	C.clGetDeviceInfo(device, C.CL_DEVICE_NAME, C.size_t(len(name)), unsafe.Pointer(&name[0]), nil)
+	openCL.name = C.GoString(&name[0])
+	openCL.context, openCL.queue, openCL.kernel = context, queue, kernel
+	return nil
+}
+
+// Escape iterates the samples of a band of rows on the device, returning the iteration each escaped on, or the
+// iteration limit if it didn't, in row-major order with the samples of each pixel together.
+func Escape(batch Batch) ([]int32, error) {
+	if _, err := Device(); err != nil {
+		return nil, err
+	}
+	total := batch.Width * batch.Rows * batch.Samples * batch.Samples
+	escapes := make([]int32, total)
+	size := C.size_t(total * 4)
+
+	openCL.mu.Lock()
+	defer openCL.mu.Unlock()
+
+	var status C.cl_int
+	out := C.clCreateBuffer(openCL.context, C.CL_MEM_WRITE_ONLY, size, nil, &status)
+	if status != C.CL_SUCCESS {
+		return nil, clError("clCreateBuffer", status)
+	}
+	defer C.clReleaseMemObject(out)
+
+	centreRe, centreIm := C.cl_double(real(batch.Centre)), C.cl_double(imag(batch.Centre))
+	scale, limit := C.cl_double(batch.Scale), C.cl_double(batch.Limit)
+	width, height, y0 := C.cl_int(batch.Width), C.cl_int(batch.Height), C.cl_int(batch.Y0)
+	n, iterations := C.cl_int(batch.Samples), C.cl_int(batch.Iterations)
+	args := []struct {
+		size  uintptr
+		value unsafe.Pointer
+	}{
+		{unsafe.Sizeof(out), unsafe.Pointer(&out)},
+		{unsafe.Sizeof(centreRe), unsafe.Pointer(&centreRe)},
+		{unsafe.Sizeof(centreIm), unsafe.Pointer(&centreIm)},
+		{unsafe.Sizeof(scale), unsafe.Pointer(&scale)},
+		{unsafe.Sizeof(limit), unsafe.Pointer(&limit)},
+		{unsafe.Sizeof(width), unsafe.Pointer(&width)},
+		{unsafe.Sizeof(height), unsafe.Pointer(&height)},
+		{unsafe.Sizeof(y0), unsafe.Pointer(&y0)},
+		{unsafe.Sizeof(n), unsafe.Pointer(&n)},
+		{unsafe.Sizeof(iterations), unsafe.Pointer(&iterations)},
+	}
+	for i, arg := range args {
+		status = C.clSetKernelArg(openCL.kernel, C.cl_uint(i), C.size_t(arg.size), arg.value)
+		if status != C.CL_SUCCESS {
+			return nil, clError("clSetKernelArg", status)
+		}
+	}
+
+	global := C.size_t(total)
+	status = C.clEnqueueNDRangeKernel(openCL.queue, openCL.kernel, 1, nil, &global, nil, 0, nil, nil)
+	if status != C.CL_SUCCESS {
+		return nil, clError("clEnqueueNDRangeKernel", status)
+	}
+	status = C.clEnqueueReadBuffer(openCL.queue, out, C.CL_TRUE, 0, size, unsafe.Pointer(&escapes[0]), 0, nil, nil)
+	if status != C.CL_SUCCESS {
+		return nil, clError("clEnqueueReadBuffer", status)
+	}
+	return escapes, nil
+}
+
+func clError(call string, status C.cl_int) error {
+	return fmt.Errorf("opencl: %s failed with opencl error %d", call, int(status))
+}
@@ -0,0 +1,211 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+const (
+	// the polynomial of the newton fractal when Params.Polynomial is empty, z^3 - 1
+	defaultPolynomial = "1, 0, 0, -1"
+	// an orbit has converged once a step moves it less than this
+	newtonTolerance = 1e-9
+)
+
+// polynomial is a polynomial in z with complex coefficients, and its roots
+type polynomial struct {
+	// the coefficients from the highest power down, with a non-zero leading coefficient
+	coefficients []complex128
+	roots        []complex128
+}
+
+var (
+	// polynomials parsed so far, keyed by their source, as each tile of a frame looks its polynomial up again
+	parsedPolynomials   = make(map[string]*polynomial)
+	parsedPolynomialsMu sync.Mutex
+)
+
+// CheckPolynomial reports whether source is a valid Params.Polynomial, returning the problem with it if not.
+func CheckPolynomial(source string) error {
+	_, err := parsePolynomial(source)
+	return err
+}
+
+// parses comma separated coefficients, from the highest power down, into a polynomial and finds its roots. Empty means
+// defaultPolynomial.
+func parsePolynomial(source string) (*polynomial, error) {
+	if source == "" {
+		source = defaultPolynomial
+	}
+	parsedPolynomialsMu.Lock()
+	defer parsedPolynomialsMu.Unlock()
+
+	if poly, ok := parsedPolynomials[source]; ok {
+		return poly, nil
+	}
+	var coefficients []complex128
+	for i, s := range strings.Split(source, ",") {
+		a, err := strconv.ParseComplex(strings.TrimSpace(s), 128)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coefficient %q at position %d", strings.TrimSpace(s), i+1)
+		}
+		// leading zeros don't change the polynomial
+		if a == 0 && len(coefficients) == 0 {
+			continue
+		}
+		coefficients = append(coefficients, a)
+	}
+	if len(coefficients) < 2 {
+		return nil, fmt.Errorf("the polynomial must have a degree of at least one")
+	}
+
+	poly := &polynomial{coefficients: coefficients, roots: findRoots(coefficients)}
+	parsedPolynomials[source] = poly
+	return poly, nil
+}
+
+// evaluates the polynomial and its derivative at z with Horner's method
+func (poly *polynomial) evaluate(z complex128) (value, derivative complex128) {
+	for _, a := range poly.coefficients {
+		derivative = derivative*z + value
+		value = value*z + a
+	}
+	return value, derivative
+}
+
+// returns the index of the root closest to z
+func (poly *polynomial) nearestRoot(z complex128) int {
+	nearest, best := 0, math.Inf(1)
+	for i, r := range poly.roots {
+		if d := cmplx.Abs(z - r); d < best {
+			nearest, best = i, d
+		}
+	}
+	return nearest
+}
+
+// finds every root of the polynomial with the given coefficients, leading coefficient first, using the Durand-Kerner
+// method, which refines guesses at all of them at once
+func findRoots(coefficients []complex128) []complex128 {
+	// Durand-Kerner needs a monic polynomial, which has the same roots
+	monic := make([]complex128, len(coefficients))
+	for i, a := range coefficients {
+		monic[i] = a / coefficients[0]
+	}
+	poly := polynomial{coefficients: monic}
+
+	// the guesses are conventionally started on powers of a number which is neither real nor a root of unity
+	roots := make([]complex128, len(monic)-1)
+	guess := complex128(1)
+	for i := range roots {
+		roots[i] = guess
+		guess *= 0.4 + 0.9i
+	}
+	for iteration := 0; iteration < 1000; iteration++ {
+		largest := 0.0
+		for i, r := range roots {
+			value, _ := poly.evaluate(r)
+			denominator := complex128(1)
+			for j, s := range roots {
+				if j != i {
+					denominator *= r - s
+				}
+			}
+			if denominator == 0 {
+				continue
+			}
+			step := value / denominator
+			roots[i] -= step
+			largest = math.Max(largest, cmplx.Abs(step))
+		}
+		if largest < 1e-14 {
+			break
+		}
+	}
+	return roots
+}
+
+// iterates newton's method for the polynomial of p from the point c until it converges on a root or the iteration
+// limit is reached, gathering the escape data in needs. Points which converge count as having escaped on the iteration
+// they converged on, with Sample.Root recording which root they converged to.
+func escapeNewton(c complex128, p Params, poly *polynomial, needs Needs) Sample {
+	var distance func(z complex128) float64
+	var trap float64
+	if needs&NeedsTrap != 0 {
+		distance = lookupTrap(p.Trap)
+		trap = math.Inf(1)
+	}
+
+	z, previousStep := c, math.Inf(1)
+	for n := 0; n < p.Iterations; n++ {
+		value, derivative := poly.evaluate(z)
+		// the method is undefined at stationary points, so such orbits never converge
+		if derivative == 0 {
+			break
+		}
+		step := value / derivative
+		z -= step
+		if distance != nil {
+			trap = math.Min(trap, distance(z))
+		}
+
+		size := cmplx.Abs(step)
+		if size < newtonTolerance {
+			s := Sample{N: n, Root: poly.nearestRoot(z), Trap: trap}
+			if needs&NeedsSmooth != 0 {
+				s.Smooth = smoothConvergence(n, size, previousStep)
+			}
+			return s
+		}
+		// orbits thrown off to infinity never come back
+		if math.IsNaN(size) || math.IsInf(size, 0) {
+			break
+		}
+		previousStep = size
+	}
+	return Sample{N: p.Iterations, Trap: trap}
+}
+
+// returns the fractional iteration an orbit converged on, given the size of the step on iteration n which fell below
+// the tolerance and of the step before it. It interpolates where between the two steps the tolerance was crossed, on
+// a logarithmic scale, so it runs continuously from one iteration to the next.
+func smoothConvergence(n int, step, previousStep float64) float64 {
+	if step <= 0 || math.IsInf(previousStep, 1) || previousStep <= step {
+		return float64(n)
+	}
+	t := math.Log(previousStep/newtonTolerance) / math.Log(previousStep/step)
+	return math.Max(0, float64(n)-1+t)
+}
+
+// rootsColourer implements ColouringRoots
+type rootsColourer struct{}
+
+func (rootsColourer) Needs() Needs {
+	return NeedsSmooth
+}
+
+func (rootsColourer) Colour(p Params, _ []int) func(s Sample) color.RGBA {
+	gradient := lookupGradient(p.Palette)
+	roots := 1
+	if p.isNewton() {
+		poly, _ := parsePolynomial(p.Polynomial)
+		roots = len(poly.roots)
+	}
+	// the contrast sets how quickly the colours darken, at the same rate as ColouringSmooth moves through the palette
+	falloff := float64(p.contrast()) / 256
+	return func(s Sample) color.RGBA {
+		if !s.Escaped(p) {
+			return palette.Interior
+		}
+		c := gradient.At(float64(s.Root) / float64(roots))
+		f := math.Exp(-s.Smooth * falloff)
+		return color.RGBA{R: uint8(float64(c.R) * f), G: uint8(float64(c.G) * f), B: uint8(float64(c.B) * f), A: c.A}
+	}
+}
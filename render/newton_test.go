@@ -0,0 +1,80 @@
+package render
+
+import (
+	"context"
+	"math/cmplx"
+	"testing"
+)
+
+func TestParsePolynomial(t *testing.T) {
+	tests := []struct {
+		polynomial string
+		want       []complex128
+	}{
+		{polynomial: "", want: []complex128{1, complex(-0.5, 0.8660254037844386), complex(-0.5, -0.8660254037844386)}},
+		{polynomial: "1, 0, 1", want: []complex128{1i, -1i}},
+		{polynomial: "0, 2, -4", want: []complex128{2}},
+		{polynomial: "1, -3i, -2", want: []complex128{1i, 2i}},
+	}
+	for _, tt := range tests {
+		poly, err := parsePolynomial(tt.polynomial)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tt.polynomial, err)
+			continue
+		}
+		if len(poly.roots) != len(tt.want) {
+			t.Errorf("expected %q to have %d roots, got %v", tt.polynomial, len(tt.want), poly.roots)
+			continue
+		}
+		// the roots are found in no particular order
+		for _, want := range tt.want {
+			if r := poly.roots[poly.nearestRoot(want)]; cmplx.Abs(r-want) > 1e-9 {
+				t.Errorf("expected %q to have a root at %v, got %v", tt.polynomial, want, poly.roots)
+			}
+		}
+	}
+
+	for _, polynomial := range []string{"1", "0, 3", "1, z", "1,, 2"} {
+		if err := CheckPolynomial(polynomial); err == nil {
+			t.Errorf("expected an error parsing %q", polynomial)
+		}
+	}
+}
+
+func TestNewton(t *testing.T) {
+	p := testParams()
+	p.Fractal, p.Centre, p.Colouring = "newton", 0, ColouringRoots
+	poly, err := parsePolynomial(p.Polynomial)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// points close to a root converge on it almost immediately
+	for _, r := range poly.roots {
+		s := escapeNewton(r+complex(0.01, 0.01), p, poly, p.needs())
+		if !s.Escaped(p) || s.N > 5 || poly.roots[s.Root] != r {
+			t.Errorf("expected the point next to %v to converge on it quickly, got root %v after %d iterations",
+				r, poly.roots[s.Root], s.N)
+		}
+	}
+	// the origin is a stationary point of z^3 - 1, where the method is undefined
+	if s := escapeNewton(0, p, poly, p.needs()); s.Escaped(p) {
+		t.Errorf("expected the origin not to converge, got root %v after %d iterations", poly.roots[s.Root], s.N)
+	}
+
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the basins of the three roots get three distinct hues
+	right, top, bottom := img.RGBAAt(p.Width-2, p.Height/2), img.RGBAAt(1, 1), img.RGBAAt(1, p.Height-2)
+	if right == top || right == bottom || top == bottom {
+		t.Errorf("expected the basins of different roots to be coloured differently, got %v, %v and %v", right, top,
+			bottom)
+	}
+
+	p.Polynomial = "1, x"
+	if _, err := Render(context.Background(), p); err == nil {
+		t.Errorf("expected an error rendering an invalid polynomial")
+	}
+}
@@ -0,0 +1,74 @@
+package render
+
+import "context"
+
+// how many samples each launch of the OpenCL kernel iterates at most, so that long renders can be cancelled between
+// launches
+const openCLBatchSamples = 1 << 20
+
+// openCLBatch describes a launch of the kernel over the samples of a band of rows of an image
+type openCLBatch struct {
+	centre            complex128
+	scale, limit      float64
+	width, height     int
+	y0, rows, samples int
+	iterations        int
+}
+
+// HasOpenCL reports whether an OpenCL device which supports double precision is available for Params.OpenCL to iterate
+// on. It's always false unless built with the opencl build tag.
+func HasOpenCL() bool {
+	_, err := openCLDevice()
+	return err == nil
+}
+
+// OpenCLDevice returns the name of the device Params.OpenCL iterates on, or an error saying why there isn't one.
+func OpenCLDevice() (string, error) {
+	return openCLDevice()
+}
+
+// reports whether iterateOpenCL can be used in place of escapeMandelbrot
+func hasOpenCLPath(p Params, needs Needs) bool {
+	flat := p.Interior == "" || p.Interior == InteriorFlat
+	return p.OpenCL && hasFastPath(p) && needs&NeedsSmooth == 0 && flat && HasOpenCL()
+}
+
+// iterates every sample of the image described by p on the OpenCL device, a band of rows per launch of the kernel
+func iterateOpenCL(ctx context.Context, p Params) ([]Sample, error) {
+	n := p.samplesPerAxis()
+	count := n * n
+	rows := openCLBatchSamples / (p.Width * count)
+	if rows < 1 {
+		rows = 1
+	}
+
+	samples := make([]Sample, p.Width*p.Height*count)
+	for y0 := 0; y0 < p.Height; y0 += rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch := openCLBatch{
+			centre:     p.Centre,
+			scale:      p.Scale,
+			limit:      newBailout(p).limit,
+			width:      p.Width,
+			height:     p.Height,
+			y0:         y0,
+			rows:       rows,
+			samples:    n,
+			iterations: p.Iterations,
+		}
+		if y0+rows > p.Height {
+			batch.rows = p.Height - y0
+		}
+		escapes, err := openCLEscape(batch)
+		if err != nil {
+			return nil, err
+		}
+		band := samples[y0*p.Width*count:]
+		for i, e := range escapes {
+			band[i] = Sample{N: int(e)}
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,23 @@
+//go:build opencl && cgo
+
+package render
+
+import "github.com/jemgunay/mandelbrot/render/internal/opencl"
+
+func openCLDevice() (string, error) {
+	return opencl.Device()
+}
+
+func openCLEscape(batch openCLBatch) ([]int32, error) {
+	return opencl.Escape(opencl.Batch{
+		Centre:     batch.centre,
+		Scale:      batch.scale,
+		Limit:      batch.limit,
+		Width:      batch.width,
+		Height:     batch.height,
+		Y0:         batch.y0,
+		Rows:       batch.rows,
+		Samples:    batch.samples,
+		Iterations: batch.iterations,
+	})
+}
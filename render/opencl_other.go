@@ -0,0 +1,16 @@
+//go:build !opencl || !cgo
+
+package render
+
+import "errors"
+
+// only builds with the opencl tag link against an OpenCL driver
+var errNoOpenCL = errors.New("built without opencl support, rebuild with -tags opencl")
+
+func openCLDevice() (string, error) {
+	return "", errNoOpenCL
+}
+
+func openCLEscape(batch openCLBatch) ([]int32, error) {
+	return nil, errNoOpenCL
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenCL(t *testing.T) {
+	p := testParams()
+	p.Width, p.Height, p.Samples = 63, 47, 2
+	p.Iterations = 500
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// without a device the cpu iterates instead, so the samples are the same either way
+	p.OpenCL = true
+	if hasOpenCLPath(p, p.needs()) != HasOpenCL() {
+		t.Fatalf("expected the opencl path to be used only when there's a device")
+	}
+	got, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for name, modify := range map[string]func(p *Params){
+		"fractal":  func(p *Params) { p.Fractal = "tricorn" },
+		"smooth":   func(p *Params) { p.Colouring = ColouringSmooth },
+		"interior": func(p *Params) { p.Interior = InteriorOrbit },
+	} {
+		q := p
+		modify(&q)
+		if hasOpenCLPath(q, q.needs()) {
+			t.Errorf("expected no opencl path with a different %s", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Orbit returns the orbit of the point c under the fractal of p: the points z the iteration visits, from the start of
+// the orbit up to and including the first to escape, or p.Iterations points after the start if it never does. The
+// newton fractal's orbit is the path newton's method takes towards a root, ending once it converges. Julia sets start
+// the orbit at c and iterate with Seed instead. p must be valid.
+func Orbit(p Params, c complex128) []complex128 {
+	if p.isNewton() {
+		poly, _ := parsePolynomial(p.Polynomial)
+		return newtonOrbit(c, p, poly)
+	}
+
+	var z complex128
+	if p.Julia {
+		z, c = c, p.Seed
+	}
+	iterate := p.formula()
+	bailout := newBailout(p)
+	orbit := []complex128{z}
+	for n := 0; n < p.Iterations; n++ {
+		z = iterate(z, c, p.Exponent)
+		// points thrown off to infinity can't be drawn
+		if cmplx.IsNaN(z) || cmplx.IsInf(z) {
+			break
+		}
+		orbit = append(orbit, z)
+		if bailout.escaped(z) {
+			break
+		}
+	}
+	return orbit
+}
+
+// returns the points newton's method visits from c, as iterated by escapeNewton
+func newtonOrbit(c complex128, p Params, poly *polynomial) []complex128 {
+	z := c
+	orbit := []complex128{z}
+	for n := 0; n < p.Iterations; n++ {
+		value, derivative := poly.evaluate(z)
+		if derivative == 0 {
+			break
+		}
+		step := value / derivative
+		size := cmplx.Abs(step)
+		if math.IsNaN(size) || math.IsInf(size, 0) {
+			break
+		}
+		z -= step
+		orbit = append(orbit, z)
+		if size < newtonTolerance {
+			break
+		}
+	}
+	return orbit
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestOrbit(t *testing.T) {
+	p := Params{Iterations: 6, Fractal: "mandelbrot"}
+	tests := []struct {
+		name   string
+		modify func(p *Params)
+		c      complex128
+		want   []complex128
+	}{
+		{name: "escapes", modify: func(p *Params) {}, c: 1, want: []complex128{0, 1, 2, 5, 26}},
+		{name: "periodic", modify: func(p *Params) {}, c: -1, want: []complex128{0, -1, 0, -1, 0, -1, 0}},
+		{name: "julia", modify: func(p *Params) { p.Julia, p.Seed = true, -1 }, c: 1i,
+			want: []complex128{1i, -2, 3, 8, 63}},
+		{name: "burning ship", modify: func(p *Params) { p.Fractal = "burning-ship" }, c: 1i,
+			want: []complex128{0, 1i, -1 + 1i, 3i, -9 + 1i, 80 + 19i}},
+		{name: "formula", modify: func(p *Params) { p.Formula = "z^3 + c" }, c: 1, want: []complex128{0, 1, 2, 9, 730}},
+	}
+	for _, tt := range tests {
+		q := p
+		tt.modify(&q)
+		got := Orbit(q, tt.c)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected orbit %v, got %v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if cmplx.Abs(got[i]-tt.want[i]) > 1e-12 {
+				t.Errorf("%s: expected orbit %v, got %v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+
+	// the orbit ends on the iteration escape reports the point escaping on
+	p.Iterations = 200
+	for _, c := range []complex128{complex(-0.75, 0.1), complex(0.5, 0.5), complex(-1.8, 0.01)} {
+		s := escape(c, p, mandelbrot, 0)
+		if got := len(Orbit(p, c)); !s.Escaped(p) || got != s.N+2 {
+			t.Errorf("expected the orbit of %v to escape after %d points, got %d", c, s.N+2, got)
+		}
+	}
+}
+
+func TestNewtonOrbit(t *testing.T) {
+	p := Params{Iterations: 50, Fractal: "newton"}
+	orbit := Orbit(p, 2)
+	if end := orbit[len(orbit)-1]; cmplx.Abs(end-1) > 1e-6 {
+		t.Errorf("expected the orbit of 2 to converge on the root 1, ended at %v after %d points", end, len(orbit))
+	}
+	// the method is undefined at the origin, where the derivative of z^3 - 1 vanishes
+	if orbit := Orbit(p, 0); len(orbit) != 1 {
+		t.Errorf("expected the orbit of 0 to stop at its start, got %v", orbit)
+	}
+}
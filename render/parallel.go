@@ -0,0 +1,94 @@
+package render
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the width and height in pixels of the tiles images are divided into for iterating in parallel. They're small enough
+// that an expensive region of the set is spread across many tiles, which keeps every worker busy until the end.
+const workTileSize = 16
+
+// WorkStats describes how busy the workers iterating images in parallel have been.
+type WorkStats struct {
+	// the total time the workers spent iterating, and the time they could have spent had none of them sat idle
+	Busy, Available time.Duration
+}
+
+// Sub returns the work done since the stats prev were read.
+func (s WorkStats) Sub(prev WorkStats) WorkStats {
+	return WorkStats{Busy: s.Busy - prev.Busy, Available: s.Available - prev.Available}
+}
+
+// Utilisation returns the fraction of the time available that the workers were busy, from 0 to 1.
+func (s WorkStats) Utilisation() float64 {
+	if s.Available <= 0 {
+		return 0
+	}
+	return float64(s.Busy) / float64(s.Available)
+}
+
+var (
+	// the worker stats gathered across every image iterated
+	workStats   WorkStats
+	workStatsMu sync.Mutex
+)
+
+// ReadWorkStats returns how busy the workers have been across every image iterated so far. Subtracting stats read
+// earlier gives how busy they've been since.
+func ReadWorkStats() WorkStats {
+	workStatsMu.Lock()
+	defer workStatsMu.Unlock()
+	return workStats
+}
+
+// works through each tile of a width x height image across runtime.GOMAXPROCS workers. Rather than each worker being
+// handed an equal share of the image up front, which leaves most of them idle while those given the interior of the
+// set finish, workers take the next tile from a shared queue whenever they finish one. newWorker is called once per
+// worker to create the func it works on its tiles with, so that each can keep its own buffers. Workers stop taking
+// tiles once ctx is cancelled, returning its error.
+func forEachTile(ctx context.Context, width, height int, newWorker func() func(tile image.Rectangle)) error {
+	columns := (width + workTileSize - 1) / workTileSize
+	tiles := columns * ((height + workTileSize - 1) / workTileSize)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > tiles {
+		workers = tiles
+	}
+
+	// the queue is the index of the next tile to be taken, in row-major order so that images fill in from the top
+	var next, busy int64 = -1, 0
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		work := newWorker()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerStart := time.Now()
+			defer func() {
+				atomic.AddInt64(&busy, int64(time.Since(workerStart)))
+			}()
+			for ctx.Err() == nil {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= tiles {
+					return
+				}
+				corner := image.Pt(i%columns*workTileSize, i/columns*workTileSize)
+				tile := image.Rectangle{Min: corner, Max: corner.Add(image.Pt(workTileSize, workTileSize))}
+				work(tile.Intersect(image.Rect(0, 0, width, height)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// workers are busy from starting until they run out of tiles, so the rest of the time they were idle
+	workStatsMu.Lock()
+	workStats.Busy += time.Duration(busy)
+	workStats.Available += time.Since(start) * time.Duration(workers)
+	workStatsMu.Unlock()
+	return ctx.Err()
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestForEachTile(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// sizes which aren't a multiple of the tile size leave partial tiles along the right and bottom
+	width, height := 3*workTileSize+5, 2*workTileSize+1
+	var mu sync.Mutex
+	counts := make([]int, width*height)
+	workers := 0
+	before := ReadWorkStats()
+	err := forEachTile(context.Background(), width, height, func() func(tile image.Rectangle) {
+		workers++
+		return func(tile image.Rectangle) {
+			mu.Lock()
+			defer mu.Unlock()
+			for y := tile.Min.Y; y < tile.Max.Y; y++ {
+				for x := tile.Min.X; x < tile.Max.X; x++ {
+					counts[y*width+x]++
+				}
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if workers != 4 {
+		t.Errorf("expected 4 workers, got %d", workers)
+	}
+	if stats := ReadWorkStats().Sub(before); stats.Busy <= 0 || stats.Busy > stats.Available {
+		t.Errorf("expected the workers to be busy for some but not more than all of the time available, got %+v", stats)
+	}
+	for i, n := range counts {
+		if n != 1 {
+			t.Fatalf("expected every pixel to be worked on once, got %d times for (%d, %d)", n, i%width, i/width)
+		}
+	}
+
+	// a single tile only needs a single worker
+	workers = 0
+	forEachTile(context.Background(), workTileSize, workTileSize, func() func(tile image.Rectangle) {
+		workers++
+		return func(image.Rectangle) {}
+	})
+	if workers != 1 {
+		t.Errorf("expected 1 worker for a single tile, got %d", workers)
+	}
+}
+
+func TestForEachTileCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := forEachTile(ctx, 100, 100, func() func(tile image.Rectangle) {
+		return func(image.Rectangle) {
+			t.Errorf("expected no tiles to be worked on once cancelled")
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"math"
+	"math/big"
+)
+
+// the bits of precision the reference orbit is computed with beyond those needed to tell its pixels apart, absorbing
+// the rounding errors which build up over its iterations
+const referenceGuardBits = 64
+
+// the reference orbit of perturbation, the orbit of the centre of the image rounded to double precision after each
+// iteration. It starts at zero and runs until it escapes or reaches the iteration limit.
+type referenceOrbit []complex128
+
+// iterates the orbit of the centre of the image described by p in enough precision to resolve its pixels
+func newReferenceOrbit(p Params) referenceOrbit {
+	prec := uint(53+math.Max(0, -math.Log2(p.relativeSpacing()))) + referenceGuardBits
+	newFloat := func(f float64) *big.Float {
+		return new(big.Float).SetPrec(prec).SetFloat64(f)
+	}
+	cr, ci := newFloat(real(p.Centre)), newFloat(imag(p.Centre))
+	x, y, x2, y2, xy := newFloat(0), newFloat(0), newFloat(0), newFloat(0), newFloat(0)
+
+	limit := newBailout(p).limit
+	orbit := referenceOrbit{0}
+	for n := 0; n < p.Iterations; n++ {
+		// y = 2*x*y + ci, x = x*x - y*y + cr
+		xy.Mul(x, y)
+		y.Add(xy.Add(xy, xy), ci)
+		x.Add(x.Sub(x2, y2), cr)
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+
+		re, _ := x.Float64()
+		im, _ := y.Float64()
+		orbit = append(orbit, complex(re, im))
+		if re*re+im*im > limit {
+			break
+		}
+	}
+	return orbit
+}
+
+// iterates the point offset d from the centre of the image as a perturbation of the reference orbit. The difference
+// dz from the reference is iterated rather than z itself, which keeps it small enough to be precise in double
+// precision: with Z the reference and dc the offset of the point, z = Z + dz evolves as dz = 2*Z*dz + dz*dz + dc.
+//
+// Whenever z comes closer to zero than dz, or the reference orbit runs out because the centre escaped, the orbit is
+// rebased onto the start of the reference, carrying on with z itself as the difference from Z = 0. This avoids the
+// glitches a single reference otherwise suffers where the point's orbit strays too far from it.
+func escapePerturbed(d complex128, p Params, orbit referenceOrbit, needs Needs) Sample {
+	limit := newBailout(p).limit
+	var z, dz complex128
+	ref := 0
+	for n := 0; n < p.Iterations; n++ {
+		dz = 2*orbit[ref]*dz + dz*dz + d
+		ref++
+		z = orbit[ref] + dz
+
+		r := real(z)*real(z) + imag(z)*imag(z)
+		if r > limit {
+			if needs&NeedsSmooth != 0 {
+				return Sample{N: n, Smooth: smoothIteration(p, n, z)}
+			}
+			return Sample{N: n}
+		}
+		if r < real(dz)*real(dz)+imag(dz)*imag(dz) || ref == len(orbit)-1 {
+			dz, ref = z, 0
+		}
+	}
+	return Sample{N: p.Iterations, Shade: interiorShade(p, z, p.Centre+d, mandelbrot)}
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPerturbation(t *testing.T) {
+	// shallow enough for double precision to resolve, so both should agree
+	p := testParams()
+	p.Centre, p.Scale, p.Iterations = complex(-0.743643887037151, 0.13182590420533), 1e-9, 3000
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.Precision = PrecisionPerturbation
+	got, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the odd point close to the boundary of the set escapes on a different iteration due to rounding
+	differ := 0
+	for i := range want {
+		if got[i].N != want[i].N {
+			differ++
+		}
+	}
+	if differ > len(want)/20 {
+		t.Errorf("expected at most 5%% of samples to differ from double precision, got %d of %d", differ, len(want))
+	}
+
+	// far beyond double precision, points several pixels apart round to the same point, so only perturbation tells them
+	// apart
+	p.Precision, p.Scale, p.Iterations, p.Colouring = "", 1e-20, 8000, ColouringSmooth
+	if got := p.SelectedPrecision(); got != PrecisionPerturbation {
+		t.Fatalf("expected perturbation to be picked for a deep zoom, got %s", got)
+	}
+	distinct := func(p Params) int {
+		samples, err := iterateSamples(context.Background(), p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		values := make(map[float64]bool)
+		for _, s := range samples {
+			values[s.Smooth] = true
+		}
+		return len(values)
+	}
+	perturbed := distinct(p)
+	p.Precision = PrecisionFloat64
+	if rounded := distinct(p); perturbed < len(want)/2 || rounded > perturbed/10 {
+		t.Errorf("expected perturbation to resolve far more distinct points than double precision, got %d and %d",
+			perturbed, rounded)
+	}
+}
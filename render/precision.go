@@ -0,0 +1,97 @@
+package render
+
+import "math"
+
+// the arithmetic points can be iterated with, from the cheapest to the most precise
+const (
+	// PrecisionAuto picks the cheapest of the other precisions which resolves the pixels of each image.
+	PrecisionAuto = "auto"
+	// PrecisionFloat32 iterates eight points at once in single precision with AVX2 vector instructions, twice as many
+	// as double precision fits in a vector. It's only used alongside Params.SIMD, as single precision is no faster
+	// than double when iterating one point at a time.
+	PrecisionFloat32 = "float32"
+	// PrecisionFloat64 iterates in double precision.
+	PrecisionFloat64 = "float64"
+	// PrecisionPerturbation iterates a single reference orbit through the centre of the image in arbitrary precision,
+	// then each point in double precision as a small perturbation of it, so detail is resolved far beyond the limits
+	// of double precision. It's only used for the mandelbrot with a euclidean bailout.
+	PrecisionPerturbation = "perturbation"
+)
+
+// the smallest pixel spacing, relative to the magnitude of the coordinates, that float32 arithmetic can resolve before
+// neighbouring pixels collapse into blocks
+const float32MinRelativeSpacing = 1.0 / (1 << 20)
+
+// Float64MinRelativeSpacing is the smallest pixel spacing, relative to the magnitude of the coordinates, that float64
+// arithmetic can resolve before neighbouring pixels collapse into blocks. Deeper views need PrecisionPerturbation.
+const Float64MinRelativeSpacing = 1.0 / (1 << 44)
+
+var precisions = []string{PrecisionAuto, PrecisionFloat32, PrecisionFloat64, PrecisionPerturbation}
+
+// Precisions returns the names of the arithmetic precisions the image can be iterated with.
+func Precisions() []string {
+	return append([]string(nil), precisions...)
+}
+
+// IsPrecision reports whether name is the name of a supported precision.
+func IsPrecision(name string) bool {
+	for _, n := range precisions {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectedPrecision returns the precision the image described by p is iterated with: its Precision if that's set and
+// supported by the rest of p, otherwise the cheapest supported precision which resolves its pixels. Precisions which
+// aren't supported fall back to PrecisionFloat64.
+func (p Params) SelectedPrecision() string {
+	switch {
+	case p.Precision == PrecisionFloat32 && p.supportsFloat32(), p.Precision == PrecisionFloat64:
+		return p.Precision
+	case p.Precision == PrecisionPerturbation && p.Perturbable():
+		return p.Precision
+	case p.Precision != "" && p.Precision != PrecisionAuto:
+		return PrecisionFloat64
+	}
+
+	spacing := p.relativeSpacing()
+	if spacing >= float32MinRelativeSpacing && p.supportsFloat32() {
+		return PrecisionFloat32
+	}
+	if spacing < Float64MinRelativeSpacing && p.Perturbable() {
+		return PrecisionPerturbation
+	}
+	return PrecisionFloat64
+}
+
+// Perturbable reports whether the image described by p can be iterated with PrecisionPerturbation.
+func (p Params) Perturbable() bool {
+	return hasFastPath(p)
+}
+
+// Resolves reports whether the precision the image described by p is iterated with tells its neighbouring pixels
+// apart. Images which it doesn't are drawn as blocks of identical pixels, e.g. when zoomed in beyond double precision
+// with a fractal which can't be perturbed or with the precision forced to PrecisionFloat64.
+func (p Params) Resolves() bool {
+	switch p.SelectedPrecision() {
+	case PrecisionFloat32:
+		return p.relativeSpacing() >= float32MinRelativeSpacing
+	case PrecisionFloat64:
+		return p.relativeSpacing() >= Float64MinRelativeSpacing
+	}
+	return true
+}
+
+func (p Params) supportsFloat32() bool {
+	return hasSIMDPath(p, p.needs())
+}
+
+// returns the spacing between pixels relative to the magnitude of the coordinates of the image, or to one for images
+// near the origin
+func (p Params) relativeSpacing() float64 {
+	halfWidth, halfHeight := float64(p.Width)/2*p.Scale, float64(p.Height)/2*p.Scale
+	magnitude := math.Max(math.Abs(real(p.Centre))+halfWidth, math.Abs(imag(p.Centre))+halfHeight)
+	return p.Scale / math.Max(magnitude, 1)
+}
@@ -0,0 +1,31 @@
+package render
+
+import "testing"
+
+func TestResolves(t *testing.T) {
+	tests := []struct {
+		name      string
+		fractal   string
+		precision string
+		scale     float64
+		want      bool
+	}{
+		{name: "shallow", fractal: "mandelbrot", scale: 1e-6, want: true},
+		{name: "deep auto", fractal: "mandelbrot", scale: 1e-20, want: true},
+		{name: "deep perturbation", fractal: "mandelbrot", precision: PrecisionPerturbation, scale: 1e-20, want: true},
+		{name: "deep float64", fractal: "mandelbrot", precision: PrecisionFloat64, scale: 1e-20, want: false},
+		{name: "deep unperturbable", fractal: "burning-ship", scale: 1e-20, want: false},
+		{name: "float32 at float64 depth", fractal: "mandelbrot", precision: PrecisionFloat32, scale: 1e-10, want: false},
+	}
+	for _, tt := range tests {
+		if tt.precision == PrecisionFloat32 && !HasSIMD() {
+			// single precision is only used with avx2
+			continue
+		}
+		p := testParams()
+		p.Fractal, p.Precision, p.Scale, p.SIMD = tt.fractal, tt.precision, tt.scale, true
+		if got := p.Resolves(); got != tt.want {
+			t.Errorf("%s: expected Resolves() = %t with %s, got %t", tt.name, tt.want, p.SelectedPrecision(), got)
+		}
+	}
+}
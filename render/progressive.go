@@ -0,0 +1,208 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// the iteration limit of the first pass of a progressive render, which is doubled for each pass after it
+	progressiveFirstPass = 64
+	// the samples in each of the runs the samples of a progressive render are dealt out to its workers in, so that
+	// neighbouring runs of similar cost are spread across the workers
+	progressiveRun = 64
+	// how many iterations a sample is iterated for between checks of the time
+	progressiveCheck = 1024
+)
+
+// Progressive iterates an image in slices of time, keeping the orbit of every sample between slices so that each
+// slice carries on where the last one stopped. It iterates in passes which double the iteration limit each time, so
+// the picture improves continuously: first the bands far from the set appear, then those ever closer to it. Samples
+// which haven't escaped yet are coloured as interior until they do.
+//
+// Only the images CanProgress reports can be iterated progressively.
+type Progressive struct {
+	p       Params
+	samples []Sample
+	// the orbit of each sample so far, the iterations it has survived, and whether it's still being iterated
+	x, y   []float64
+	n      []int
+	active []bool
+	// the iteration limit of the current pass and how many passes came before it
+	depth, pass int
+	// how far through its share of the samples each worker is in the current pass
+	cursors []int
+	done    bool
+}
+
+// CanProgress reports whether the image described by p can be iterated by a Progressive: the mandelbrot with a
+// euclidean bailout, a flat interior, colourings which need nothing beyond the fractional escape iteration, and within
+// double precision, as the orbits of perturbation aren't kept.
+func CanProgress(p Params) bool {
+	flat := p.Interior == "" || p.Interior == InteriorFlat
+	return hasFastPath(p) && flat && !p.SampleEdges && p.SelectedPrecision() != PrecisionPerturbation
+}
+
+// NewProgressive prepares the image described by p for iterating progressively. It fails if p isn't valid or can't be
+// iterated progressively.
+func NewProgressive(p Params) (*Progressive, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if !CanProgress(p) {
+		return nil, fmt.Errorf("the image can't be iterated progressively")
+	}
+
+	n := p.samplesPerAxis()
+	count := p.Width * p.Height * n * n
+	pr := &Progressive{
+		p:       p,
+		samples: make([]Sample, count),
+		x:       make([]float64, count),
+		y:       make([]float64, count),
+		n:       make([]int, count),
+		active:  make([]bool, count),
+		depth:   progressiveFirstPass,
+		cursors: make([]int, runtime.GOMAXPROCS(0)),
+	}
+	if pr.depth > p.Iterations {
+		pr.depth = p.Iterations
+	}
+	for i := range pr.samples {
+		// samples are interior until they escape
+		pr.samples[i] = Sample{N: p.Iterations}
+		c := pr.point(i)
+		pr.active[i] = !inCardioidOrBulb(real(c), imag(c))
+	}
+	return pr, nil
+}
+
+// returns the point on the plane of the sample at index i, spread across its pixel as iterateSamples spreads them
+func (pr *Progressive) point(i int) complex128 {
+	n := pr.p.samplesPerAxis()
+	pixel, s := i/(n*n), i%(n*n)
+	ox, oy := (float64(s%n)+0.5)/float64(n), (float64(s/n)+0.5)/float64(n)
+	return pr.p.PixelToPlane(float64(pixel%pr.p.Width)+ox, float64(pixel/pr.p.Width)+oy)
+}
+
+// Step iterates the image for up to budget, reporting whether it has been iterated to the iteration limit. It returns
+// ctx's error if ctx is cancelled, leaving the image iterated as far as it got.
+func (pr *Progressive) Step(ctx context.Context, budget time.Duration) (bool, error) {
+	deadline := time.Now().Add(budget)
+	for !pr.done {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		var wg sync.WaitGroup
+		finished := make([]bool, len(pr.cursors))
+		for w := range pr.cursors {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				finished[w] = pr.work(ctx, w, deadline)
+			}(w)
+		}
+		wg.Wait()
+
+		for _, f := range finished {
+			if !f {
+				return false, ctx.Err()
+			}
+		}
+		// every worker has finished the pass, so start the next one with twice the iterations
+		if pr.depth == pr.p.Iterations {
+			pr.done = true
+			break
+		}
+		if pr.depth *= 2; pr.depth > pr.p.Iterations {
+			pr.depth = pr.p.Iterations
+		}
+		pr.pass++
+		for w := range pr.cursors {
+			pr.cursors[w] = 0
+		}
+	}
+	return true, nil
+}
+
+// iterates worker w's share of the samples up to the depth of the current pass until the deadline passes or ctx is
+// cancelled, reporting whether it finished its share
+func (pr *Progressive) work(ctx context.Context, w int, deadline time.Time) bool {
+	workers := len(pr.cursors)
+	limit := newBailout(pr.p).limit
+	needs := pr.p.needs()
+	for k := pr.cursors[w]; ; k++ {
+		i := (k/progressiveRun*workers+w)*progressiveRun + k%progressiveRun
+		if i >= len(pr.samples) {
+			pr.cursors[w] = k
+			return true
+		}
+		if !pr.active[i] {
+			continue
+		}
+
+		c := pr.point(i)
+		cr, ci := real(c), imag(c)
+		x, y, n := pr.x[i], pr.y[i], pr.n[i]
+		x2, y2 := x*x, y*y
+		for n < pr.depth {
+			y = 2*x*y + ci
+			x = x2 - y2 + cr
+			x2, y2 = x*x, y*y
+			if x2+y2 > limit {
+				pr.samples[i] = Sample{N: n}
+				if needs&NeedsSmooth != 0 {
+					pr.samples[i].Smooth = smoothIteration(pr.p, n, complex(x, y))
+				}
+				pr.active[i] = false
+				break
+			}
+			n++
+
+			// stop part way through a sample rather than overrun the deadline on a deep pass
+			if n%progressiveCheck == 0 && (time.Now().After(deadline) || ctx.Err() != nil) {
+				pr.x[i], pr.y[i], pr.n[i] = x, y, n
+				pr.cursors[w] = k
+				return false
+			}
+		}
+		pr.x[i], pr.y[i], pr.n[i] = x, y, n
+
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			pr.cursors[w] = k + 1
+			return false
+		}
+	}
+}
+
+// Progress returns the fraction of the passes done, from 0 to 1, counting the current pass as done as far as its
+// workers have got through it.
+func (pr *Progressive) Progress() float64 {
+	if pr.done {
+		return 1
+	}
+	passes := pr.pass + 1
+	for depth := pr.depth; depth < pr.p.Iterations; depth *= 2 {
+		passes++
+	}
+	var through float64
+	share := float64(len(pr.samples)) / float64(len(pr.cursors))
+	for _, k := range pr.cursors {
+		through += float64(k) / share
+	}
+	through /= float64(len(pr.cursors))
+	if through > 1 {
+		through = 1
+	}
+	return (float64(pr.pass) + through) / float64(passes)
+}
+
+// Image colours the image as iterated so far.
+func (pr *Progressive) Image() *image.RGBA {
+	return colourSamples(pr.p, pr.samples)
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgressive(t *testing.T) {
+	p := testParams()
+	p.Iterations, p.Samples, p.Colouring = 1000, 2, ColouringSmooth
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pr, err := NewProgressive(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// slices far too short to finish in lead to the same samples, just over more steps
+	var steps int
+	last := -1.0
+	for done := false; !done; steps++ {
+		if done, err = pr.Step(context.Background(), time.Microsecond); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if progress := pr.Progress(); progress < last {
+			t.Fatalf("expected progress to never go backwards, got %g after %g", progress, last)
+		} else {
+			last = progress
+		}
+	}
+	if steps < 2 || last != 1 {
+		t.Fatalf("expected several steps ending fully done, got %d steps and progress %g", steps, last)
+	}
+	for i := range want {
+		if pr.samples[i] != want[i] {
+			t.Fatalf("sample %d = %+v, want %+v", i, pr.samples[i], want[i])
+		}
+	}
+	assertImagesEqual(t, pr.Image(), colourSamples(p, want))
+
+	for name, modify := range map[string]func(p *Params){
+		"fractal":      func(p *Params) { p.Fractal = "tricorn" },
+		"interior":     func(p *Params) { p.Interior = InteriorOrbit },
+		"sample edges": func(p *Params) { p.SampleEdges = true },
+		"perturbation": func(p *Params) { p.Precision = PrecisionPerturbation },
+	} {
+		q := p
+		modify(&q)
+		if _, err := NewProgressive(q); err == nil {
+			t.Errorf("expected a different %s not to be iterated progressively", name)
+		}
+	}
+}
+
+func TestProgressiveCancel(t *testing.T) {
+	pr, err := NewProgressive(testParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if done, err := pr.Step(ctx, time.Second); done || err != context.Canceled {
+		t.Fatalf("expected a cancelled step to stop with its context's error, got %t and %v", done, err)
+	}
+}
@@ -0,0 +1,431 @@
+// Package render generates escape-time fractal images.
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// Params describes a single render.
+type Params struct {
+	// Centre is the point on the complex plane at the centre of the image.
+	Centre complex128
+	// Scale is the distance on the complex plane between the centres of adjacent pixels.
+	Scale float64
+	// Width and Height are the image dimensions in pixels.
+	Width, Height int
+
+	// Iterations is the maximum number of iterations before a point is considered to be inside the set.
+	Iterations int
+	// Fractal is the name of the formula to iterate, one of Fractals.
+	Fractal string
+	// Exponent is the power d of the multibrot formula z^d + c.
+	Exponent float64
+	// Formula is a custom formula in z and c, such as "z^3 + c*sin(z)", iterated instead of Fractal if it isn't empty.
+	// Formulas can use + - * / ^, parentheses, the constants i, pi and e, and the functions sin, cos, tan, sinh, cosh,
+	// tanh, exp, log, sqrt, conj, abs, re, im and fold, which folds z into the first quadrant like the burning ship.
+	// The smooth and distance colourings assume custom formulas are quadratic.
+	Formula string
+	// Polynomial is the comma separated complex coefficients, from the highest power down, of the polynomial whose roots
+	// the newton fractal finds, e.g. "1, 0, 0, -1" for z^3 - 1 or "1, 0, 2i, 1" for z^3 + 2iz + 1. Empty means z^3 - 1.
+	Polynomial string
+	// Julia renders the Julia set of Seed rather than the fractal itself: each point is the start of an orbit with
+	// Seed as the constant c, instead of being c with the orbit starting at zero.
+	Julia bool
+	// Seed is the constant c of the Julia set rendered when Julia is set. The newton fractal has no Julia sets, so it
+	// ignores both.
+	Seed complex128
+	// Bailout is the escape radius beyond which a point is considered to have escaped, measured with Norm. Zero means
+	// DefaultBailout.
+	Bailout float64
+	// Norm is the name of the norm the escape radius is measured with, one of Norms. Empty means NormEuclidean.
+	Norm string
+	// Colouring is the name of the colouring algorithm, one of Colourings. Empty means ColouringBands.
+	Colouring string
+	// Contrast is the shade step between consecutive escape iterations of ColouringBands, which ColouringSmooth repeats
+	// its palette in step with. Zero means palette.Contrast.
+	Contrast int
+	// Palette is the name of the palette.Gradients gradient used by colourings other than ColouringBands. Empty means
+	// the first gradient.
+	Palette string
+	// Interior is the name of the interior colouring mode for points which never escape, one of Interiors. Empty means
+	// InteriorFlat.
+	Interior string
+	// Levels adjusts the brightness, contrast and gamma of every colouring. The zero value leaves the colours as they
+	// are.
+	Levels palette.Levels
+	// Trap is the name of the orbit trap shape used by ColouringOrbitTrap, one of Traps. Empty means TrapPoint.
+	Trap string
+	// Samples is the number of samples taken along each axis of a pixel, which are averaged to anti-alias the image.
+	// Zero is treated as one.
+	Samples int
+	// SIMD iterates four points at once with AVX2 vector instructions where HasSIMD reports the CPU supports them,
+	// falling back to iterating one at a time otherwise. It only speeds up the mandelbrot with a euclidean bailout and
+	// colourings and interiors which need nothing but the escape iteration.
+	SIMD bool
+	// OpenCL iterates every sample at once in double precision on an OpenCL device where HasOpenCL reports there's
+	// one, falling back to the CPU otherwise. Like SIMD, it only applies to the mandelbrot with a euclidean bailout and
+	// colourings and interiors which need nothing but the escape iteration.
+	OpenCL bool
+	// Precision is the name of the arithmetic points are iterated with, one of Precisions. Empty means PrecisionAuto.
+	Precision string
+	// Trace skips iterating the insides of rectangles whose borders are all the same, such as large areas of the set's
+	// interior, filling them with the border's sample instead. It speeds up views with a lot of interior or wide
+	// escape bands several times over, but detail finer than a pixel which doesn't reach a rectangle's border can be
+	// lost. It only applies to the mandelbrot with a euclidean bailout and one sample per pixel.
+	Trace bool
+	// SampleEdges restricts anti-aliasing to pixels on edges, whose escape iteration differs from a neighbour's by more
+	// than one. They take Samples x Samples jittered samples while the rest are sampled once, at a fraction of the cost
+	// of sampling every pixel.
+	SampleEdges bool
+}
+
+// Validate reports whether the parameters describe a renderable image.
+func (p Params) Validate() error {
+	if p.Width <= 0 || p.Height <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", p.Width, p.Height)
+	}
+	if p.Scale <= 0 {
+		return fmt.Errorf("scale must be positive, got %g", p.Scale)
+	}
+	if p.Iterations <= 0 {
+		return fmt.Errorf("iterations must be positive, got %d", p.Iterations)
+	}
+	if p.Bailout < 0 {
+		return fmt.Errorf("bailout must not be negative, got %g", p.Bailout)
+	}
+	if p.Norm != "" && !IsNorm(p.Norm) {
+		return fmt.Errorf("unknown bailout norm %q", p.Norm)
+	}
+	if p.Contrast < 0 {
+		return fmt.Errorf("contrast must not be negative, got %d", p.Contrast)
+	}
+	if p.Levels.Brightness < -1 || p.Levels.Brightness > 1 {
+		return fmt.Errorf("brightness must be between -1 and 1, got %g", p.Levels.Brightness)
+	}
+	if p.Levels.Contrast < 0 || p.Levels.Gamma < 0 {
+		return fmt.Errorf("levels contrast and gamma must not be negative, got %g and %g", p.Levels.Contrast,
+			p.Levels.Gamma)
+	}
+	if p.Samples < 0 {
+		return fmt.Errorf("samples must not be negative, got %d", p.Samples)
+	}
+	if !IsFractal(p.Fractal) {
+		return fmt.Errorf("unknown fractal %q", p.Fractal)
+	}
+	if p.Formula != "" {
+		if _, err := compileFormula(p.Formula); err != nil {
+			return fmt.Errorf("invalid formula %q: %s", p.Formula, err)
+		}
+	}
+	if p.isNewton() {
+		if _, err := parsePolynomial(p.Polynomial); err != nil {
+			return fmt.Errorf("invalid polynomial %q: %s", p.Polynomial, err)
+		}
+	}
+	if p.Precision != "" && !IsPrecision(p.Precision) {
+		return fmt.Errorf("unknown precision %q", p.Precision)
+	}
+	if p.Colouring != "" && !IsColouring(p.Colouring) {
+		return fmt.Errorf("unknown colouring %q", p.Colouring)
+	}
+	if _, ok := palette.LookupGradient(p.Palette); p.Palette != "" && !ok {
+		return fmt.Errorf("unknown palette %q", p.Palette)
+	}
+	if p.Interior != "" && !IsInterior(p.Interior) {
+		return fmt.Errorf("unknown interior colouring %q", p.Interior)
+	}
+	if p.Trap != "" && !IsTrap(p.Trap) {
+		return fmt.Errorf("unknown orbit trap %q", p.Trap)
+	}
+	return nil
+}
+
+// PixelToPlane maps a position in image space, where (0, 0) is the top left corner of the top left pixel, to the
+// complex plane.
+func (p Params) PixelToPlane(x, y float64) complex128 {
+	return p.Centre + p.pixelOffset(x, y)
+}
+
+// returns the offset on the complex plane of a position in image space from the centre of the image. Offsets stay
+// precise where the absolute positions of neighbouring pixels round to the same point.
+func (p Params) pixelOffset(x, y float64) complex128 {
+	return complex((x-float64(p.Width)/2)*p.Scale, -(y-float64(p.Height)/2)*p.Scale)
+}
+
+// Render generates the image described by p. The top row of the image is the top of the viewport, i.e. the imaginary
+// axis points up. If ctx is cancelled part way through, the partial image is discarded and ctx's error is returned.
+func Render(ctx context.Context, p Params) (*image.RGBA, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	// iterate every sample first, as some colourings depend on the whole frame
+	samples, err := iterateSamples(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return colourSamples(p, samples), nil
+}
+
+// the number of samples taken along each axis of a pixel
+func (p Params) samplesPerAxis() int {
+	if p.Samples < 1 {
+		return 1
+	}
+	return p.Samples
+}
+
+// iterates every sample of the image described by p. The samples of each pixel are stored contiguously, with pixels in
+// row-major order.
+func iterateSamples(ctx context.Context, p Params) ([]Sample, error) {
+	iterate := p.formula()
+	needs := p.needs()
+	precision := p.SelectedPrecision()
+	// points are passed around as offsets from the centre of the image, for perturbation
+	escapeFunc := func(d complex128) Sample {
+		return escape(p.Centre+d, p, iterate, needs)
+	}
+	switch {
+	case precision == PrecisionPerturbation:
+		orbit := newReferenceOrbit(p)
+		escapeFunc = func(d complex128) Sample {
+			return escapePerturbed(d, p, orbit, needs)
+		}
+	case hasFastPath(p):
+		escapeFunc = func(d complex128) Sample {
+			return escapeMandelbrot(p.Centre+d, p, needs)
+		}
+	case p.isNewton():
+		poly, _ := parsePolynomial(p.Polynomial)
+		escapeFunc = func(d complex128) Sample {
+			return escapeNewton(p.Centre+d, p, poly, needs)
+		}
+	}
+
+	n := p.samplesPerAxis()
+	if p.SampleEdges && n > 1 {
+		return iterateEdgeSamples(ctx, p, escapeFunc)
+	}
+
+	escapeRow := func(points []complex128, samples []Sample) {
+		for i, d := range points {
+			samples[i] = escapeFunc(d)
+		}
+	}
+	if precision == PrecisionFloat32 {
+		escapeRow = func(points []complex128, samples []Sample) {
+			escapeRowSIMD32(p, points, samples)
+		}
+	} else if precision == PrecisionFloat64 && hasSIMDPath(p, needs) {
+		escapeRow = func(points []complex128, samples []Sample) {
+			escapeRowSIMD(p, points, samples)
+		}
+	}
+
+	// the device iterates faster by brute force than the cpu can trace
+	if precision == PrecisionFloat64 && hasOpenCLPath(p, needs) {
+		return iterateOpenCL(ctx, p)
+	}
+	if canTrace(p) {
+		samples := make([]Sample, p.Width*p.Height)
+		err := forEachTile(ctx, p.Width, p.Height, func() func(tile image.Rectangle) {
+			return newTracer(p, samples, escapeRow).trace
+		})
+		if err != nil {
+			return nil, err
+		}
+		return samples, nil
+	}
+
+	// samples are spread evenly across each pixel, so a single sample lands on the pixel centre
+	offsets := make([]float64, n)
+	for i := range offsets {
+		offsets[i] = (float64(i) + 0.5) / float64(n)
+	}
+
+	count := n * n
+	samples := make([]Sample, p.Width*p.Height*count)
+	err := forEachTile(ctx, p.Width, p.Height, func() func(tile image.Rectangle) {
+		points := make([]complex128, 0, workTileSize*count)
+		return func(tile image.Rectangle) {
+			for py := tile.Min.Y; py < tile.Max.Y; py++ {
+				points = points[:0]
+				for px := tile.Min.X; px < tile.Max.X; px++ {
+					for _, oy := range offsets {
+						for _, ox := range offsets {
+							points = append(points, p.pixelOffset(float64(px)+ox, float64(py)+oy))
+						}
+					}
+				}
+				escapeRow(points, samples[(py*p.Width+tile.Min.X)*count:(py*p.Width+tile.Max.X)*count])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// colours the samples produced by iterateSamples, averaging the samples of each pixel
+func colourSamples(p Params, samples []Sample) *image.RGBA {
+	histogram := newHistogram(p)
+	addToHistogram(histogram, p, samples)
+	return paintSamples(p, samples, newColourer(p, histogram), 0)
+}
+
+// colours the samples of the image described by p into an image whose top row is at top
+func paintSamples(p Params, samples []Sample, colour func(s Sample) color.RGBA, top int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, top, p.Width, top+p.Height))
+
+	n := p.samplesPerAxis()
+	count := n * n
+	for i := 0; i < p.Width*p.Height; i++ {
+		img.SetRGBA(i%p.Width, top+i/p.Width, averageColour(samples[i*count:(i+1)*count], colour))
+	}
+	return img
+}
+
+// colours each of a pixel's samples and averages them
+func averageColour(samples []Sample, colour func(s Sample) color.RGBA) color.RGBA {
+	var r, g, b, a int
+	for _, s := range samples {
+		c := colour(s)
+		r, g, b, a = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A)
+	}
+	n := len(samples)
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
+
+// Sample is the result of iterating a single point.
+type Sample struct {
+	// N is the iteration the point escaped on, or the iteration limit if it didn't escape. Points of the newton fractal
+	// escape by converging on a root.
+	N int
+	// Root is the index of the root of the newton fractal's polynomial which the point converged to.
+	Root int
+	// Smooth is the fractional escape iteration of points which escaped, which runs continuously across the bands of N.
+	// It is only computed for colourings which need it.
+	Smooth float64
+	// Shade is the interior colouring value of points which didn't escape.
+	Shade float64
+	// Trap is the closest the orbit came to the orbit trap, for colourings which need it.
+	Trap float64
+	// Distance is the estimated distance in pixels from a point which escaped to the set, for colourings which need it.
+	Distance float64
+}
+
+// Escaped reports whether the sample's point escaped within the iteration limit of p.
+func (s Sample) Escaped(p Params) bool {
+	return s.N < p.Iterations
+}
+
+// iterates the point c until it escapes or the iteration limit is reached, gathering the escape data in needs
+func escape(c complex128, p Params, iterate formula, needs Needs) Sample {
+	var z complex128
+	if p.Julia {
+		z, c = c, p.Seed
+	}
+	bailout := newBailout(p)
+	var distance func(z complex128) float64
+	var trap float64
+	if needs&NeedsTrap != 0 {
+		distance = lookupTrap(p.Trap)
+		trap = math.Inf(1)
+	}
+	// the derivative of the orbit with respect to the point, for estimating the distance to the set. Julia sets vary
+	// the start of the orbit rather than c.
+	estimate := needs&NeedsDistance != 0
+	var dz complex128
+	if p.Julia {
+		dz = 1
+	}
+
+	for n := 0; n < p.Iterations; n++ {
+		if estimate {
+			dz = p.derivative(z) * dz
+			if !p.Julia {
+				dz++
+			}
+		}
+		z = iterate(z, c, p.Exponent)
+		if distance != nil {
+			trap = math.Min(trap, distance(z))
+		}
+
+		if bailout.escaped(z) {
+			s := Sample{N: n, Trap: trap}
+			if needs&NeedsSmooth != 0 {
+				s.Smooth = smoothIteration(p, n, z)
+			}
+			if estimate {
+				s.Distance = exteriorDistance(z, dz) / p.Scale
+			}
+			return s
+		}
+	}
+	return Sample{N: p.Iterations, Shade: interiorShade(p, z, c, iterate), Trap: trap}
+}
+
+// reports whether escapeMandelbrot can be used in place of escape
+func hasFastPath(p Params) bool {
+	return p.Fractal == "mandelbrot" && p.Formula == "" && !p.Julia && newBailout(p).kind == euclidean && p.needs()&(NeedsTrap|NeedsDistance) == 0
+}
+
+// escape specialised for the mandelbrot with a euclidean bailout, iterating on the real and imaginary components
+// directly. Keeping their squares around for the next iteration leaves three multiplications per iteration and no
+// square root, producing the same orbits as escape roughly twice as fast.
+func escapeMandelbrot(c complex128, p Params, needs Needs) Sample {
+	cr, ci := real(c), imag(c)
+	limit := newBailout(p).limit
+	var x, y, x2, y2 float64
+
+	// interior points can stop iterating early when there's no need to shade them
+	shortcut := p.Interior == "" || p.Interior == InteriorFlat
+	if shortcut && inCardioidOrBulb(cr, ci) {
+		return Sample{N: p.Iterations}
+	}
+	// a point on the orbit, saved at ever doubling intervals to detect the orbit settling into a cycle of any length
+	var savedX, savedY float64
+	saveAt := 1
+
+	for n := 0; n < p.Iterations; n++ {
+		y = 2*x*y + ci
+		x = x2 - y2 + cr
+		x2, y2 = x*x, y*y
+
+		if x2+y2 > limit {
+			if needs&NeedsSmooth != 0 {
+				return Sample{N: n, Smooth: smoothIteration(p, n, complex(x, y))}
+			}
+			return Sample{N: n}
+		}
+
+		if shortcut {
+			if math.Abs(x-savedX) < periodicityEpsilon && math.Abs(y-savedY) < periodicityEpsilon {
+				return Sample{N: p.Iterations}
+			}
+			if n == saveAt {
+				savedX, savedY = x, y
+				saveAt *= 2
+			}
+		}
+	}
+	return Sample{N: p.Iterations, Shade: interiorShade(p, complex(x, y), c, mandelbrot)}
+}
+
+// reports whether c is inside the main cardioid or the period 2 bulb, which together cover most of the set
+func inCardioidOrBulb(cr, ci float64) bool {
+	ci2 := ci * ci
+	q := (cr-0.25)*(cr-0.25) + ci2
+	if q*(q+cr-0.25) <= ci2/4 {
+		return true
+	}
+	return (cr+1)*(cr+1)+ci2 <= 1.0/16
+}
@@ -0,0 +1,679 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+func testParams() Params {
+	return Params{
+		Centre:     complex(-0.5, 0),
+		Scale:      4.0 / 64,
+		Width:      64,
+		Height:     48,
+		Iterations: 100,
+		Fractal:    "mandelbrot",
+	}
+}
+
+func TestRender(t *testing.T) {
+	p := testParams()
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != p.Width || h != p.Height {
+		t.Fatalf("expected %dx%d image, got %dx%d", p.Width, p.Height, w, h)
+	}
+
+	// the centre of the image is inside the main cardioid, and the corners are far outside the set
+	if c := img.RGBAAt(p.Width/2, p.Height/2); c != palette.Interior {
+		t.Errorf("expected interior colour at centre, got %v", c)
+	}
+	// -2.5+1.5i escapes on the third iteration
+	if c := img.RGBAAt(0, 0); c != palette.Escape(2) {
+		t.Errorf("expected colour of third iteration escape at corner, got %v", c)
+	}
+}
+
+func TestRenderSymmetry(t *testing.T) {
+	// the mandelbrot set is symmetric about the real axis, so an image centred on it has mirrored rows
+	p := testParams()
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for y := 0; y < p.Height/2; y++ {
+		for x := 0; x < p.Width; x++ {
+			if a, b := img.RGBAAt(x, y), img.RGBAAt(x, p.Height-1-y); a != b {
+				t.Fatalf("pixel (%d, %d) %v doesn't mirror (%d, %d) %v", x, y, a, x, p.Height-1-y, b)
+			}
+		}
+	}
+}
+
+func TestRenderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Render(ctx, testParams()); err != context.Canceled {
+		t.Fatalf("expected %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestRenderInvalidParams(t *testing.T) {
+	tests := map[string]func(p *Params){
+		"zero width":        func(p *Params) { p.Width = 0 },
+		"negative height":   func(p *Params) { p.Height = -1 },
+		"zero scale":        func(p *Params) { p.Scale = 0 },
+		"zero iterations":   func(p *Params) { p.Iterations = 0 },
+		"negative samples":  func(p *Params) { p.Samples = -1 },
+		"unknown fractal":   func(p *Params) { p.Fractal = "julia-ish" },
+		"missing fractal":   func(p *Params) { p.Fractal = "" },
+		"unknown colouring": func(p *Params) { p.Colouring = "sepia" },
+		"unknown palette":   func(p *Params) { p.Palette = "mauve" },
+		"unknown interior":  func(p *Params) { p.Interior = "hollow" },
+		"unknown trap":      func(p *Params) { p.Trap = "spiral" },
+		"negative bailout":  func(p *Params) { p.Bailout = -1 },
+		"negative contrast": func(p *Params) { p.Contrast = -1 },
+		"unknown norm":      func(p *Params) { p.Norm = "taxicab" },
+		"bright":            func(p *Params) { p.Levels.Brightness = 2 },
+		"negative gamma":    func(p *Params) { p.Levels.Gamma = -1 },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := testParams()
+			modify(&p)
+			if _, err := Render(context.Background(), p); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestRenderSamples(t *testing.T) {
+	// supersampling averages colours, so a pixel straddling the boundary of the set blends interior and exterior
+	p := testParams()
+	p.Samples = 4
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	blended := false
+	for x := 0; x < p.Width && !blended; x++ {
+		blended = isBlend(img.RGBAAt(x, p.Height/2), p)
+	}
+	if !blended {
+		t.Error("expected a pixel along the set boundary on the real axis to blend the interior and exterior")
+	}
+}
+
+// reports whether c is a blend of samples of the bands colouring of p, being neither the interior nor the colour of
+// any escape iteration
+func isBlend(c color.RGBA, p Params) bool {
+	if c == palette.Interior {
+		return false
+	}
+	for n := 0; n < p.Iterations; n++ {
+		if c == palette.EscapeContrast(n, p.contrast()) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRenderHistogram(t *testing.T) {
+	p := testParams()
+	p.Colouring = ColouringHistogram
+	p.Palette = "grey"
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the most frequent escape values dominate the grey ramp, so the brightest exterior pixel is fully white
+	var brightest uint8
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			if c := img.RGBAAt(x, y); c.A == 255 && c.R > brightest {
+				brightest = c.R
+			}
+		}
+	}
+	if brightest != 255 {
+		t.Errorf("expected the latest escaping pixels to reach the end of the gradient, brightest was %d", brightest)
+	}
+	if c := img.RGBAAt(p.Width/2, p.Height/2); c != palette.Interior {
+		t.Errorf("expected interior colour at centre, got %v", c)
+	}
+}
+
+func TestPixelToPlane(t *testing.T) {
+	p := testParams()
+	tests := []struct {
+		x, y float64
+		want complex128
+	}{
+		{x: 32, y: 24, want: p.Centre},
+		{x: 0, y: 0, want: complex(-2.5, 1.5)},
+		{x: 64, y: 48, want: complex(1.5, -1.5)},
+	}
+	for _, tt := range tests {
+		if got := p.PixelToPlane(tt.x, tt.y); got != tt.want {
+			t.Errorf("PixelToPlane(%g, %g) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		c       complex128
+		fractal string
+		n       int
+		escaped bool
+	}{
+		{c: 0, fractal: "mandelbrot", n: 50, escaped: false},
+		{c: -1, fractal: "mandelbrot", n: 50, escaped: false},
+		{c: 20, fractal: "mandelbrot", n: 0, escaped: true},
+		{c: 2, fractal: "mandelbrot", n: 2, escaped: true},
+		{c: complex(0, 1), fractal: "mandelbrot", n: 50, escaped: false},
+		{c: -1.75, fractal: "burning-ship", n: 50, escaped: false},
+		{c: 0, fractal: "tricorn", n: 50, escaped: false},
+		{c: 0, fractal: "multibrot", n: 50, escaped: false},
+	}
+	for _, tt := range tests {
+		p := Params{Iterations: 50, Fractal: tt.fractal, Exponent: 3}
+		iterate, _ := lookupFractal(tt.fractal)
+		s := escape(tt.c, p, iterate, p.needs())
+		if s.N != tt.n || s.Escaped(p) != tt.escaped {
+			t.Errorf("%s escape(%v) = (%d, %t), want (%d, %t)", tt.fractal, tt.c, s.N, s.Escaped(p), tt.n, tt.escaped)
+		}
+	}
+}
+
+func TestJulia(t *testing.T) {
+	// the Julia set of 0 is the unit disc
+	p := Params{Iterations: 100, Fractal: "mandelbrot", Julia: true, Colouring: ColouringDistance, Scale: 1}
+	for _, tt := range []struct {
+		z       complex128
+		escaped bool
+	}{
+		{z: 0, escaped: false},
+		{z: complex(0.5, -0.5), escaped: false},
+		{z: 1.5, escaped: true},
+		{z: complex(0, -2), escaped: true},
+	} {
+		if s := escape(tt.z, p, mandelbrot, p.needs()); s.Escaped(p) != tt.escaped {
+			t.Errorf("escaped(%v) = %t, want %t", tt.z, s.Escaped(p), tt.escaped)
+		}
+	}
+
+	// 2 is 1 away from the unit circle, and the distance estimate is accurate to within a factor of 4
+	if s := escape(2, p, mandelbrot, p.needs()); s.Distance < 1.0/4 || s.Distance > 4 {
+		t.Errorf("distance estimate from 2 = %g, want within a factor of 4 of 1", s.Distance)
+	}
+
+	// 0.5 is outside the mandelbrot set, so its Julia set is a dust which even the origin escapes
+	p.Seed = complex(0.5, 0)
+	if s := escape(0, p, mandelbrot, p.needs()); !s.Escaped(p) {
+		t.Error("expected 0 to escape the Julia set of 0.5")
+	}
+	if hasFastPath(p) {
+		t.Error("expected no fast path for Julia sets")
+	}
+}
+
+func TestMultibrot(t *testing.T) {
+	// whole exponents take a fast path which should agree with the general power
+	z, c := complex(0.3, -0.7), complex(-0.1, 0.2)
+	for _, d := range []float64{2, 3, 5} {
+		fast := multibrot(z, c, d)
+		general := multibrot(z, c, d+1e-12)
+		if diff := fast - general; real(diff)*real(diff)+imag(diff)*imag(diff) > 1e-18 {
+			t.Errorf("exponent %g: fast path %v differs from general power %v", d, fast, general)
+		}
+	}
+	if got := multibrot(z, c, 2); got != mandelbrot(z, c, 2) {
+		t.Errorf("multibrot with exponent 2 = %v, want mandelbrot %v", got, mandelbrot(z, c, 2))
+	}
+}
+
+func TestFractals(t *testing.T) {
+	for _, name := range Fractals() {
+		if !IsFractal(name) {
+			t.Errorf("IsFractal(%q) = false for a listed fractal", name)
+		}
+	}
+	if IsFractal("") {
+		t.Error(`IsFractal("") = true`)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		c    complex128
+		want int
+	}{
+		// the main cardioid has an attracting fixed point, the bulb to its left a 2-cycle, and the bulb above a 3-cycle
+		{c: 0, want: 1},
+		{c: -0.25, want: 1},
+		{c: -1, want: 2},
+		{c: complex(-0.12, 0.75), want: 3},
+	}
+	for _, tt := range tests {
+		p := Params{Iterations: 1000, Fractal: "mandelbrot", Interior: InteriorPeriod}
+		s := escape(tt.c, p, mandelbrot, p.needs())
+		if s.Escaped(p) {
+			t.Fatalf("%v unexpectedly escaped", tt.c)
+		}
+		if int(s.Shade) != tt.want {
+			t.Errorf("period of %v = %g, want %d", tt.c, s.Shade, tt.want)
+		}
+	}
+}
+
+func TestInteriorDistance(t *testing.T) {
+	// the centre of the period 2 bulb is 0.25 away from its boundary, and the distance estimate is accurate to within a
+	// factor of 4
+	p := Params{Iterations: 1000, Fractal: "mandelbrot", Interior: InteriorDistance, Scale: 1}
+	s := escape(-1, p, mandelbrot, p.needs())
+	if s.Shade < 0.25/4 || s.Shade > 0.25*4 {
+		t.Errorf("distance estimate from -1 = %g, want within a factor of 4 of 0.25", s.Shade)
+	}
+
+	// points closer to the boundary should have smaller estimates
+	if near := escape(-0.76, p, mandelbrot, p.needs()); near.Shade >= s.Shade {
+		t.Errorf("distance estimate near the boundary %g isn't less than at the bulb centre %g", near.Shade, s.Shade)
+	}
+}
+
+func TestRenderInterior(t *testing.T) {
+	for _, interior := range Interiors() {
+		p := testParams()
+		p.Interior = interior
+		img, err := Render(context.Background(), p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", interior, err)
+		}
+
+		c := img.RGBAAt(p.Width/2, p.Height/2)
+		if interior == InteriorFlat && c != palette.Interior {
+			t.Errorf("%s: expected flat interior colour at centre, got %v", interior, c)
+		}
+		if interior != InteriorFlat && c.A != 255 {
+			t.Errorf("%s: expected opaque interior shading at centre, got %v", interior, c)
+		}
+	}
+}
+
+func TestRenderLevels(t *testing.T) {
+	p := testParams()
+	plain, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.Levels = palette.Levels{Brightness: 0.1, Contrast: 1.2, Gamma: 1.5}
+	adjusted, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// every sample but the flat interior is adjusted, which with one sample per pixel adjusts every pixel outside the set
+	adjust := p.Levels.Adjuster()
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			want := plain.RGBAAt(x, y)
+			if want != palette.Interior {
+				want = adjust(want)
+			}
+			if got := adjusted.RGBAAt(x, y); got != want {
+				t.Fatalf("pixel (%d, %d) is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestRenderLevelsBlack(t *testing.T) {
+	// black outside the set is brightened like any other colour, while the interior of the set stays black
+	black := color.RGBA{0, 0, 0, 255}
+	gradient := palette.Gradient{{Pos: 0, Colour: black}, {Pos: 1, Colour: black}}
+	if err := palette.RegisterGradient("black", gradient); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p := testParams()
+	p.Colouring, p.Palette, p.Levels = ColouringSmooth, "black", palette.Levels{Brightness: 0.2}
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if c := img.RGBAAt(p.Width/2, p.Height/2); c != palette.Interior {
+		t.Errorf("expected the interior of the set to stay %v, got %v", palette.Interior, c)
+	}
+	if c, want := img.RGBAAt(0, 0), (color.RGBA{51, 51, 51, 255}); c != want {
+		t.Errorf("expected black outside the set to be brightened to %v, got %v", want, c)
+	}
+}
+
+func TestTrapDistance(t *testing.T) {
+	tests := []struct {
+		trap string
+		z    complex128
+		want float64
+	}{
+		{trap: TrapPoint, z: complex(3, 4), want: 5},
+		{trap: "", z: complex(0, -2), want: 2},
+		{trap: TrapLine, z: complex(3, -0.5), want: 0.5},
+		{trap: TrapLine, z: complex(-0.25, 2), want: 0.25},
+		{trap: TrapRing, z: complex(0, 0.5), want: 0.5},
+		{trap: TrapRing, z: complex(-3, 0), want: 2},
+	}
+	for _, tt := range tests {
+		if got := lookupTrap(tt.trap)(tt.z); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%q trap distance of %v = %g, want %g", tt.trap, tt.z, got, tt.want)
+		}
+	}
+}
+
+func TestRenderOrbitTrap(t *testing.T) {
+	for _, trap := range Traps() {
+		p := testParams()
+		p.Colouring = ColouringOrbitTrap
+		p.Trap = trap
+		img, err := Render(context.Background(), p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", trap, err)
+		}
+
+		// every point is coloured from the gradient, including the interior
+		for _, pt := range []image.Point{{X: 0, Y: 0}, {X: p.Width / 2, Y: p.Height / 2}} {
+			if c := img.RGBAAt(pt.X, pt.Y); c.A != 255 {
+				t.Errorf("%s: expected an opaque colour at %v, got %v", trap, pt, c)
+			}
+		}
+	}
+
+	// the origin is on the orbit of the centre of the main cardioid, so it sits right on the point trap
+	p := Params{Iterations: 10, Fractal: "mandelbrot", Colouring: ColouringOrbitTrap}
+	s := escape(0, p, mandelbrot, p.needs())
+	if s.Trap != 0 {
+		t.Errorf("expected a trap distance of 0 at the origin, got %g", s.Trap)
+	}
+}
+
+func TestSmoothIteration(t *testing.T) {
+	// escaping further past the bailout means escaping earlier, so the fraction runs down to an iteration before n
+	p := Params{Iterations: 50, Fractal: "mandelbrot", Colouring: ColouringSmooth}
+	for _, c := range []complex128{2, complex(0.3, 0.6), complex(-0.8, 0.2), complex(0.26, 0)} {
+		s := escape(c, p, mandelbrot, p.needs())
+		if !s.Escaped(p) {
+			t.Fatalf("%v unexpectedly didn't escape", c)
+		}
+		if s.Smooth <= float64(s.N)-2 || s.Smooth > float64(s.N) {
+			t.Errorf("smooth iteration of %v = %g, want within 2 iterations below %d", c, s.Smooth, s.N)
+		}
+	}
+
+	if s := escape(2, p, mandelbrot, 0); s.Smooth != 0 {
+		t.Errorf("expected no smooth iteration without smooth colouring, got %g", s.Smooth)
+	}
+}
+
+func TestExteriorDistance(t *testing.T) {
+	// the rightmost point of the set is 0.25, and the distance estimate is accurate to within a factor of 4
+	p := Params{Iterations: 1000, Fractal: "mandelbrot", Colouring: ColouringDistance, Scale: 1}
+	s := escape(1, p, mandelbrot, p.needs())
+	if s.Distance < 0.75/4 || s.Distance > 0.75*4 {
+		t.Errorf("distance estimate from 1 = %g, want within a factor of 4 of 0.75", s.Distance)
+	}
+
+	if near := escape(0.26, p, mandelbrot, p.needs()); near.Distance >= s.Distance {
+		t.Errorf("distance estimate near the boundary %g isn't less than further away %g", near.Distance, s.Distance)
+	}
+}
+
+// colours every point the same
+type solidColourer struct{}
+
+func (solidColourer) Needs() Needs {
+	return 0
+}
+
+func (solidColourer) Colour(Params, []int) func(s Sample) color.RGBA {
+	return func(Sample) color.RGBA {
+		return color.RGBA{R: 10, G: 20, B: 30, A: 255}
+	}
+}
+
+func TestRegisterColouring(t *testing.T) {
+	// registrations last for the whole test binary, which may run the test more than once
+	if !IsColouring("test-solid") {
+		RegisterColouring("test-solid", solidColourer{})
+	}
+	if !IsColouring("test-solid") {
+		t.Fatal("expected the registered colouring to be supported")
+	}
+	if names := Colourings(); names[len(names)-1] != "test-solid" {
+		t.Errorf("expected the registered colouring last in %v", names)
+	}
+
+	p := testParams()
+	p.Colouring = "test-solid"
+	img, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c := img.RGBAAt(0, 0); c != (color.RGBA{R: 10, G: 20, B: 30, A: 255}) {
+		t.Errorf("expected the registered colour, got %v", c)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a colouring twice to panic")
+		}
+	}()
+	RegisterColouring(ColouringBands, solidColourer{})
+}
+
+func TestBailout(t *testing.T) {
+	tests := []struct {
+		norm    string
+		bailout float64
+		z       complex128
+		escaped bool
+	}{
+		{norm: "", bailout: 0, z: complex(16, 0), escaped: false},
+		{norm: "", bailout: 0, z: complex(12, 12), escaped: true},
+		{norm: NormEuclidean, bailout: 2, z: complex(1.5, 1.5), escaped: true},
+		{norm: NormManhattan, bailout: 16, z: complex(8, 7), escaped: false},
+		{norm: NormManhattan, bailout: 16, z: complex(-9, 8), escaped: true},
+		{norm: NormImaginary, bailout: 16, z: complex(1000, 15), escaped: false},
+		{norm: NormImaginary, bailout: 16, z: complex(0, -17), escaped: true},
+	}
+	for _, tt := range tests {
+		b := newBailout(Params{Bailout: tt.bailout, Norm: tt.norm})
+		if got := b.escaped(tt.z); got != tt.escaped {
+			t.Errorf("%q norm with bailout %g: escaped(%v) = %t, want %t", tt.norm, tt.bailout, tt.z, got, tt.escaped)
+		}
+	}
+}
+
+func TestEscapeBailout(t *testing.T) {
+	// a smaller escape radius is crossed sooner: 2 -> 6 -> 38
+	p := Params{Iterations: 50, Fractal: "mandelbrot"}
+	if s := escape(2, p, mandelbrot, p.needs()); s.N != 2 {
+		t.Errorf("expected escape on iteration 2 with the default bailout, got %d", s.N)
+	}
+	p.Bailout = 4
+	if s := escape(2, p, mandelbrot, p.needs()); s.N != 1 {
+		t.Errorf("expected escape on iteration 1 with a bailout of 4, got %d", s.N)
+	}
+}
+
+func TestEscapeMandelbrot(t *testing.T) {
+	// the fast path must produce exactly the same samples as the general formula
+	for _, colouring := range []string{ColouringBands, ColouringSmooth} {
+		for _, interior := range Interiors() {
+			p := testParams()
+			p.Colouring = colouring
+			p.Interior = interior
+			if !hasFastPath(p) {
+				t.Fatalf("%s %s: expected the fast path to be used", colouring, interior)
+			}
+			for py := 0; py < p.Height; py++ {
+				for px := 0; px < p.Width; px++ {
+					c := p.PixelToPlane(float64(px)+0.5, float64(py)+0.5)
+					if got, want := escapeMandelbrot(c, p, p.needs()), escape(c, p, mandelbrot, p.needs()); got != want {
+						t.Fatalf("%s %s: escapeMandelbrot(%v) = %+v, want %+v", colouring, interior, c, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	for name, modify := range map[string]func(p *Params){
+		"fractal":    func(p *Params) { p.Fractal = "tricorn" },
+		"norm":       func(p *Params) { p.Norm = NormManhattan },
+		"orbit trap": func(p *Params) { p.Colouring = ColouringOrbitTrap },
+		"distance":   func(p *Params) { p.Colouring = ColouringDistance },
+	} {
+		p := testParams()
+		modify(&p)
+		if hasFastPath(p) {
+			t.Errorf("expected no fast path with a different %s", name)
+		}
+	}
+}
+
+// the spread of points benchmarked, from quick escapes to points inside the set which run to the iteration limit
+var benchmarkPoints = []complex128{complex(-0.75, 0.1), complex(-0.5, 0.5), complex(0.3, 0.5), complex(-1.5, 0), 2}
+
+func BenchmarkEscape(b *testing.B) {
+	p := testParams()
+	p.Iterations = 1000
+	b.Run("complex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, c := range benchmarkPoints {
+				escape(c, p, mandelbrot, 0)
+			}
+		}
+	})
+	b.Run("float", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, c := range benchmarkPoints {
+				escapeMandelbrot(c, p, 0)
+			}
+		}
+	})
+}
+
+func BenchmarkRender(b *testing.B) {
+	p := testParams()
+	p.Width, p.Height = 256, 192
+	p.Scale = 4.0 / 256
+	p.Iterations = 500
+	for i := 0; i < b.N; i++ {
+		if _, err := Render(context.Background(), p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestInCardioidOrBulb(t *testing.T) {
+	tests := []struct {
+		c    complex128
+		want bool
+	}{
+		{c: 0, want: true},
+		{c: -0.5, want: true},
+		{c: 0.2, want: true},
+		{c: -1, want: true},
+		{c: -1.2, want: true},
+		{c: 0.3, want: false},
+		{c: -0.75, want: true},
+		{c: complex(-0.75, 0.1), want: false},
+		{c: complex(-0.12, 0.75), want: false},
+		{c: 2, want: false},
+	}
+	for _, tt := range tests {
+		if got := inCardioidOrBulb(real(tt.c), imag(tt.c)); got != tt.want {
+			t.Errorf("inCardioidOrBulb(%v) = %t, want %t", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSampleEdges(t *testing.T) {
+	p := testParams()
+	p.Samples = 3
+	p.SampleEdges = true
+	edges, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.Samples = 1
+	p.SampleEdges = false
+	single, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// pixels away from edges keep their single sample colour, while some on edges are blended
+	centre := single.RGBAAt(p.Width/2, p.Height/2)
+	if c := edges.RGBAAt(p.Width/2, p.Height/2); c != centre {
+		t.Errorf("expected pixel off an edge to keep its colour %v, got %v", centre, c)
+	}
+	blended := false
+	for x := 0; x < p.Width && !blended; x++ {
+		blended = isBlend(edges.RGBAAt(x, p.Height/2), p)
+	}
+	if !blended {
+		t.Error("expected a pixel along the set boundary on the real axis to blend the interior and exterior")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	seen := make(map[float64]bool)
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			for axis := 0; axis < 2; axis++ {
+				v := jitter(i, j, axis)
+				if v < 0.25 || v >= 0.75 {
+					t.Fatalf("jitter(%d, %d, %d) = %g, want within [0.25, 0.75)", i, j, axis, v)
+				}
+				seen[v] = true
+			}
+		}
+	}
+	if len(seen) < 16 {
+		t.Errorf("expected varied jitter, got %d distinct offsets from 32", len(seen))
+	}
+}
+
+func BenchmarkRenderAntiAliased(b *testing.B) {
+	p := testParams()
+	p.Width, p.Height = 256, 192
+	p.Scale = 4.0 / 256
+	p.Iterations = 500
+	p.Samples = 3
+	for _, edges := range []bool{false, true} {
+		p.SampleEdges = edges
+		name := "every-pixel"
+		if edges {
+			name = "edges"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Render(context.Background(), p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
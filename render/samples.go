@@ -0,0 +1,194 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"image"
+
+	"github.com/jemgunay/mandelbrot/palette"
+)
+
+// Samples holds the iterated but not yet coloured samples of an image. Iterating is by far the expensive part of a
+// render, so splitting it from colouring lets an image be iterated in strips, e.g. on several machines, then joined
+// and coloured as a whole, which keeps colourings that depend on the whole frame, such as histogram, seamless.
+type Samples struct {
+	// Params describes the image the samples were iterated for.
+	Params  Params
+	samples []Sample
+}
+
+// Iterate iterates every sample of the image described by p without colouring them.
+func Iterate(ctx context.Context, p Params) (*Samples, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	samples, err := iterateSamples(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &Samples{Params: p, samples: samples}, nil
+}
+
+// Image colours the samples.
+func (s *Samples) Image() *image.RGBA {
+	return colourSamples(s.Params, s.samples)
+}
+
+// Recolour colours the samples with the colouring settings of p, such as its palette, without iterating them again.
+// It fails if p describes a different image or needs information the samples weren't iterated with, such as orbit trap
+// distances; see CanRecolour.
+func (s *Samples) Recolour(p Params) (*image.RGBA, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if !CanRecolour(s.Params, p) {
+		return nil, fmt.Errorf("samples can't be recoloured with differently iterated params")
+	}
+	return colourSamples(p, s.samples), nil
+}
+
+// CanRecolour reports whether samples iterated with a can be coloured with b, i.e. the two only differ in settings
+// applied after iterating.
+func CanRecolour(a, b Params) bool {
+	return a.IterationParams() == b.IterationParams()
+}
+
+// IterationParams returns p with the settings which only affect colouring cleared, leaving those which affect
+// iterating. Samples iterated with them can be coloured with p.
+func (p Params) IterationParams() Params {
+	needs := p.needs()
+	p.Palette, p.Contrast, p.Levels = "", 0, palette.Levels{}
+	// colourings which record anything extra while iterating can only recolour samples iterated for the same colouring
+	if needs&iterationNeeds == 0 {
+		p.Colouring = ""
+	}
+	if needs&NeedsTrap == 0 {
+		p.Trap = ""
+	}
+	return p
+}
+
+// Split divides the image described by p into n horizontal strips of near equal height, ordered top to bottom. Fewer
+// strips are returned if the image is less than n pixels tall.
+func Split(p Params, n int) []Params {
+	if n > p.Height {
+		n = p.Height
+	}
+	// every strip is iterated with the precision picked for the whole image, so that they join without seams
+	p.Precision = p.SelectedPrecision()
+	strips := make([]Params, 0, n)
+	for i := 0; i < n; i++ {
+		top, bottom := i*p.Height/n, (i+1)*p.Height/n
+
+		strip := p
+		strip.Height = bottom - top
+		strip.Centre = p.PixelToPlane(float64(p.Width)/2, float64(top+bottom)/2)
+		strips = append(strips, strip)
+	}
+	return strips
+}
+
+// Join combines the samples of the strips returned by Split(p, n) back into the samples of the image described by p.
+func Join(p Params, strips []*Samples) (*Samples, error) {
+	joined := &Samples{Params: p, samples: make([]Sample, 0, p.Width*p.Height*p.samplesPerAxis()*p.samplesPerAxis())}
+	height := 0
+	for i, strip := range strips {
+		sp := strip.Params
+		if sp.Width != p.Width || sp.Scale != p.Scale || sp.Samples != p.Samples {
+			return nil, fmt.Errorf("strip %d doesn't match the image being joined", i)
+		}
+		height += sp.Height
+		joined.samples = append(joined.samples, strip.samples...)
+	}
+	if height != p.Height {
+		return nil, fmt.Errorf("strips are %d pixels tall in total, expected %d", height, p.Height)
+	}
+	return joined, nil
+}
+
+// the encoded form of Samples
+type samplesData struct {
+	Params     Params
+	Iterations []int32
+	Smooths    []float64
+	Shades     []float64
+	Traps      []float64
+	Distances  []float64
+}
+
+// MarshalBinary encodes the samples, e.g. for sending between machines.
+func (s *Samples) MarshalBinary() ([]byte, error) {
+	data := samplesData{Params: s.Params, Iterations: make([]int32, len(s.samples))}
+	for i, smp := range s.samples {
+		data.Iterations[i] = int32(smp.N)
+		// only some colourings use the rest of the escape data, so it's left out of images without any
+		setSparse(&data.Smooths, i, smp.Smooth, len(s.samples))
+		setSparse(&data.Shades, i, smp.Shade, len(s.samples))
+		setSparse(&data.Traps, i, smp.Trap, len(s.samples))
+		setSparse(&data.Distances, i, smp.Distance, len(s.samples))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes samples encoded by MarshalBinary.
+func (s *Samples) UnmarshalBinary(b []byte) error {
+	var data samplesData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return err
+	}
+
+	n := data.Params.samplesPerAxis()
+	if len(data.Iterations) != data.Params.Width*data.Params.Height*n*n {
+		return fmt.Errorf("expected %d samples for a %dx%d image, got %d", data.Params.Width*data.Params.Height*n*n,
+			data.Params.Width, data.Params.Height, len(data.Iterations))
+	}
+	for name, values := range map[string][]float64{
+		"smooth iterations": data.Smooths,
+		"shades":            data.Shades,
+		"trap distances":    data.Traps,
+		"distances":         data.Distances,
+	} {
+		if values != nil && len(values) != len(data.Iterations) {
+			return fmt.Errorf("expected %d %s, got %d", len(data.Iterations), name, len(values))
+		}
+	}
+
+	s.Params = data.Params
+	s.samples = make([]Sample, len(data.Iterations))
+	for i, n := range data.Iterations {
+		s.samples[i] = Sample{
+			N:        int(n),
+			Smooth:   sparseAt(data.Smooths, i),
+			Shade:    sparseAt(data.Shades, i),
+			Trap:     sparseAt(data.Traps, i),
+			Distance: sparseAt(data.Distances, i),
+		}
+	}
+	return nil
+}
+
+// sets the i'th of n values to v, only allocating the values once one isn't zero
+func setSparse(values *[]float64, i int, v float64, n int) {
+	if v == 0 {
+		return
+	}
+	if *values == nil {
+		*values = make([]float64, n)
+	}
+	(*values)[i] = v
+}
+
+// returns the i'th value, or zero if none of the values were set
+func sparseAt(values []float64, i int) float64 {
+	if values == nil {
+		return 0
+	}
+	return values[i]
+}
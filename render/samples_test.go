@@ -0,0 +1,172 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestSplitJoin(t *testing.T) {
+	p := testParams()
+	p.Colouring = ColouringHistogram
+	p.Interior = InteriorOrbit
+
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	strips := Split(p, 5)
+	if len(strips) != 5 {
+		t.Fatalf("expected 5 strips, got %d", len(strips))
+	}
+	parts := make([]*Samples, 0, len(strips))
+	for _, strip := range strips {
+		s, err := Iterate(context.Background(), strip)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		parts = append(parts, s)
+	}
+
+	joined, err := Join(p, parts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, joined.Image(), want)
+
+	if _, err := Join(p, parts[1:]); err == nil {
+		t.Error("expected an error joining too few strips")
+	}
+}
+
+func TestSplitShortImage(t *testing.T) {
+	p := testParams()
+	p.Height = 3
+	if strips := Split(p, 10); len(strips) != 3 {
+		t.Errorf("expected 3 strips, got %d", len(strips))
+	}
+}
+
+func TestSamplesBinary(t *testing.T) {
+	p := testParams()
+	p.Interior = InteriorDistance
+	s, err := Iterate(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var decoded Samples
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if decoded.Params != p {
+		t.Errorf("decoded params %+v, want %+v", decoded.Params, p)
+	}
+	assertImagesEqual(t, decoded.Image(), s.Image())
+
+	if err := decoded.UnmarshalBinary(data[:len(data)/2]); err == nil {
+		t.Error("expected an error decoding truncated samples")
+	}
+}
+
+func TestStripRenderer(t *testing.T) {
+	for _, colouring := range Colourings() {
+		p := testParams()
+		p.Colouring = colouring
+		want, err := Render(context.Background(), p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", colouring, err)
+		}
+
+		r, err := NewStripRenderer(p, 10)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", colouring, err)
+		}
+		if r.Len() != 5 {
+			t.Fatalf("%s: expected 5 strips, got %d", colouring, r.Len())
+		}
+
+		// paste the strips together at the positions they report
+		got := image.NewRGBA(want.Bounds())
+		for i := 0; i < r.Len(); i++ {
+			strip, err := r.Strip(context.Background(), i)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", colouring, err)
+			}
+			draw.Draw(got, strip.Bounds(), strip, strip.Bounds().Min, draw.Src)
+		}
+		assertImagesEqual(t, got, want)
+	}
+}
+
+func TestRecolour(t *testing.T) {
+	p := testParams()
+	s, err := Iterate(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	recoloured := p
+	recoloured.Colouring = ColouringHistogram
+	recoloured.Palette = "lime"
+	recoloured.Levels.Gamma = 1.4
+	got, err := s.Recolour(recoloured)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want, err := Render(context.Background(), recoloured)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+
+	for name, modify := range map[string]func(p *Params){
+		"iterations": func(p *Params) { p.Iterations++ },
+		"centre":     func(p *Params) { p.Centre += 0.1 },
+		"interior":   func(p *Params) { p.Interior = InteriorPeriod },
+		"orbit trap": func(p *Params) { p.Colouring = ColouringOrbitTrap },
+	} {
+		q := p
+		modify(&q)
+		if _, err := s.Recolour(q); err == nil {
+			t.Errorf("expected an error recolouring with a different %s", name)
+		}
+	}
+}
+
+func BenchmarkIterate(b *testing.B) {
+	p := testParams()
+	p.Width, p.Height = 256, 192
+	p.Scale = 4.0 / 256
+	p.Iterations = 500
+	for i := 0; i < b.N; i++ {
+		if _, err := Iterate(context.Background(), p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkImage(b *testing.B) {
+	p := testParams()
+	p.Width, p.Height = 256, 192
+	p.Scale = 4.0 / 256
+	p.Iterations = 500
+	for _, colouring := range Colourings() {
+		p.Colouring = colouring
+		s, err := Iterate(context.Background(), p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(colouring, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.Image()
+			}
+		})
+	}
+}
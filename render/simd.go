@@ -0,0 +1,123 @@
+package render
+
+import "math"
+
+// HasSIMD reports whether Params.SIMD can vectorise iterating on this CPU.
+func HasSIMD() bool {
+	return hasSIMD
+}
+
+// reports whether escapeRowSIMD can be used in place of escapeMandelbrot
+func hasSIMDPath(p Params, needs Needs) bool {
+	flat := p.Interior == "" || p.Interior == InteriorFlat
+	return p.SIMD && hasSIMD && hasFastPath(p) && needs&NeedsSmooth == 0 && flat
+}
+
+// iterates the points at the given offsets from the centre of the image four at a time with escape4, writing their
+// samples to samples. Like escapeMandelbrot, points in
+// the main cardioid or period 2 bulb are filled in without iterating, but there is no periodicity checking as every
+// lane runs until all four escape.
+func escapeRowSIMD(p Params, points []complex128, samples []Sample) {
+	limit := newBailout(p).limit
+	var cr, ci [4]float64
+	var n [4]int64
+	// the index of the point in each lane
+	var lanes [4]int
+
+	k := 0
+	flush := func() {
+		// spare lanes are filled with a point which escapes on the first iteration
+		for ; k < 4; k++ {
+			cr[k], ci[k], lanes[k] = limit+1, 0, -1
+		}
+		escape4(&cr, &ci, limit, p.Iterations, &n)
+		for i, idx := range lanes {
+			if idx >= 0 {
+				samples[idx] = Sample{N: int(n[i])}
+			}
+		}
+		k = 0
+	}
+
+	for i, d := range points {
+		c := p.Centre + d
+		if inCardioidOrBulb(real(c), imag(c)) {
+			samples[i] = Sample{N: p.Iterations}
+			continue
+		}
+		cr[k], ci[k], lanes[k] = real(c), imag(c), i
+		if k++; k == 4 {
+			flush()
+		}
+	}
+	if k > 0 {
+		flush()
+	}
+}
+
+// how many lanes escape8 waits for to finish before returning to have them refilled. Waiting for a few amortises the
+// cost of returning, while returning well before all have finished keeps most lanes busy.
+const refillLanes = 4
+
+// the state of the eight lanes iterated by escape8: the point each lane is iterating, the point its orbit has reached
+// and the number of iterations it has survived
+type lanes8 struct {
+	cr, ci, x, y [8]float32
+	n            [8]int32
+}
+
+// escapeRowSIMD in single precision, iterating eight points at a time with escape8. Rather than every lane running
+// until all eight points escape, lanes are refilled with the next points once a few of them have escaped or reached the
+// iteration limit, so lanes spend little time idle waiting for the slowest.
+func escapeRowSIMD32(p Params, points []complex128, samples []Sample) {
+	limit := float32(newBailout(p).limit)
+	var s lanes8
+	// the index of the point in each lane, or -1 for lanes with no point left to iterate
+	var lanes [8]int
+
+	next := 0
+	// loads the next point which needs iterating into lane k, reporting whether there was one left
+	load := func(k int) bool {
+		for ; next < len(points); next++ {
+			c := p.Centre + points[next]
+			if inCardioidOrBulb(real(c), imag(c)) {
+				samples[next] = Sample{N: p.Iterations}
+				continue
+			}
+			s.cr[k], s.ci[k], s.x[k], s.y[k], s.n[k], lanes[k] = float32(real(c)), float32(imag(c)), 0, 0, 0, next
+			next++
+			return true
+		}
+		// idle lanes iterate the origin, which never escapes, from so far below the iteration limit they never reach it
+		s.cr[k], s.ci[k], s.x[k], s.y[k], s.n[k], lanes[k] = 0, 0, 0, 0, math.MinInt32, -1
+		return false
+	}
+
+	active := 0
+	for k := range lanes {
+		if load(k) {
+			active++
+		}
+	}
+	for active > 0 {
+		want := refillLanes
+		if active < want {
+			want = active
+		}
+		done := escape8(&s, limit, p.Iterations, want)
+		for k, idx := range lanes {
+			if idx < 0 {
+				// keep idle lanes well away from the iteration limit however long the others run
+				s.n[k] = math.MinInt32
+				continue
+			}
+			if done&(1<<k) == 0 {
+				continue
+			}
+			samples[idx] = Sample{N: int(s.n[k])}
+			if !load(k) {
+				active--
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+//go:build amd64 && !purego
+
+package render
+
+// whether the CPU and OS support the AVX2 instructions used by escape4
+var hasSIMD = detectAVX2()
+
+// iterates the mandelbrot for four points at once with a euclidean bailout, writing the iteration each point escaped
+// on, or iterations if it didn't escape, to n. Implemented in simd_amd64.s.
+//
+//go:noescape
+func escape4AVX2(cr, ci *[4]float64, limit float64, iterations int, n *[4]int64)
+
+// iterates the mandelbrot in single precision for the eight lanes of s at once with a euclidean bailout, until at
+// least want of the lanes' points have escaped or reached the iteration limit. It updates the orbits and iteration
+// counts of s, returning a mask of the lanes which finished. Implemented in simd_amd64.s.
+//
+//go:noescape
+func escape8AVX2(s *lanes8, limit float32, iterations, want int) (done uint32)
+
+// implemented in simd_amd64.s
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+func xgetbv() (eax, edx uint32)
+
+func escape4(cr, ci *[4]float64, limit float64, iterations int, n *[4]int64) {
+	escape4AVX2(cr, ci, limit, iterations, n)
+}
+
+func escape8(s *lanes8, limit float32, iterations, want int) uint32 {
+	return escape8AVX2(s, limit, iterations, want)
+}
+
+// reports whether the CPU supports AVX2 and the OS saves the 256 bit registers it uses on context switches
+func detectAVX2() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 7 {
+		return false
+	}
+	const osxsave, avx = 1 << 27, 1 << 28
+	if _, _, ecx, _ := cpuid(1, 0); ecx&osxsave == 0 || ecx&avx == 0 {
+		return false
+	}
+	// the OS must have enabled saving both the SSE and AVX register state
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+	const avx2 = 1 << 5
+	_, ebx, _, _ := cpuid(7, 0)
+	return ebx&avx2 != 0
+}
@@ -0,0 +1,128 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func escape4AVX2(cr, ci *[4]float64, limit float64, iterations int, n *[4]int64)
+TEXT ·escape4AVX2(SB), NOSPLIT, $0-40
+	MOVQ cr+0(FP), SI
+	MOVQ ci+8(FP), DI
+	MOVQ iterations+24(FP), CX
+	MOVQ n+32(FP), DX
+
+	VMOVUPD (SI), Y0           // cr
+	VMOVUPD (DI), Y1           // ci
+	VXORPD  Y2, Y2, Y2         // x
+	VXORPD  Y3, Y3, Y3         // y
+	VXORPD  Y4, Y4, Y4         // x*x
+	VXORPD  Y5, Y5, Y5         // y*y
+	VBROADCASTSD limit+16(FP), Y6
+	VPXOR   Y7, Y7, Y7         // iterations survived by each lane
+	VPCMPEQQ Y10, Y10, Y10     // lanes which haven't escaped yet
+
+	TESTQ CX, CX
+	JLE   done
+
+loop:
+	// y = 2*x*y + ci
+	VMULPD Y3, Y2, Y8
+	VADDPD Y8, Y8, Y8
+	VADDPD Y1, Y8, Y3
+
+	// x = x*x - y*y + cr
+	VSUBPD Y5, Y4, Y8
+	VADDPD Y0, Y8, Y2
+
+	VMULPD Y2, Y2, Y4
+	VMULPD Y3, Y3, Y5
+
+	// lanes escape once x*x + y*y > limit, counting the iterations survived until then
+	VADDPD   Y5, Y4, Y8
+	VCMPPD   $0x1e, Y6, Y8, Y9
+	VANDNPD  Y10, Y9, Y10
+	VPSUBQ   Y10, Y7, Y7
+
+	VMOVMSKPD Y10, AX
+	TESTQ     AX, AX
+	JZ        done
+
+	DECQ CX
+	JNZ  loop
+
+done:
+	VMOVDQU Y7, (DX)
+	VZEROUPPER
+	RET
+
+// func escape8AVX2(s *lanes8, limit float32, iterations, want int) (done uint32)
+TEXT ·escape8AVX2(SB), NOSPLIT, $0-36
+	MOVQ s+0(FP), SI
+	MOVQ want+24(FP), CX
+
+	VMOVUPS 0(SI), Y0          // cr
+	VMOVUPS 32(SI), Y1         // ci
+	VMOVUPS 64(SI), Y2         // x
+	VMOVUPS 96(SI), Y3         // y
+	VMOVDQU 128(SI), Y7        // iterations survived by each lane
+	VMULPS  Y2, Y2, Y4         // x*x
+	VMULPS  Y3, Y3, Y5         // y*y
+	VBROADCASTSS limit+8(FP), Y6
+	VPCMPEQD Y12, Y12, Y12     // all ones
+	VPBROADCASTD iterations+16(FP), Y11
+	VPADDD  Y12, Y11, Y11      // iterations-1, as there's only a greater than comparison
+	VPXOR   Y14, Y14, Y14      // lanes which have finished
+
+loop8:
+	// y = 2*x*y + ci
+	VMULPS Y3, Y2, Y8
+	VADDPS Y8, Y8, Y8
+	VADDPS Y1, Y8, Y3
+
+	// x = x*x - y*y + cr
+	VSUBPS Y5, Y4, Y8
+	VADDPS Y0, Y8, Y2
+
+	VMULPS Y2, Y2, Y4
+	VMULPS Y3, Y3, Y5
+
+	// lanes finish once x*x + y*y > limit, and the rest survive another iteration
+	VADDPS  Y5, Y4, Y8
+	VCMPPS  $0x1e, Y6, Y8, Y9
+	VPOR    Y9, Y14, Y14
+	VPXOR   Y12, Y14, Y10
+	VPSUBD  Y10, Y7, Y7
+
+	// lanes also finish on reaching the iteration limit
+	VPCMPGTD Y11, Y7, Y13
+	VPOR     Y13, Y14, Y14
+
+	// stop once enough lanes have finished
+	VMOVMSKPS Y14, AX
+	POPCNTL   AX, BX
+	CMPQ      BX, CX
+	JL        loop8
+
+	VMOVUPS Y2, 64(SI)
+	VMOVUPS Y3, 96(SI)
+	VMOVDQU Y7, 128(SI)
+	MOVL    AX, done+32(FP)
+	VZEROUPPER
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
@@ -0,0 +1,14 @@
+//go:build !amd64 || purego
+
+package render
+
+// only amd64 has a vectorised inner loop
+const hasSIMD = false
+
+func escape4(cr, ci *[4]float64, limit float64, iterations int, n *[4]int64) {
+	panic("render: no vectorised inner loop on this platform")
+}
+
+func escape8(s *lanes8, limit float32, iterations, want int) uint32 {
+	panic("render: no vectorised inner loop on this platform")
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSIMD(t *testing.T) {
+	if !HasSIMD() {
+		t.Skip("no vectorised inner loop on this CPU")
+	}
+
+	// a width which isn't a multiple of four leaves spare lanes at the end of each row
+	p := testParams()
+	p.Width, p.Height = 63, 47
+	p.Iterations = 500
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.SIMD, p.Precision = true, PrecisionFloat64
+	if !hasSIMDPath(p, p.needs()) {
+		t.Fatal("expected the vectorised path to be used")
+	}
+	got, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for name, modify := range map[string]func(p *Params){
+		"fractal":  func(p *Params) { p.Fractal = "tricorn" },
+		"smooth":   func(p *Params) { p.Colouring = ColouringSmooth },
+		"interior": func(p *Params) { p.Interior = InteriorOrbit },
+	} {
+		q := p
+		modify(&q)
+		if hasSIMDPath(q, q.needs()) {
+			t.Errorf("expected no vectorised path with a different %s", name)
+		}
+	}
+}
+
+func TestSIMD32(t *testing.T) {
+	if !HasSIMD() {
+		t.Skip("no vectorised inner loop on this CPU")
+	}
+
+	// a width which isn't a multiple of eight leaves spare lanes at the end of each row
+	p := testParams()
+	p.Width, p.Height = 63, 47
+	p.Iterations = 500
+	want, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.SIMD = true
+	if got := p.SelectedPrecision(); got != PrecisionFloat32 {
+		t.Fatalf("expected single precision to be picked for a shallow zoom, got %s", got)
+	}
+	got, err := iterateSamples(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// rounding differently only changes the escape iteration of the odd point close to the boundary of the set
+	differ := 0
+	for i := range want {
+		if got[i] != want[i] {
+			differ++
+		}
+	}
+	if differ > len(want)/100 {
+		t.Errorf("expected at most 1%% of samples to differ from double precision, got %d of %d", differ, len(want))
+	}
+
+	// deeper zooms need double precision to tell the pixels apart
+	p.Scale = 1e-9
+	if got := p.SelectedPrecision(); got != PrecisionFloat64 {
+		t.Errorf("expected double precision to be picked for a deep zoom, got %s", got)
+	}
+}
+
+func BenchmarkIterateSIMD(b *testing.B) {
+	p := testParams()
+	p.Width, p.Height = 256, 192
+	p.Scale = 4.0 / 256
+	p.Iterations = 500
+	for _, precision := range []string{"", PrecisionFloat64, PrecisionFloat32} {
+		p.SIMD, p.Precision = precision != "", precision
+		name := "scalar"
+		if precision != "" {
+			name = "simd-" + precision
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := iterateSamples(context.Background(), p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
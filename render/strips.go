@@ -0,0 +1,74 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// StripRenderer renders an image a horizontal strip at a time, so that memory use is bounded by the size of a strip
+// however large the image is. Colourings which depend on the whole frame take an extra pass iterating every strip to
+// gather their histogram, so that the strips join seamlessly.
+type StripRenderer struct {
+	// Iterate iterates the samples of a strip, defaulting to the package level Iterate. It can be replaced to iterate
+	// strips elsewhere, e.g. on other machines.
+	Iterate func(ctx context.Context, p Params) (*Samples, error)
+
+	p      Params
+	strips []Params
+	// the row each strip starts on
+	tops []int
+	// maps samples to colours, created once the histogram of the whole image has been gathered
+	colour func(s Sample) color.RGBA
+}
+
+// NewStripRenderer creates a renderer for the image described by p, split into strips of at most rows pixels.
+func NewStripRenderer(p Params, rows int) (*StripRenderer, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if rows < 1 {
+		rows = 1
+	}
+
+	r := &StripRenderer{
+		Iterate: Iterate,
+		p:       p,
+		strips:  Split(p, (p.Height+rows-1)/rows),
+	}
+	top := 0
+	for _, strip := range r.strips {
+		r.tops = append(r.tops, top)
+		top += strip.Height
+	}
+	return r, nil
+}
+
+// Len returns the number of strips.
+func (r *StripRenderer) Len() int {
+	return len(r.strips)
+}
+
+// Strip renders the i'th strip from the top. The returned image's bounds are its position within the whole image. The
+// first call gathers the histogram of the whole image if the colouring requires it.
+func (r *StripRenderer) Strip(ctx context.Context, i int) (*image.RGBA, error) {
+	if r.colour == nil {
+		histogram := newHistogram(r.p)
+		if histogram != nil {
+			for _, strip := range r.strips {
+				s, err := r.Iterate(ctx, strip)
+				if err != nil {
+					return nil, err
+				}
+				addToHistogram(histogram, strip, s.samples)
+			}
+		}
+		r.colour = newColourer(r.p, histogram)
+	}
+
+	s, err := r.Iterate(ctx, r.strips[i])
+	if err != nil {
+		return nil, err
+	}
+	return paintSamples(r.strips[i], s.samples, r.colour, r.tops[i]), nil
+}
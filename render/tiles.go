@@ -0,0 +1,315 @@
+package render
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// the width and height of a cached tile in pixels
+	tileSize = 64
+	// how many tiles beyond the edges of the latest frame are kept cached
+	tileCacheMargin = 8
+)
+
+// TileCache renders frames from tiles laid out on a fixed pixel grid over the complex plane, reusing tiles computed for
+// previous frames. Panning only has to compute newly exposed tiles, making it near instant even at high iteration
+// counts. Tiles hold uncoloured samples, so changing colouring settings such as the palette only recolours them, while
+// changing anything else which affects iterating discards them.
+//
+// The mandelbrot and tricorn are symmetric about the real axis, so tiles below the axis are mirrored from those above
+// it where they've been computed, halving the work of views which straddle it, such as the initial view.
+//
+// The tiles of the previous iteration settings are kept too, so that switching back and forth between two settings,
+// such as rendering at a lower resolution while the view moves, doesn't discard the tiles of either.
+//
+// Tiles are rendered from the centre of the frame outwards, so that the part of a slow frame being looked at fills in
+// first.
+//
+// To line up with the grid, frames are snapped to the nearest whole pixel, so the rendered image can be offset from the
+// requested centre by up to half a pixel. SnapToGrid returns where it really is.
+type TileCache struct {
+	mu sync.Mutex
+	// the tiles of the latest frame's iteration settings, and of the settings before those
+	tileLayer
+	previous tileLayer
+	stats    CacheStats
+}
+
+// tileLayer holds the cached tiles of a single set of iteration settings
+type tileLayer struct {
+	// the params the tiles were iterated with, with the viewport position, image size and colouring settings cleared
+	key   Params
+	tiles map[image.Point][]Sample
+	// how long each tile took to iterate, for the tile time overlay
+	costs map[image.Point]time.Duration
+}
+
+func newTileLayer(key Params) tileLayer {
+	return tileLayer{key: key, tiles: make(map[image.Point][]Sample), costs: make(map[image.Point]time.Duration)}
+}
+
+// CacheStats counts the tiles a TileCache has reused and computed. Tiles mirrored from their reflection in the real
+// axis are counted as Mirrored rather than as Misses.
+type CacheStats struct {
+	Hits, Misses, Mirrored int
+}
+
+// HitRate returns the fraction of tiles which were reused rather than computed, from 0 to 1.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns the number of tiles the cache has reused and computed across every frame it has rendered.
+func (tc *TileCache) Stats() CacheStats {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.stats
+}
+
+// NewTileCache creates an empty tile cache.
+func NewTileCache() *TileCache {
+	return &TileCache{tileLayer: newTileLayer(Params{}), previous: newTileLayer(Params{})}
+}
+
+// Render generates the image described by p like the package level Render, reusing cached tiles where possible. Tiles
+// completed before ctx is cancelled are kept for the next render.
+func (tc *TileCache) Render(ctx context.Context, p Params) (*image.RGBA, error) {
+	return tc.RenderProgress(ctx, p, 0, nil)
+}
+
+// Progress receives the fraction of a frame which has rendered so far and a partial image of it. Partial images are
+// transparent where tiles are still to be rendered and opaque elsewhere, with the interior of the set painted black, so
+// that they can be drawn over an older frame.
+type Progress func(done float64, partial *image.RGBA)
+
+// RenderProgress is like Render, but while the frame renders it also calls progress roughly every interval, if it isn't
+// nil. Frames which render within interval never call progress.
+func (tc *TileCache) RenderProgress(ctx context.Context, p Params, interval time.Duration, progress Progress) (*image.RGBA, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	// tiles are centred elsewhere than the frame, so settle the precision for the whole frame up front rather than
+	// letting each tile pick its own
+	p.Precision = p.SelectedPrecision()
+	if key := cacheKey(p); key != tc.key {
+		if key == tc.previous.key {
+			tc.tileLayer, tc.previous = tc.previous, tc.tileLayer
+		} else {
+			tc.previous, tc.tileLayer = tc.tileLayer, newTileLayer(key)
+		}
+	}
+
+	frame, tileBounds := frameTiles(p)
+
+	count := p.samplesPerAxis() * p.samplesPerAxis()
+	samples := make([]Sample, p.Width*p.Height*count)
+	// the regions of the frame copied from tiles so far, relative to the frame
+	var completed []image.Rectangle
+	lastProgress := time.Now()
+
+	for _, pos := range centreOutwards(frame, tileBounds) {
+		tile, err := tc.tile(ctx, p, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		// copy the part of the tile overlapping the frame, a row at a time
+		tileRect := image.Rect(pos.X*tileSize, pos.Y*tileSize, (pos.X+1)*tileSize, (pos.Y+1)*tileSize)
+		overlap := tileRect.Intersect(frame)
+		rowLen := overlap.Dx() * count
+		for y := overlap.Min.Y; y < overlap.Max.Y; y++ {
+			src := ((y-tileRect.Min.Y)*tileSize + overlap.Min.X - tileRect.Min.X) * count
+			dst := ((y-frame.Min.Y)*p.Width + overlap.Min.X - frame.Min.X) * count
+			copy(samples[dst:dst+rowLen], tile[src:src+rowLen])
+		}
+
+		completed = append(completed, overlap.Sub(frame.Min))
+		if progress != nil && time.Since(lastProgress) >= interval {
+			done := float64(len(completed)) / float64(tileBounds.Dx()*tileBounds.Dy())
+			progress(done, partialImage(p, samples, completed))
+			lastProgress = time.Now()
+		}
+	}
+
+	// forget tiles which have drifted well away from the frame to bound memory use
+	keep := tileBounds.Inset(-tileCacheMargin)
+	for pos := range tc.tiles {
+		if !pos.In(keep) {
+			delete(tc.tiles, pos)
+			delete(tc.costs, pos)
+		}
+	}
+
+	return colourSamples(p, samples), nil
+}
+
+// returns the params tiles of frames described by p are iterated with, which are the same for every frame whose tiles
+// can be shared
+func cacheKey(p Params) Params {
+	key := p.IterationParams()
+	key.Centre, key.Width, key.Height = 0, 0, 0
+	return key
+}
+
+// returns the pixels on the grid covered by the frame described by p, and the positions of the tiles overlapping them
+func frameTiles(p Params) (image.Rectangle, image.Rectangle) {
+	origin := gridOrigin(p)
+	frame := image.Rectangle{Min: origin, Max: origin.Add(image.Pt(p.Width, p.Height))}
+	tileBounds := image.Rectangle{
+		Min: image.Pt(floorDiv(frame.Min.X, tileSize), floorDiv(frame.Min.Y, tileSize)),
+		Max: image.Pt(floorDiv(frame.Max.X-1, tileSize)+1, floorDiv(frame.Max.Y-1, tileSize)+1),
+	}
+	return frame, tileBounds
+}
+
+// returns the positions of the tiles in tileBounds ordered by the distance of their centres from the centre of the
+// frame, nearest first, so that frames fill in in rings around the middle of the view
+func centreOutwards(frame, tileBounds image.Rectangle) []image.Point {
+	// distances are measured in doubled pixels to keep the centres whole
+	centre := frame.Min.Add(frame.Max)
+	distance := func(pos image.Point) int {
+		d := pos.Mul(2 * tileSize).Add(image.Pt(tileSize, tileSize)).Sub(centre)
+		return d.X*d.X + d.Y*d.Y
+	}
+
+	var order []image.Point
+	for ty := tileBounds.Min.Y; ty < tileBounds.Max.Y; ty++ {
+		for tx := tileBounds.Min.X; tx < tileBounds.Max.X; tx++ {
+			order = append(order, image.Pt(tx, ty))
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distance(order[i]) < distance(order[j])
+	})
+	return order
+}
+
+// colours the completed regions of a frame which is still rendering, leaving the rest transparent
+func partialImage(p Params, samples []Sample, completed []image.Rectangle) *image.RGBA {
+	count := p.samplesPerAxis() * p.samplesPerAxis()
+	histogram := newHistogram(p)
+	for _, r := range completed {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			addToHistogram(histogram, p, samples[(y*p.Width+r.Min.X)*count:(y*p.Width+r.Max.X)*count])
+		}
+	}
+	colour := newColourer(p, histogram)
+
+	img := image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+	for _, r := range completed {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i := y*p.Width + x
+				c := averageColour(samples[i*count:(i+1)*count], colour)
+				// the colours are premultiplied, so making them opaque blends them over black
+				c.A = 255
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+// SnapToGrid returns p with its centre moved to where TileCache renders it, on the nearest whole pixel of its grid.
+// Drawing a rendered frame at the snapped centre rather than the requested one keeps it steady while panning by less
+// than a pixel at a time.
+func SnapToGrid(p Params) Params {
+	if p.Scale <= 0 {
+		return p
+	}
+	origin := gridOrigin(p)
+	p.Centre = complex(
+		(float64(origin.X)+float64(p.Width)/2)*p.Scale,
+		-(float64(origin.Y)+float64(p.Height)/2)*p.Scale,
+	)
+	return p
+}
+
+// returns the position on the pixel grid of the top left pixel of the frame described by p. The grid has pixel (0, 0)
+// just below and to the right of the origin, with y increasing downwards.
+func gridOrigin(p Params) image.Point {
+	return image.Pt(
+		int(math.Round(real(p.Centre)/p.Scale-float64(p.Width)/2)),
+		int(math.Round(-imag(p.Centre)/p.Scale-float64(p.Height)/2)),
+	)
+}
+
+// returns the samples of the tile at pos on the pixel grid, computing it if it isn't cached
+func (tc *TileCache) tile(ctx context.Context, p Params, pos image.Point) ([]Sample, error) {
+	if tile, ok := tc.tiles[pos]; ok {
+		tc.stats.Hits++
+		return tile, nil
+	}
+
+	if symmetric(p) {
+		if reflection, ok := tc.tiles[image.Pt(pos.X, -pos.Y-1)]; ok {
+			tile := mirrorTile(reflection, p.samplesPerAxis())
+			tc.tiles[pos] = tile
+			tc.stats.Mirrored++
+			return tile, nil
+		}
+	}
+
+	tp := p
+	tp.Width, tp.Height = tileSize, tileSize
+	tp.Centre = complex(
+		(float64(pos.X)+0.5)*tileSize*p.Scale,
+		-(float64(pos.Y)+0.5)*tileSize*p.Scale,
+	)
+	start := time.Now()
+	tile, err := iterateSamples(ctx, tp)
+	if err != nil {
+		return nil, err
+	}
+	tc.tiles[pos] = tile
+	tc.costs[pos] = time.Since(start)
+	tc.stats.Misses++
+	return tile, nil
+}
+
+// reports whether the samples of every point of images described by p are the same as those of its conjugate. Tile
+// rows on the grid mirror each other exactly in the real axis, so their samples can be copied rather than iterated.
+func symmetric(p Params) bool {
+	// jittered samples aren't placed symmetrically
+	if p.Formula != "" || p.Julia || p.SampleEdges {
+		return false
+	}
+	return p.Fractal == "mandelbrot" || p.Fractal == "tricorn"
+}
+
+// returns the tile reflected in the real axis from the samples of its reflection, flipping the rows of pixels and the
+// rows of samples within each pixel, which has n samples per axis
+func mirrorTile(reflection []Sample, n int) []Sample {
+	count := n * n
+	tile := make([]Sample, len(reflection))
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			dst, src := (y*tileSize+x)*count, ((tileSize-1-y)*tileSize+x)*count
+			for i := 0; i < n; i++ {
+				copy(tile[dst+i*n:dst+(i+1)*n], reflection[src+(n-1-i)*n:src+(n-i)*n])
+			}
+		}
+	}
+	return tile
+}
+
+// divides rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
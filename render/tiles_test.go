@@ -0,0 +1,313 @@
+package render
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+// params on the tile grid, so that the cache doesn't need to snap them
+func gridParams() Params {
+	p := testParams()
+	p.Scale = 1.0 / 64
+	p.Centre = complex(-0.5, 0)
+	return p
+}
+
+func TestTileCacheMatchesRender(t *testing.T) {
+	tc := NewTileCache()
+	for _, colouring := range Colourings() {
+		p := gridParams()
+		p.Colouring = colouring
+
+		want, err := Render(context.Background(), p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got, err := tc.Render(context.Background(), p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assertImagesEqual(t, got, want)
+	}
+}
+
+func TestTileCacheSampleEdges(t *testing.T) {
+	// edge detection looks beyond the sides of each tile, so tiled frames match whole renders
+	p := gridParams()
+	p.Samples = 2
+	p.SampleEdges = true
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got, err := NewTileCache().Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+}
+
+func TestTileCachePan(t *testing.T) {
+	tc := NewTileCache()
+	p := gridParams()
+	if _, err := tc.Render(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cached := len(tc.tiles)
+	if stats := tc.Stats(); stats.Hits != 0 || stats.Misses+stats.Mirrored != cached {
+		t.Errorf("expected the first frame to compute or mirror all %d tiles, got %+v", cached, stats)
+	}
+
+	// pan by a whole number of pixels so the frame stays on the grid
+	p.Centre += complex(10*p.Scale, -3*p.Scale)
+	got, err := tc.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+
+	if len(tc.tiles) < cached {
+		t.Errorf("expected tiles from the first frame to be reused, cache shrank from %d to %d", cached, len(tc.tiles))
+	}
+	if stats := tc.Stats(); stats.Hits == 0 || stats.HitRate() <= 0 || stats.HitRate() >= 1 {
+		t.Errorf("expected the panned frame to reuse some tiles and compute others, got %+v", stats)
+	}
+}
+
+func TestTileCacheProgress(t *testing.T) {
+	p := gridParams()
+	p.Colouring = ColouringHistogram
+	var dones []float64
+	var last *image.RGBA
+	got, err := NewTileCache().RenderProgress(context.Background(), p, 0, func(done float64, partial *image.RGBA) {
+		dones = append(dones, done)
+		last = partial
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(dones) < 2 || dones[len(dones)-1] != 1 {
+		t.Fatalf("expected progress after each tile up to 1, got %v", dones)
+	}
+	for i := 1; i < len(dones); i++ {
+		if dones[i] <= dones[i-1] {
+			t.Fatalf("expected progress to increase, got %v", dones)
+		}
+	}
+	// the final partial image is the whole frame, made opaque
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			want := got.RGBAAt(x, y)
+			want.A = 255
+			if c := last.RGBAAt(x, y); c != want {
+				t.Fatalf("expected %v at (%d, %d) in the final partial image, got %v", want, x, y, c)
+			}
+		}
+	}
+}
+
+func TestTileCacheInvalidation(t *testing.T) {
+	tc := NewTileCache()
+	p := gridParams()
+	if _, err := tc.Render(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// changing anything but the viewport position invalidates the cache
+	p.Iterations++
+	got, err := tc.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+}
+
+func TestTileCacheKeepsPreviousSettings(t *testing.T) {
+	tc := NewTileCache()
+	p := gridParams()
+	coarse := p
+	coarse.Iterations /= 2
+	for _, params := range []Params{p, coarse} {
+		if _, err := tc.Render(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// switching back to the first settings reuses their tiles
+	before := tc.Stats()
+	got, err := tc.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := tc.Stats(); after.Misses != before.Misses || after.Mirrored != before.Mirrored {
+		t.Fatalf("expected every tile to be reused, got %+v then %+v", before, after)
+	}
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+}
+
+func TestCentreOutwards(t *testing.T) {
+	frame := image.Rect(-100, -40, 240, 200)
+	tileBounds := image.Rect(-2, -1, 4, 4)
+	order := centreOutwards(frame, tileBounds)
+	if len(order) != tileBounds.Dx()*tileBounds.Dy() {
+		t.Fatalf("expected %d tiles, got %d", tileBounds.Dx()*tileBounds.Dy(), len(order))
+	}
+
+	// the first tile holds the centre of the frame, and each tile is no nearer to it than the one before
+	centre := frame.Min.Add(frame.Max).Div(2)
+	if first := image.Rect(0, 0, tileSize, tileSize).Add(order[0].Mul(tileSize)); !centre.In(first) {
+		t.Fatalf("expected the first tile %v to contain the centre %v", first, centre)
+	}
+	distance := func(pos image.Point) float64 {
+		d := pos.Mul(tileSize).Add(image.Pt(tileSize/2, tileSize/2)).Sub(centre)
+		return math.Hypot(float64(d.X), float64(d.Y))
+	}
+	for i := 1; i < len(order); i++ {
+		if distance(order[i]) < distance(order[i-1]) {
+			t.Fatalf("expected tiles ordered by distance from the centre, got %v", order)
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct{ a, b, want int }{
+		{a: 0, b: 64, want: 0},
+		{a: 63, b: 64, want: 0},
+		{a: 64, b: 64, want: 1},
+		{a: -1, b: 64, want: -1},
+		{a: -64, b: 64, want: -1},
+		{a: -65, b: 64, want: -2},
+	}
+	for _, tt := range tests {
+		if got := floorDiv(tt.a, tt.b); got != tt.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func assertImagesEqual(t *testing.T, got, want *image.RGBA) {
+	t.Helper()
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("image bounds %v, want %v", got.Bounds(), want.Bounds())
+	}
+	for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y++ {
+		for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x++ {
+			if g, w := got.RGBAAt(x, y), want.RGBAAt(x, y); g != w {
+				t.Fatalf("pixel (%d, %d) is %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestTileCacheRecolour(t *testing.T) {
+	tc := NewTileCache()
+	p := gridParams()
+	if _, err := tc.Render(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var pos image.Point
+	for pos = range tc.tiles {
+		break
+	}
+	first := tc.tiles[pos]
+
+	// colouring settings are applied to the cached samples rather than iterating again
+	p.Colouring = ColouringHistogram
+	p.Palette = "fire"
+	got, err := tc.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if &tc.tiles[pos][0] != &first[0] {
+		t.Error("expected the cached tiles to be reused after changing the palette")
+	}
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+}
+
+func TestTileCacheMirror(t *testing.T) {
+	// a frame straddling the real axis off centre, with several samples per pixel to be flipped too
+	p := gridParams()
+	p.Width, p.Height = 100, 200
+	p.Centre += complex(0, 40*p.Scale)
+	p.Samples = 3
+	p.Colouring = ColouringSmooth
+	tc := NewTileCache()
+	got, err := tc.Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stats := tc.Stats(); stats.Mirrored == 0 {
+		t.Errorf("expected tiles below the real axis to be mirrored, got %+v", stats)
+	}
+	want, err := Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, want)
+
+	// the burning ship isn't symmetric
+	p.Fractal = "burning-ship"
+	tc = NewTileCache()
+	if _, err := tc.Render(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stats := tc.Stats(); stats.Mirrored != 0 {
+		t.Errorf("expected the burning ship not to be mirrored, got %+v", stats)
+	}
+}
+
+func TestSnapToGrid(t *testing.T) {
+	// frames a fraction of a pixel off the grid are rendered at the nearest pixel on it
+	want := gridParams()
+	p := want
+	p.Centre += complex(0.3*p.Scale, -0.4*p.Scale)
+	if got := SnapToGrid(p); got != want {
+		t.Errorf("expected the centre to snap to %v, got %v", want.Centre, got.Centre)
+	}
+
+	// odd sizes put the centre halfway across a pixel
+	p.Width++
+	snapped := SnapToGrid(p)
+	got, err := NewTileCache().Render(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantImg, err := Render(context.Background(), snapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertImagesEqual(t, got, wantImg)
+}
+
+func BenchmarkTileCachePan(b *testing.B) {
+	// each frame moves one pixel right, as when panning, so only a column of tiles is iterated every tile width
+	tc := NewTileCache()
+	p := gridParams()
+	p.Width, p.Height = 256, 192
+	p.Iterations = 500
+	for i := 0; i < b.N; i++ {
+		p.Centre += complex(p.Scale, 0)
+		if _, err := tc.Render(context.Background(), p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"math/cmplx"
+)
+
+// the supported orbit trap shapes used by ColouringOrbitTrap
+const (
+	// TrapPoint traps orbits around the origin.
+	TrapPoint = "point"
+	// TrapLine traps orbits along the real and imaginary axes, producing Pickover stalks.
+	TrapLine = "line"
+	// TrapRing traps orbits around the unit circle.
+	TrapRing = "ring"
+)
+
+var traps = []string{TrapPoint, TrapLine, TrapRing}
+
+// how quickly the gradient runs outwards from the trap, in gradient lengths per unit of distance on the plane
+const trapFalloff = 2.5
+
+// Traps returns the names of the supported orbit trap shapes.
+func Traps() []string {
+	return append([]string(nil), traps...)
+}
+
+// IsTrap reports whether name is a supported orbit trap shape.
+func IsTrap(name string) bool {
+	for _, t := range traps {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// returns a func measuring the distance from a point to the named trap shape
+func lookupTrap(shape string) func(z complex128) float64 {
+	switch shape {
+	case TrapLine:
+		return func(z complex128) float64 {
+			return math.Min(math.Abs(real(z)), math.Abs(imag(z)))
+		}
+	case TrapRing:
+		return func(z complex128) float64 {
+			return math.Abs(cmplx.Abs(z) - 1)
+		}
+	default:
+		return cmplx.Abs
+	}
+}
+
+// trapColourer implements ColouringOrbitTrap, colouring samples by how close their orbits came to the trap. Points
+// inside the set are coloured the same way unless an interior colouring mode is selected.
+type trapColourer struct{}
+
+func (trapColourer) Needs() Needs {
+	return NeedsTrap
+}
+
+func (trapColourer) Colour(p Params, _ []int) func(s Sample) color.RGBA {
+	gradient := lookupGradient(p.Palette)
+	return func(s Sample) color.RGBA {
+		return gradient.At(1 - math.Exp(-s.Trap*trapFalloff))
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	// the file a tour is read from, or - for stdin
+	scriptPath string
+	// the commands of the tour, or nil if there isn't one
+	tourCommands <-chan scriptCommand
+)
+
+// the commands a tour script can contain
+const (
+	scriptGoto       = "goto"
+	scriptZoomTo     = "zoom-to"
+	scriptLocation   = "location"
+	scriptWait       = "wait"
+	scriptScreenshot = "screenshot"
+	scriptQuit       = "quit"
+)
+
+// scriptCommand is a single line of a tour script
+type scriptCommand struct {
+	op   string
+	line int
+	// the view to move to, a zoom of 0 keeping the current zoom
+	centre pixel.Vec
+	zoom   float64
+	// the location to move to
+	location int
+	// how long to wait for, or how long to take getting to the view
+	duration time.Duration
+	// where to write the screenshot, or empty to number it after -export
+	path string
+}
+
+// parses a line of a tour script, returning false if it's blank or a comment:
+//
+//	goto <centre> <zoom>
+//	zoom-to <centre> [zoom] [over <duration>]
+//	location <name> [over <duration>]
+//	wait <duration>
+//	screenshot [path]
+//	quit
+//
+// Centres are complex numbers such as -0.7453+0.1127i, or real and imaginary components separated by a comma.
+func parseScriptLine(s string, line int) (scriptCommand, bool, error) {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return scriptCommand{}, false, nil
+	}
+	cmd := scriptCommand{op: fields[0], line: line}
+	args := fields[1:]
+
+	// zoom-to and location take their time from a trailing "over <duration>", and the transition time otherwise
+	if cmd.op == scriptZoomTo || cmd.op == scriptLocation {
+		cmd.duration = transitionTime
+		if n := len(args); n >= 2 && args[n-2] == "over" {
+			d, err := time.ParseDuration(args[n-1])
+			if err != nil || d < 0 {
+				return cmd, false, fmt.Errorf("line %d: invalid duration %q", line, args[n-1])
+			}
+			cmd.duration, args = d, args[:n-2]
+		}
+	}
+
+	var err error
+	switch cmd.op {
+	case scriptGoto, scriptZoomTo:
+		if len(args) < 1 || len(args) > 2 || cmd.op == scriptGoto && len(args) != 2 {
+			return cmd, false, fmt.Errorf("line %d: usage: %s", line, scriptUsage(cmd.op))
+		}
+		if cmd.centre, err = parseScriptCentre(args[0]); err != nil {
+			return cmd, false, fmt.Errorf("line %d: invalid centre %q: %s", line, args[0], err)
+		}
+		if len(args) == 2 {
+			if cmd.zoom, err = strconv.ParseFloat(strings.TrimSuffix(args[1], "x"), 64); err != nil || cmd.zoom <= 0 {
+				return cmd, false, fmt.Errorf("line %d: invalid zoom %q", line, args[1])
+			}
+		}
+	case scriptLocation:
+		if len(args) != 1 {
+			return cmd, false, fmt.Errorf("line %d: usage: %s", line, scriptUsage(cmd.op))
+		}
+		if cmd.location = lookupLocation(args[0]); cmd.location < 0 {
+			return cmd, false, fmt.Errorf("line %d: unknown location %q, expected one of %s", line, args[0],
+				strings.Join(locationNames(), ", "))
+		}
+	case scriptWait:
+		if len(args) != 1 {
+			return cmd, false, fmt.Errorf("line %d: usage: %s", line, scriptUsage(cmd.op))
+		}
+		if cmd.duration, err = time.ParseDuration(args[0]); err != nil || cmd.duration < 0 {
+			return cmd, false, fmt.Errorf("line %d: invalid duration %q", line, args[0])
+		}
+	case scriptScreenshot:
+		if len(args) > 1 {
+			return cmd, false, fmt.Errorf("line %d: usage: %s", line, scriptUsage(cmd.op))
+		}
+		if len(args) == 1 {
+			cmd.path = args[0]
+		}
+	case scriptQuit:
+		if len(args) != 0 {
+			return cmd, false, fmt.Errorf("line %d: usage: %s", line, scriptUsage(cmd.op))
+		}
+	default:
+		return cmd, false, fmt.Errorf("line %d: unknown command %q", line, cmd.op)
+	}
+	return cmd, true, nil
+}
+
+// returns the usage of a script command for error messages
+func scriptUsage(op string) string {
+	switch op {
+	case scriptGoto:
+		return "goto <centre> <zoom>"
+	case scriptZoomTo:
+		return "zoom-to <centre> [zoom] [over <duration>]"
+	case scriptLocation:
+		return "location <name> [over <duration>]"
+	case scriptWait:
+		return "wait <duration>"
+	case scriptScreenshot:
+		return "screenshot [path]"
+	}
+	return op
+}
+
+// parses a centre written as a complex number or as comma separated components
+func parseScriptCentre(s string) (pixel.Vec, error) {
+	if strings.Contains(s, ",") {
+		p, err := parsePoint(s)
+		return pixel.V(p.Re, p.Im), err
+	}
+	c, err := strconv.ParseComplex(s, 128)
+	if err != nil {
+		return pixel.ZV, fmt.Errorf("expected a complex number such as -0.75+0.1i")
+	}
+	return pixel.V(real(c), imag(c)), nil
+}
+
+// reads a whole tour script, so that a mistake anywhere in a file is reported before the tour starts
+func readScript(r io.Reader) ([]scriptCommand, error) {
+	var commands []scriptCommand
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		cmd, ok, err := parseScriptLine(scanner.Text(), line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands, scanner.Err()
+}
+
+// opens the -script tour, returning nil if there isn't one. A script file is read in full up front, while commands
+// piped to stdin run as they arrive, invalid ones being reported and skipped.
+func openScript() (<-chan scriptCommand, error) {
+	if scriptPath == "" {
+		return nil, nil
+	}
+	commands := make(chan scriptCommand)
+	if scriptPath == "-" {
+		go func() {
+			defer close(commands)
+			scanner := bufio.NewScanner(os.Stdin)
+			for line := 1; scanner.Scan(); line++ {
+				cmd, ok, err := parseScriptLine(scanner.Text(), line)
+				if err != nil {
+					fmt.Printf("invalid script command: %s\n", err)
+				} else if ok {
+					commands <- cmd
+				}
+			}
+		}()
+		return commands, nil
+	}
+
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	script, err := readScript(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script %s: %s", scriptPath, err)
+	}
+	go func() {
+		defer close(commands)
+		for _, cmd := range script {
+			commands <- cmd
+		}
+	}()
+	return commands, nil
+}
+
+// scriptRunner plays a tour script in the window, one command at a time
+type scriptRunner struct {
+	commands <-chan scriptCommand
+	// when the current wait ends
+	waitUntil time.Time
+	// receives the result of the screenshot being rendered, if there is one
+	shooting chan error
+	// how many screenshots have been numbered after -export
+	shots int
+}
+
+// returns the next command to run, or false if the previous one is still running or there isn't one yet. busy reports
+// whether the view is still moving to where the previous command sent it.
+func (s *scriptRunner) next(now time.Time, busy bool) (scriptCommand, bool) {
+	if s.commands == nil || busy || now.Before(s.waitUntil) {
+		return scriptCommand{}, false
+	}
+	if s.shooting != nil {
+		select {
+		case err := <-s.shooting:
+			if err != nil {
+				fmt.Printf("failed to take screenshot: %s\n", err)
+			}
+			s.shooting = nil
+		default:
+			return scriptCommand{}, false
+		}
+	}
+	select {
+	case cmd, ok := <-s.commands:
+		if !ok {
+			s.commands = nil
+			fmt.Println("Finished script")
+			return scriptCommand{}, false
+		}
+		logf(logDebug, "running script command", "line", cmd.line, "command", cmd.op)
+		if cmd.op == scriptWait {
+			s.waitUntil = now.Add(cmd.duration)
+		}
+		return cmd, true
+	default:
+		return scriptCommand{}, false
+	}
+}
+
+// renders p to the screenshot's path in the background, holding back the rest of the script until it's written
+func (s *scriptRunner) screenshot(cmd scriptCommand, p render.Params, b bookmark) {
+	path := cmd.path
+	if path == "" {
+		s.shots++
+		ext := filepath.Ext(exportPath)
+		path = fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(exportPath, ext), s.shots, ext)
+	}
+	s.shooting = make(chan error, 1)
+	go func() {
+		fmt.Printf("Taking %dx%d screenshot %s\n", p.Width, p.Height, path)
+		s.shooting <- writeImage(path, p, b, nil)
+	}()
+}
+
+// returns the bounds of the view the command moves to in a pane of the given bounds, from the current bounds
+func (cmd scriptCommand) bounds(current, paneBounds pixel.Rect) pixel.Rect {
+	b := newBookmark(current)
+	b.Centre = point{Re: cmd.centre.X, Im: cmd.centre.Y}
+	if cmd.zoom > 0 {
+		b.Zoom = cmd.zoom
+	}
+	return b.bounds(paneBounds)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+func TestParseScriptLine(t *testing.T) {
+	defer func(d time.Duration) { transitionTime = d }(transitionTime)
+	transitionTime = time.Second
+
+	tests := []struct {
+		line    string
+		want    scriptCommand
+		wantOK  bool
+		wantErr bool
+	}{
+		{line: ""},
+		{line: "   "},
+		{line: "# a comment"},
+		{line: "goto -0.7453+0.1127i 600", wantOK: true,
+			want: scriptCommand{op: scriptGoto, centre: pixel.V(-0.7453, 0.1127), zoom: 600}},
+		{line: "goto -0.7453,0.1127 600x # the seahorse valley", wantOK: true,
+			want: scriptCommand{op: scriptGoto, centre: pixel.V(-0.7453, 0.1127), zoom: 600}},
+		{line: "zoom-to -1+0i", wantOK: true,
+			want: scriptCommand{op: scriptZoomTo, centre: pixel.V(-1, 0), duration: time.Second}},
+		{line: "zoom-to -1+0i 20 over 5s", wantOK: true,
+			want: scriptCommand{op: scriptZoomTo, centre: pixel.V(-1, 0), zoom: 20, duration: 5 * time.Second}},
+		{line: "location elephant over 0s", wantOK: true,
+			want: scriptCommand{op: scriptLocation, location: lookupLocation("elephant")}},
+		{line: "wait 2.5s", wantOK: true, want: scriptCommand{op: scriptWait, duration: 2500 * time.Millisecond}},
+		{line: "screenshot", wantOK: true, want: scriptCommand{op: scriptScreenshot}},
+		{line: "screenshot tour.png", wantOK: true, want: scriptCommand{op: scriptScreenshot, path: "tour.png"}},
+		{line: "quit", wantOK: true, want: scriptCommand{op: scriptQuit}},
+
+		{line: "fly-to 0+0i", wantErr: true},
+		{line: "goto 0+0i", wantErr: true},
+		{line: "goto 0+0i 10 20", wantErr: true},
+		{line: "goto zero 10", wantErr: true},
+		{line: "goto 0,zero 10", wantErr: true},
+		{line: "goto 0+0i -10", wantErr: true},
+		{line: "goto 0+0i 0", wantErr: true},
+		{line: "zoom-to", wantErr: true},
+		{line: "zoom-to 0+0i over soon", wantErr: true},
+		{line: "zoom-to 0+0i over -1s", wantErr: true},
+		{line: "location", wantErr: true},
+		{line: "location atlantis", wantErr: true},
+		{line: "wait", wantErr: true},
+		{line: "wait forever", wantErr: true},
+		{line: "wait -1s", wantErr: true},
+		{line: "screenshot a.png b.png", wantErr: true},
+		{line: "quit now", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseScriptLine(tt.line, 3)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expected an error parsing %q, got %+v", tt.line, got)
+			} else if !strings.HasPrefix(err.Error(), "line 3: ") {
+				t.Errorf("expected the error parsing %q to give its line, got %q", tt.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tt.line, err)
+			continue
+		}
+		if tt.wantOK {
+			tt.want.line = 3
+		}
+		if ok != tt.wantOK || ok && got != tt.want {
+			t.Errorf("expected %q to parse as %+v (%t), got %+v (%t)", tt.line, tt.want, tt.wantOK, got, ok)
+		}
+	}
+}
+
+func TestReadScript(t *testing.T) {
+	commands, err := readScript(strings.NewReader("# tour\ngoto 0+0i 1\n\nwait 1s\nquit\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(commands) != 3 || commands[0].line != 2 || commands[1].line != 4 || commands[2].line != 5 {
+		t.Errorf("expected the three commands on lines 2, 4 and 5, got %+v", commands)
+	}
+
+	// a mistake anywhere in the script is reported before any of it runs
+	if _, err := readScript(strings.NewReader("goto 0+0i 1\nwait 1s\nzoom-to\n")); err == nil ||
+		!strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("expected an error on line 3, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+)
+
+// minimum drag distance in pixels before a mouse drag is treated as a selection rather than a click
+const minSelectionSize = 4
+
+var selectionColour = pixel.RGB(1, 1, 1)
+
+// maps a position in window pixel space to the complex plane
+func windowToPlane(pos pixel.Vec, windowBounds, bounds pixel.Rect) pixel.Vec {
+	rel := pos.Sub(windowBounds.Min).ScaledXY(pixel.V(1/windowBounds.W(), 1/windowBounds.H()))
+	return bounds.Min.Add(rel.ScaledXY(bounds.Size()))
+}
+
+// maps a position on the complex plane to window pixel space, the inverse of windowToPlane
+func planeToWindow(pos pixel.Vec, windowBounds, bounds pixel.Rect) pixel.Vec {
+	rel := pos.Sub(bounds.Min).ScaledXY(pixel.V(1/bounds.W(), 1/bounds.H()))
+	return windowBounds.Min.Add(rel.ScaledXY(windowBounds.Size()))
+}
+
+// grows the rect spanned by two corners along its shorter side so that it matches the aspect ratio of target, keeping
+// it centred on the original rect
+func aspectCorrect(a, b pixel.Vec, target pixel.Rect) pixel.Rect {
+	r := pixel.R(a.X, a.Y, b.X, b.Y).Norm()
+	aspect := target.W() / target.H()
+
+	w, h := r.W(), r.H()
+	if w/h > aspect {
+		h = w / aspect
+	} else {
+		w = h * aspect
+	}
+	return r.Resized(r.Center(), pixel.V(w, h))
+}
+
+// returns whether the drag between two window positions is large enough to be a selection
+func isSelection(a, b pixel.Vec) bool {
+	return math.Abs(a.X-b.X) >= minSelectionSize && math.Abs(a.Y-b.Y) >= minSelectionSize
+}
+
+// draws the outline of the region a drag will zoom to
+func drawSelection(imd *imdraw.IMDraw, a, b pixel.Vec, windowBounds pixel.Rect) {
+	r := aspectCorrect(a, b, windowBounds)
+	imd.Color = selectionColour
+	imd.Push(r.Min, r.Max)
+	imd.Rectangle(1)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestPlaneMapping(t *testing.T) {
+	windowBounds := pixel.R(0, 0, 800, 600)
+	bounds := pixel.R(-2.5, -1.5, 1.5, 1.5)
+	tests := []struct {
+		name        string
+		pos, want   pixel.Vec
+		planeBounds pixel.Rect
+	}{
+		{name: "origin", pos: pixel.V(0, 0), want: pixel.V(-2.5, -1.5), planeBounds: bounds},
+		{name: "centre", pos: pixel.V(400, 300), want: pixel.V(-0.5, 0), planeBounds: bounds},
+		{name: "corner", pos: pixel.V(800, 600), want: pixel.V(1.5, 1.5), planeBounds: bounds},
+		{name: "outside the window", pos: pixel.V(-400, 900), want: pixel.V(-4.5, 3), planeBounds: bounds},
+	}
+	for _, tt := range tests {
+		if got := windowToPlane(tt.pos, windowBounds, tt.planeBounds); got != tt.want {
+			t.Errorf("%s: expected %v to map to %v on the plane, got %v", tt.name, tt.pos, tt.want, got)
+		}
+		if got := planeToWindow(tt.want, windowBounds, tt.planeBounds); got != tt.pos {
+			t.Errorf("%s: expected %v to map back to %v in the window, got %v", tt.name, tt.want, tt.pos, got)
+		}
+	}
+}
+
+func TestAspectCorrect(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   pixel.Vec
+		target pixel.Rect
+		want   pixel.Rect
+	}{
+		{name: "wide drag", a: pixel.V(0, 0), b: pixel.V(400, 100), target: pixel.R(0, 0, 800, 800),
+			want: pixel.R(0, -150, 400, 250)},
+		{name: "tall drag", a: pixel.V(0, 0), b: pixel.V(100, 400), target: pixel.R(0, 0, 800, 800),
+			want: pixel.R(-150, 0, 250, 400)},
+		{name: "wide target", a: pixel.V(0, 0), b: pixel.V(100, 100), target: pixel.R(0, 0, 800, 400),
+			want: pixel.R(-50, 0, 150, 100)},
+		{name: "reversed corners", a: pixel.V(400, 100), b: pixel.V(0, 0), target: pixel.R(0, 0, 800, 800),
+			want: pixel.R(0, -150, 400, 250)},
+		{name: "matching aspect", a: pixel.V(10, 20), b: pixel.V(90, 60), target: pixel.R(0, 0, 200, 100),
+			want: pixel.R(10, 20, 90, 60)},
+	}
+	for _, tt := range tests {
+		if got := aspectCorrect(tt.a, tt.b, tt.target); got != tt.want {
+			t.Errorf("%s: expected the drag from %v to %v to select %v, got %v", tt.name, tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestIsSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b pixel.Vec
+		want bool
+	}{
+		{name: "click", a: pixel.V(100, 100), b: pixel.V(100, 100), want: false},
+		{name: "small drag", a: pixel.V(100, 100), b: pixel.V(103, 103), want: false},
+		{name: "horizontal line", a: pixel.V(100, 100), b: pixel.V(200, 101), want: false},
+		{name: "minimum size", a: pixel.V(100, 100), b: pixel.V(96, 104), want: true},
+		{name: "large drag", a: pixel.V(100, 100), b: pixel.V(300, 250), want: true},
+	}
+	for _, tt := range tests {
+		if got := isSelection(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: expected selection %t for the drag from %v to %v, got %t", tt.name, tt.want, tt.a, tt.b, got)
+		}
+	}
+}
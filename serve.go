@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+const (
+	// the width and height of a served tile in pixels
+	serveTileSize = 256
+	// the deepest zoom level served, beyond which adjacent pixels can no longer be told apart in double precision
+	serveMaxZoom = 40
+	// the number of encoded tiles kept in memory
+	serveCacheSize = 4096
+)
+
+var serveAddr string
+
+// tileServer renders XYZ map tiles on demand. Zoom level 0 is a single tile covering the plane from -2-2i to 2+2i,
+// and each subsequent level splits every tile into four.
+type tileServer struct {
+	mu    sync.Mutex
+	tiles map[tileKey][]byte
+	// cached tiles in the order they were added, oldest first
+	order []tileKey
+}
+
+type tileKey struct {
+	z, x, y int
+}
+
+// serves tiles at /tiles/{z}/{x}/{y}.png and a map viewer at / until the server fails
+func serve() error {
+	ts := &tileServer{tiles: make(map[tileKey][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tiles/", ts.handleTile)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, viewerPage, serveMaxZoom)
+	})
+
+	fmt.Printf("Serving %s tiles on %s\n", fractalName, serveAddr)
+	return http.ListenAndServe(serveAddr, mux)
+}
+
+func (ts *tileServer) handleTile(w http.ResponseWriter, r *http.Request) {
+	key, ok := parseTilePath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	ts.mu.Lock()
+	data, ok := ts.tiles[key]
+	ts.mu.Unlock()
+
+	if !ok {
+		// stop rendering if the viewer has moved on and abandoned the request
+		img, err := render.Render(r.Context(), tileParams(key))
+		if err != nil {
+			if r.Context().Err() == nil {
+				http.Error(w, fmt.Sprintf("failed to render tile: %s", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode tile: %s", err), http.StatusInternalServerError)
+			return
+		}
+		data = buf.Bytes()
+		ts.store(key, data)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(data)
+}
+
+// caches an encoded tile, evicting the oldest tiles once the cache is full
+func (ts *tileServer) store(key tileKey, data []byte) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, ok := ts.tiles[key]; ok {
+		return
+	}
+	for len(ts.order) >= serveCacheSize {
+		delete(ts.tiles, ts.order[0])
+		ts.order = ts.order[1:]
+	}
+	ts.tiles[key] = data
+	ts.order = append(ts.order, key)
+}
+
+// parses a /tiles/{z}/{x}/{y}.png path, reporting whether it refers to a tile which exists
+func parseTilePath(path string) (tileKey, bool) {
+	path = strings.TrimPrefix(path, "/tiles/")
+	if !strings.HasSuffix(path, ".png") {
+		return tileKey{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".png"), "/")
+	if len(parts) != 3 {
+		return tileKey{}, false
+	}
+
+	var coords [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return tileKey{}, false
+		}
+		coords[i] = n
+	}
+
+	key := tileKey{z: coords[0], x: coords[1], y: coords[2]}
+	if key.z > serveMaxZoom || key.x >= 1<<key.z || key.y >= 1<<key.z {
+		return tileKey{}, false
+	}
+	return key, true
+}
+
+// describes the render of a tile using the current settings. Tiles are coloured independently, so colourings which
+// depend on the whole image, such as histogram, may show seams between tiles.
+func tileParams(key tileKey) render.Params {
+	// tile rows count down from the top of the plane
+	span := defaultSpan / math.Exp2(float64(key.z))
+	min := pixel.V(-defaultSpan/2+float64(key.x)*span, defaultSpan/2-float64(key.y+1)*span)
+	bounds := pixel.R(min.X, min.Y, min.X+span, min.Y+span)
+	return newParams(bounds, pixel.V(serveTileSize, serveTileSize))
+}
+
+// a Leaflet map browsing the served tiles
+const viewerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>Mandelbrot</title>
+    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css">
+    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
+    <style>html, body, #map { margin: 0; height: 100%%; background: #000; }</style>
+</head>
+<body>
+<div id="map"></div>
+<script>
+    const map = L.map("map", {crs: L.CRS.Simple, minZoom: 0, maxZoom: %d}).setView([-128, 128], 1);
+    L.tileLayer("/tiles/{z}/{x}/{y}.png", {tileSize: 256, noWrap: true, bounds: [[0, 0], [-256, 256]]}).addTo(map);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	sessionPath      string
+	autosaveInterval time.Duration
+	restoreSession   bool
+	// the session saved by the last run, if there is one
+	lastSession *session
+)
+
+// session is the exploration state autosaved while exploring, so that it can be restored after the process dies
+type session struct {
+	Bookmark bookmark  `json:"bookmark"`
+	Saved    time.Time `json:"saved"`
+	// whether the session ended by quitting rather than being cut short
+	Clean bool `json:"clean"`
+}
+
+// returns where sessions are saved unless -session says otherwise, in the user's config directory if there is one
+func defaultSessionPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "session.json"
+	}
+	return filepath.Join(dir, "mandelbrot", "session.json")
+}
+
+// writes the session to path, replacing the previous one in a single step so that a crash part way through a save
+// can't leave a truncated file behind
+func saveSession(path string, s session) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// reads the session at path, returning nil without an error if there isn't one
+func loadSession(path string) (*session, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %s", path, err)
+	}
+	if err := s.Bookmark.validate(); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %s", path, err)
+	}
+	return &s, nil
+}
+
+// autosaver saves the session every autosaveInterval while the view keeps changing
+type autosaver struct {
+	saved    bookmark
+	lastSave time.Time
+}
+
+// saves b as the session if it has changed and the interval has passed since the last save
+func (a *autosaver) update(b bookmark, now time.Time) {
+	if autosaveInterval <= 0 || now.Sub(a.lastSave) < autosaveInterval || sameBookmark(b, a.saved) {
+		return
+	}
+	a.lastSave = now
+	if err := saveSession(sessionPath, session{Bookmark: b, Saved: now}); err != nil {
+		logf(logWarn, "failed to autosave session", "path", sessionPath, "err", err)
+		return
+	}
+	a.saved = b
+	logf(logDebug, "autosaved session", "path", sessionPath, "zoom", b.Zoom)
+}
+
+// saves b as the session on quitting, marking it as having ended cleanly
+func (a *autosaver) finish(b bookmark) {
+	if autosaveInterval <= 0 {
+		return
+	}
+	if err := saveSession(sessionPath, session{Bookmark: b, Saved: time.Now(), Clean: true}); err != nil {
+		fmt.Printf("failed to save session: %s\n", err)
+	}
+}
+
+// reports whether two bookmarks describe the same state
+func sameBookmark(a, b bookmark) bool {
+	if (a.Seed == nil) != (b.Seed == nil) || a.Seed != nil && *a.Seed != *b.Seed {
+		return false
+	}
+	a.Seed, b.Seed = nil, nil
+	return a == b
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// the URI scheme of shareable states, e.g. mandelbrot:?centre=-0.7453,0.1127&iterations=500&zoom=600
+const shareScheme = "mandelbrot"
+
+var (
+	fromState string
+	// the state decoded from fromState
+	sharedBookmark bookmark
+)
+
+// encodes the bookmark as a compact URI which decodeState reproduces it from exactly. Floats are written with the
+// fewest digits that parse back to the same value, so no precision is lost at any zoom level.
+func encodeState(b bookmark) string {
+	v := url.Values{}
+	v.Set("centre", formatPoint(b.Centre))
+	v.Set("zoom", formatFloat(b.Zoom))
+	v.Set("iterations", strconv.FormatUint(uint64(b.Iterations), 10))
+	if b.Adaptive {
+		v.Set("adaptive", "true")
+	}
+	setString := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	setString("fractal", b.Fractal)
+	setString("formula", b.Formula)
+	if b.Fractal == "multibrot" {
+		v.Set("exponent", formatFloat(b.Exponent))
+	}
+	if b.Fractal == "newton" {
+		setString("polynomial", b.Polynomial)
+	}
+	if b.Bailout != 0 {
+		v.Set("bailout", formatFloat(b.Bailout))
+	}
+	setString("norm", b.Norm)
+	setString("colouring", b.Colouring)
+	if b.Contrast != 0 {
+		v.Set("contrast", strconv.FormatUint(uint64(b.Contrast), 10))
+	}
+	setString("palette", b.Palette)
+	setString("interior", b.Interior)
+	setString("trap", b.Trap)
+	// the levels are left out while they're neutral
+	if b.Brightness != 0 {
+		v.Set("brightness", formatFloat(b.Brightness))
+	}
+	if b.LuminanceContrast != 0 {
+		v.Set("luminance-contrast", formatFloat(b.LuminanceContrast))
+	}
+	if b.Gamma != 0 {
+		v.Set("gamma", formatFloat(b.Gamma))
+	}
+	if b.Seed != nil {
+		v.Set("seed", formatPoint(*b.Seed))
+	}
+
+	// the values are mostly numbers and names, so commas are the only characters worth keeping unescaped for legibility
+	return shareScheme + ":?" + strings.Replace(v.Encode(), "%2C", ",", -1)
+}
+
+// decodes a state encoded by encodeState
+func decodeState(s string) (bookmark, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return bookmark{}, err
+	}
+	if u.Scheme != shareScheme {
+		return bookmark{}, fmt.Errorf("expected a %s: URI", shareScheme)
+	}
+	v, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return bookmark{}, err
+	}
+
+	b := bookmark{
+		Fractal:    v.Get("fractal"),
+		Formula:    v.Get("formula"),
+		Polynomial: v.Get("polynomial"),
+		Norm:       v.Get("norm"),
+		Colouring:  v.Get("colouring"),
+		Palette:    v.Get("palette"),
+		Interior:   v.Get("interior"),
+		Trap:       v.Get("trap"),
+	}
+	// parses the value of key if it's present, stopping at the first invalid value
+	parse := func(key string, parse func(string) error) {
+		if value := v.Get(key); value != "" && err == nil {
+			if parse(value) != nil {
+				err = fmt.Errorf("invalid %s %q", key, value)
+			}
+		}
+	}
+	parse("centre", func(s string) (err error) {
+		b.Centre, err = parsePoint(s)
+		return err
+	})
+	parse("zoom", func(s string) (err error) {
+		b.Zoom, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("iterations", func(s string) error {
+		n, err := strconv.ParseUint(s, 10, 0)
+		b.Iterations = uint(n)
+		return err
+	})
+	parse("adaptive", func(s string) (err error) {
+		b.Adaptive, err = strconv.ParseBool(s)
+		return err
+	})
+	parse("exponent", func(s string) (err error) {
+		b.Exponent, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("bailout", func(s string) (err error) {
+		b.Bailout, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("contrast", func(s string) error {
+		n, err := strconv.ParseUint(s, 10, 0)
+		b.Contrast = uint(n)
+		return err
+	})
+	parse("brightness", func(s string) (err error) {
+		b.Brightness, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("luminance-contrast", func(s string) (err error) {
+		b.LuminanceContrast, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("gamma", func(s string) (err error) {
+		b.Gamma, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("seed", func(s string) error {
+		seed, err := parsePoint(s)
+		b.Seed = &seed
+		return err
+	})
+	if err != nil {
+		return bookmark{}, err
+	}
+
+	if err := b.validate(); err != nil {
+		return bookmark{}, err
+	}
+	return b, nil
+}
+
+// formats f with the fewest digits that parse back to it, dropping the plus sign of large exponents which would
+// otherwise need escaping in a URI
+func formatFloat(f float64) string {
+	return strings.Replace(strconv.FormatFloat(f, 'g', -1, 64), "e+", "e", 1)
+}
+
+// formats a point as its real and imaginary components separated by a comma
+func formatPoint(p point) string {
+	return formatFloat(p.Re) + "," + formatFloat(p.Im)
+}
+
+func parsePoint(s string) (point, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return point{}, fmt.Errorf("expected two comma separated numbers")
+	}
+	re, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return point{}, err
+	}
+	im, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return point{}, err
+	}
+	return point{Re: re, Im: im}, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBookmarkRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		b    bookmark
+	}{
+		{name: "neutral levels", b: bookmark{Centre: point{Re: -0.7453, Im: 0.1127}, Zoom: 600, Iterations: 500}},
+		{name: "levels", b: bookmark{
+			Centre:            point{Re: -0.743643887037151, Im: 0.13182590420533},
+			Zoom:              3e11,
+			Iterations:        4000,
+			Colouring:         "smooth",
+			Palette:           "viridis",
+			Brightness:        -0.15,
+			LuminanceContrast: 1.35,
+			Gamma:             0.8,
+		}},
+		{name: "seed", b: bookmark{
+			Centre:     point{Re: 0.1, Im: -0.2},
+			Zoom:       1,
+			Iterations: 100,
+			Brightness: 0.05,
+			Gamma:      2.2,
+			Seed:       &point{Re: -0.123, Im: 0.745},
+		}},
+	}
+
+	dir, err := ioutil.TempDir("", "bookmark")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		state := encodeState(tt.b)
+		decoded, err := decodeState(state)
+		if err != nil {
+			t.Errorf("%s: unexpected error decoding %s: %s", tt.name, state, err)
+		} else if !reflect.DeepEqual(decoded, tt.b) {
+			t.Errorf("%s: expected %s to decode to %+v, got %+v", tt.name, state, tt.b, decoded)
+		}
+
+		path := filepath.Join(dir, "bookmark.json")
+		if err := saveBookmark(path, tt.b); err != nil {
+			t.Fatalf("%s: unexpected error saving: %s", tt.name, err)
+		}
+		loaded, err := loadBookmark(path)
+		if err != nil {
+			t.Errorf("%s: unexpected error loading: %s", tt.name, err)
+		} else if !sameBookmark(loaded, tt.b) {
+			t.Errorf("%s: expected the bookmark to load as %+v, got %+v", tt.name, tt.b, loaded)
+		}
+	}
+
+	// changing only the levels is a change of state
+	a := tests[1].b
+	b := a
+	b.Gamma = 1.2
+	if sameBookmark(a, b) {
+		t.Errorf("expected bookmarks with different levels to differ")
+	}
+}
+
+func TestDecodeStateInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{name: "NaN zoom", state: "mandelbrot:?centre=0,0&zoom=NaN&iterations=100"},
+		{name: "infinite zoom", state: "mandelbrot:?centre=0,0&zoom=Inf&iterations=100"},
+		{name: "negative zoom", state: "mandelbrot:?centre=0,0&zoom=-1&iterations=100"},
+		{name: "NaN centre", state: "mandelbrot:?centre=NaN,0&zoom=1&iterations=100"},
+		{name: "infinite centre", state: "mandelbrot:?centre=0,-Inf&zoom=1&iterations=100"},
+		{name: "infinite seed", state: "mandelbrot:?centre=0,0&zoom=1&iterations=100&seed=Inf,0"},
+	}
+	for _, tt := range tests {
+		if b, err := decodeState(tt.state); err == nil {
+			t.Errorf("%s: expected an error decoding %s, got %+v", tt.name, tt.state, b)
+		}
+	}
+}
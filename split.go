@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+var (
+	// whether the window is split between the mandelbrot and the Julia set of the point under the cursor
+	splitView bool
+	// the point the Julia set is of, which follows the cursor as it moves over the fractal. The Douady rabbit is shown
+	// until then.
+	juliaSeed = pixel.V(-0.123, 0.745)
+)
+
+// returns the regions of the window the fractal and, in split view, the Julia set are drawn in. The fractal takes the
+// whole window otherwise.
+func panes(windowBounds pixel.Rect, split bool) (fractal, julia pixel.Rect) {
+	if !split {
+		return windowBounds, pixel.Rect{}
+	}
+	mid := windowBounds.Min.X + math.Floor(windowBounds.W()/2)
+	fractal = pixel.R(windowBounds.Min.X, windowBounds.Min.Y, mid, windowBounds.Max.Y)
+	julia = pixel.R(mid, windowBounds.Min.Y, windowBounds.Max.X, windowBounds.Max.Y)
+	return fractal, julia
+}
+
+// describes a render of the Julia set of seed filling pane, centred on the origin at 1x magnification where Julia
+// sets fit within the default span
+func juliaParams(pane pixel.Rect, seed pixel.Vec) render.Params {
+	p := newParams(juliaBounds(pane), pane.Size())
+	p.Julia = true
+	p.Seed = complex(seed.X, seed.Y)
+	return p
+}
+
+// returns the region of the plane the Julia set pane shows
+func juliaBounds(pane pixel.Rect) pixel.Rect {
+	size := pane.Size()
+	return centredRect(pixel.ZV, size.Scaled(defaultSpan/math.Min(size.X, size.Y)))
+}
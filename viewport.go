@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+// how often frames which are slow to render are drawn part way through
+const partialFrameInterval = 200 * time.Millisecond
+
+// how long each slice of a frame rendered between display frames takes, or 0 to render frames in one go
+var frameBudget time.Duration
+
+// viewport renders frames in the background and holds the latest one for drawing. Each viewport has its own renderer
+// and tile cache, so several can be shown side by side.
+type viewport struct {
+	// mutex serialises access to the drawable pixel data
+	mu     sync.RWMutex
+	sprite *pixel.Sprite
+	// the params the current sprite was rendered with, snapped to the pixel it was really rendered at, used to fit it to
+	// the view until the next frame lands
+	spriteParams render.Params
+	// how long the current sprite took to render, and its cache and worker stats
+	renderTime time.Duration
+	frame      frameStats
+	// the debug overlay of the current sprite, or nil if none is being drawn
+	overlaySprite *pixel.Sprite
+	// the part of the frame rendered so far, drawn over the current sprite, and the fraction of the frame it covers.
+	// partial is nil when no frame has been rendering for longer than partialFrameInterval.
+	partial       *pixel.Sprite
+	partialParams render.Params
+	progress      float64
+	// tiles computed for previous frames, so that panning only renders newly exposed areas
+	tiles *render.TileCache
+	// the cache and worker stats as of the last frame
+	cacheStats render.CacheStats
+	workStats  render.WorkStats
+
+	// the params the background renderer should be working on, the last params it started rendering and a func to
+	// abort the render in progress
+	renderMu      sync.Mutex
+	renderChanged *sync.Cond
+	params        render.Params
+	startedParams render.Params
+	cancel        func()
+	// the debug overlay drawn over frames, one of render.Overlays, or empty for none
+	overlay string
+	// signalled each time the viewport is drawn, for frames rendered in slices to take the next slice
+	drawn chan struct{}
+}
+
+// frameStats describes how a frame was rendered, for the HUD
+type frameStats struct {
+	hits, misses, mirrored int
+	utilisation            float64
+}
+
+func newViewport() *viewport {
+	v := &viewport{
+		tiles:  render.NewTileCache(),
+		cancel: func() {},
+		drawn:  make(chan struct{}, 1),
+	}
+	v.renderChanged = sync.NewCond(&v.renderMu)
+	return v
+}
+
+// renders p straight away, then keeps rendering the latest params passed to setParams in the background
+func (v *viewport) start(p render.Params) {
+	v.params, v.startedParams = p, p
+	v.generate(context.Background(), p)
+	v.run()
+}
+
+// keeps rendering the latest params passed to setParams in the background
+func (v *viewport) run() {
+	go func() {
+		for {
+			ctx, p := v.nextRender()
+			v.generate(ctx, p)
+		}
+	}()
+}
+
+// publishes new params to the background renderer, aborting the in-flight render if they have changed
+func (v *viewport) setParams(p render.Params) {
+	v.renderMu.Lock()
+	defer v.renderMu.Unlock()
+
+	if p == v.params {
+		return
+	}
+	v.params = p
+	v.cancel()
+	v.renderChanged.Signal()
+}
+
+// switches the debug overlay drawn over frames to one of render.Overlays, or turns it off if overlay is empty. The
+// current frame is rendered again to draw it, which is quick as its tiles are cached.
+func (v *viewport) setOverlay(overlay string) {
+	v.renderMu.Lock()
+	defer v.renderMu.Unlock()
+
+	v.overlay = overlay
+	v.startedParams = render.Params{}
+	v.renderChanged.Signal()
+}
+
+// blocks until there are new params to render, returning them along with a context which is cancelled as soon as the
+// params change
+func (v *viewport) nextRender() (context.Context, render.Params) {
+	v.renderMu.Lock()
+	defer v.renderMu.Unlock()
+
+	for v.params == v.startedParams {
+		v.renderChanged.Wait()
+	}
+
+	// release the previous render's context
+	v.cancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	v.startedParams = v.params
+	return ctx, v.params
+}
+
+// generates a fresh frame represented in pixel.Sprite form, abandoning it if ctx is cancelled part way through
+func (v *viewport) generate(ctx context.Context, p render.Params) {
+	// minimised windows have no area to render into
+	if p.Width < 1 || p.Height < 1 {
+		return
+	}
+
+	if frameBudget > 0 && render.CanProgress(p) {
+		v.generateSlices(ctx, p)
+		return
+	}
+
+	// render into a fresh buffer so that an abandoned frame never reaches the screen
+	start := time.Now()
+	img, err := v.tiles.RenderProgress(ctx, p, partialFrameInterval, func(done float64, partial *image.RGBA) {
+		pixelData := pixel.PictureDataFromImage(partial)
+		partialSprite := pixel.NewSprite(pixelData, pixelData.Bounds())
+		v.mu.Lock()
+		v.partial, v.partialParams, v.progress = partialSprite, render.SnapToGrid(p), done
+		v.mu.Unlock()
+	})
+	if err != nil {
+		v.fail(ctx, err, time.Since(start))
+		return
+	}
+	v.publish(p, render.SnapToGrid(p), img, time.Since(start))
+}
+
+// generates a fresh frame a slice of frameBudget at a time, taking the next slice once the viewport has been drawn so
+// that rendering never holds up the display. Each slice carries on iterating where the last one stopped, and the frame
+// is drawn as it improves.
+func (v *viewport) generateSlices(ctx context.Context, p render.Params) {
+	start := time.Now()
+	pr, err := render.NewProgressive(p)
+	if err != nil {
+		v.fail(ctx, err, time.Since(start))
+		return
+	}
+	for {
+		done, err := pr.Step(ctx, frameBudget)
+		if err != nil {
+			v.fail(ctx, err, time.Since(start))
+			return
+		}
+		if done {
+			break
+		}
+
+		pixelData := pixel.PictureDataFromImage(pr.Image())
+		partialSprite := pixel.NewSprite(pixelData, pixelData.Bounds())
+		v.mu.Lock()
+		v.partial, v.partialParams, v.progress = partialSprite, p, pr.Progress()
+		v.mu.Unlock()
+		select {
+		case <-v.drawn:
+		case <-ctx.Done():
+		}
+	}
+	v.publish(p, p, pr.Image(), time.Since(start))
+}
+
+// drops a frame which failed after elapsed. Frames abandoned as ctx was cancelled have their params forgotten, so that
+// they're rendered again if the view returns to them before the next render starts, e.g. after panning away and
+// straight back. Frames which failed for any other reason would only fail again, so their params are kept until the
+// view changes.
+func (v *viewport) fail(ctx context.Context, err error, elapsed time.Duration) {
+	v.mu.Lock()
+	v.partial = nil
+	v.mu.Unlock()
+
+	if ctx.Err() == nil {
+		logf(logWarn, "failed to render frame", "err", err)
+		return
+	}
+	framesAbandoned.Add(1)
+	logf(logDebug, "frame abandoned", "after", elapsed, "reason", err)
+	v.renderMu.Lock()
+	v.startedParams = render.Params{}
+	v.renderMu.Unlock()
+}
+
+// replaces the current sprite with the frame img rendered with p, which took renderTime. spriteParams are the params
+// it was really rendered at, e.g. after snapping p to the tile grid.
+func (v *viewport) publish(p, spriteParams render.Params, img *image.RGBA, renderTime time.Duration) {
+	// the stats cover any frames abandoned since the last one completed too
+	cacheStats, workStats := v.tiles.Stats(), render.ReadWorkStats()
+	hits, misses := cacheStats.Hits-v.cacheStats.Hits, cacheStats.Misses-v.cacheStats.Misses
+	mirrored := cacheStats.Mirrored - v.cacheStats.Mirrored
+	utilisation := workStats.Sub(v.workStats).Utilisation()
+	v.cacheStats, v.workStats = cacheStats, workStats
+	recordFrame(renderTime, hits, misses)
+	logf(logDebug, "frame rendered", "width", p.Width, "height", p.Height, "iterations", p.Iterations,
+		"precision", p.SelectedPrecision(), "simd", p.SIMD && render.HasSIMD(), "render_time", renderTime,
+		"cache_hits", hits, "cache_misses", misses, "cache_mirrored", mirrored, "utilisation", utilisation)
+	pixelData := pixel.PictureDataFromImage(img)
+	newSprite := pixel.NewSprite(pixelData, pixelData.Bounds())
+
+	v.renderMu.Lock()
+	overlay := v.overlay
+	v.renderMu.Unlock()
+	var overlaySprite *pixel.Sprite
+	if overlay != "" {
+		overlayData := pixel.PictureDataFromImage(v.tiles.Overlay(p, overlay))
+		overlaySprite = pixel.NewSprite(overlayData, overlayData.Bounds())
+	}
+
+	v.mu.Lock()
+	v.sprite = newSprite
+	v.spriteParams = spriteParams
+	v.renderTime = renderTime
+	v.frame = frameStats{hits: hits, misses: misses, mirrored: mirrored, utilisation: utilisation}
+	v.overlaySprite = overlaySprite
+	v.partial = nil
+	v.mu.Unlock()
+}
+
+// draws the latest frame centred on centre, stretched to fit the view described by p if it was rendered for another
+// view, with its debug overlay and any frame still rendering drawn over it. It returns how long the latest frame took
+// to render, and the fraction of the frame still rendering done, or 1 if none is being drawn.
+func (v *viewport) draw(win *pixelgl.Window, p render.Params, centre pixel.Vec) (time.Duration, float64) {
+	v.mu.RLock()
+	sprite, spriteParams, renderTime := v.sprite, v.spriteParams, v.renderTime
+	overlaySprite := v.overlaySprite
+	partial, partialParams, progress := v.partial, v.partialParams, v.progress
+	v.mu.RUnlock()
+
+	if sprite != nil {
+		sprite.Draw(win, spriteMatrix(spriteParams, p, centre))
+	}
+	if overlaySprite != nil {
+		overlaySprite.Draw(win, spriteMatrix(spriteParams, p, centre))
+	}
+	if partial == nil {
+		return renderTime, 1
+	}
+	partial.Draw(win, spriteMatrix(partialParams, p, centre))
+	select {
+	case v.drawn <- struct{}{}:
+	default:
+	}
+	return renderTime, progress
+}
+
+// returns the cache and worker stats of the latest frame
+func (v *viewport) stats() frameStats {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.frame
+}
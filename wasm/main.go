@@ -0,0 +1,265 @@
+//go:build js && wasm
+
+// Command wasm runs the explorer in a browser, rendering into an HTML canvas with the CPU renderer. Drag to pan and
+// scroll or pinch to zoom. The initial settings can be changed through the page's query string, e.g.
+// ?fractal=tricorn&iterations=500&colouring=histogram&palette=fire.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"syscall/js"
+
+	"github.com/jemgunay/mandelbrot/render"
+)
+
+const (
+	// the factor the view is scaled by per mouse wheel notch
+	wheelZoom = 1.2
+	// the width and height of the complex plane shown at start up
+	defaultSpan = 4
+)
+
+var (
+	document = js.Global().Get("document")
+	canvas   js.Value
+	canvas2D js.Value
+
+	params = render.Params{
+		Centre:     complex(-0.5, 0),
+		Iterations: 200,
+		Fractal:    "mandelbrot",
+		Exponent:   3,
+	}
+	// set when params have changed since the last frame was drawn
+	dirty = true
+	// tiles computed for previous frames, so that panning only renders newly exposed areas
+	tileCache = render.NewTileCache()
+
+	// the last pointer position while dragging with the mouse or a single finger
+	dragging     bool
+	lastX, lastY float64
+	// the distance between two fingers during the last pinch event
+	pinchDistance float64
+)
+
+func main() {
+	canvas = document.Call("getElementById", "mandelbrot")
+	if canvas.IsNull() {
+		fmt.Println("no canvas with id \"mandelbrot\" found")
+		return
+	}
+	canvas2D = canvas.Call("getContext", "2d")
+
+	if err := applyQuery(js.Global().Get("location").Get("search").String()); err != nil {
+		fmt.Println(err)
+		return
+	}
+	resize()
+	params.Scale = defaultSpan / math.Min(float64(params.Width), float64(params.Height))
+
+	addEventListener(js.Global(), "resize", func(js.Value) { resize() })
+	addEventListener(canvas, "mousedown", func(e js.Value) {
+		dragging = true
+		lastX, lastY = e.Get("offsetX").Float(), e.Get("offsetY").Float()
+	})
+	addEventListener(canvas, "mousemove", func(e js.Value) {
+		if dragging {
+			x, y := e.Get("offsetX").Float(), e.Get("offsetY").Float()
+			pan(x-lastX, y-lastY)
+			lastX, lastY = x, y
+		}
+	})
+	addEventListener(js.Global(), "mouseup", func(js.Value) { dragging = false })
+	addEventListener(canvas, "wheel", func(e js.Value) {
+		e.Call("preventDefault")
+		factor := wheelZoom
+		if e.Get("deltaY").Float() < 0 {
+			factor = 1 / wheelZoom
+		}
+		zoom(e.Get("offsetX").Float(), e.Get("offsetY").Float(), factor)
+	})
+	addEventListener(canvas, "touchstart", func(e js.Value) {
+		e.Call("preventDefault")
+		startTouches(e.Get("touches"))
+	})
+	addEventListener(canvas, "touchend", func(e js.Value) { startTouches(e.Get("touches")) })
+	addEventListener(canvas, "touchmove", func(e js.Value) {
+		e.Call("preventDefault")
+		moveTouches(e.Get("touches"))
+	})
+
+	var frame js.Func
+	frame = js.FuncOf(func(js.Value, []js.Value) interface{} {
+		draw()
+		js.Global().Call("requestAnimationFrame", frame)
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", frame)
+
+	// keep the callbacks alive
+	select {}
+}
+
+// overrides the default params with any set in the query string
+func applyQuery(query string) error {
+	values := js.Global().Get("URLSearchParams").New(query)
+	get := func(key string) (string, bool) {
+		v := values.Call("get", key)
+		if v.IsNull() {
+			return "", false
+		}
+		return v.String(), true
+	}
+
+	if v, ok := get("fractal"); ok {
+		params.Fractal = v
+	}
+	if v, ok := get("formula"); ok {
+		params.Formula = v
+	}
+	if v, ok := get("polynomial"); ok {
+		params.Polynomial = v
+	}
+	if v, ok := get("colouring"); ok {
+		params.Colouring = v
+	}
+	if v, ok := get("palette"); ok {
+		params.Palette = v
+	}
+	if v, ok := get("interior"); ok {
+		params.Interior = v
+	}
+	if v, ok := get("trap"); ok {
+		params.Trap = v
+	}
+	if v, ok := get("iterations"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid iterations %q", v)
+		}
+		params.Iterations = n
+	}
+	if v, ok := get("norm"); ok {
+		params.Norm = v
+	}
+	if v, ok := get("bailout"); ok {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bailout %q", v)
+		}
+		params.Bailout = r
+	}
+	if v, ok := get("exponent"); ok {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid exponent %q", v)
+		}
+		params.Exponent = d
+	}
+
+	// check everything but the image size, which isn't known yet
+	p := params
+	p.Width, p.Height, p.Scale = 1, 1, 1
+	return p.Validate()
+}
+
+// matches the image size to the canvas' size on the page
+func resize() {
+	width, height := canvas.Get("clientWidth").Int(), canvas.Get("clientHeight").Int()
+	canvas.Set("width", width)
+	canvas.Set("height", height)
+	params.Width, params.Height = width, height
+	dirty = true
+}
+
+// moves the view by the given number of pixels, so that the point under the pointer follows it
+func pan(dx, dy float64) {
+	params.Centre -= complex(dx*params.Scale, -dy*params.Scale)
+	dirty = true
+}
+
+// scales the view by factor, keeping the point under the pixel (x, y) fixed
+func zoom(x, y, factor float64) {
+	fixed := params.PixelToPlane(x, y)
+	params.Centre = fixed + (params.Centre-fixed)*complex(factor, 0)
+	params.Scale *= factor
+	dirty = true
+}
+
+// records the positions of the fingers currently touching the canvas
+func startTouches(touches js.Value) {
+	dragging = false
+	switch touches.Length() {
+	case 1:
+		dragging = true
+		lastX, lastY = touchPos(touches.Index(0))
+	case 2:
+		pinchDistance = touchDistance(touches)
+	}
+}
+
+// pans with a single finger, and zooms about the centre of a pinch with two
+func moveTouches(touches js.Value) {
+	switch touches.Length() {
+	case 1:
+		if !dragging {
+			startTouches(touches)
+			return
+		}
+		x, y := touchPos(touches.Index(0))
+		pan(x-lastX, y-lastY)
+		lastX, lastY = x, y
+	case 2:
+		distance := touchDistance(touches)
+		if pinchDistance > 0 && distance > 0 {
+			x0, y0 := touchPos(touches.Index(0))
+			x1, y1 := touchPos(touches.Index(1))
+			zoom((x0+x1)/2, (y0+y1)/2, pinchDistance/distance)
+		}
+		pinchDistance = distance
+	}
+}
+
+// returns the position of a touch relative to the canvas
+func touchPos(touch js.Value) (float64, float64) {
+	rect := canvas.Call("getBoundingClientRect")
+	return touch.Get("clientX").Float() - rect.Get("left").Float(), touch.Get("clientY").Float() - rect.Get("top").Float()
+}
+
+// returns the distance in pixels between the first two touches
+func touchDistance(touches js.Value) float64 {
+	x0, y0 := touchPos(touches.Index(0))
+	x1, y1 := touchPos(touches.Index(1))
+	return math.Hypot(x1-x0, y1-y0)
+}
+
+// renders the current view into the canvas if it has changed since the last frame
+func draw() {
+	if !dirty || params.Width < 1 || params.Height < 1 {
+		return
+	}
+	dirty = false
+
+	img, err := tileCache.Render(context.Background(), params)
+	if err != nil {
+		fmt.Printf("failed to render: %s\n", err)
+		return
+	}
+
+	data := canvas2D.Call("createImageData", params.Width, params.Height)
+	js.CopyBytesToJS(data.Get("data"), img.Pix)
+	canvas2D.Call("putImageData", data, 0, 0)
+	document.Set("title", fmt.Sprintf("Mandelbrot - %s - %d iterations", params.Fractal, params.Iterations))
+}
+
+// registers a handler for a DOM event. Handlers are allowed to call preventDefault.
+func addEventListener(target js.Value, event string, handler func(e js.Value)) {
+	f := js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		handler(args[0])
+		return nil
+	})
+	target.Call("addEventListener", event, f, map[string]interface{}{"passive": false})
+}